@@ -1,77 +1,251 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/dineshd30/ledger-service/internal/ledger"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/viper"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
 	"go.uber.org/zap"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		os.Exit(runVerify(os.Args[2:]))
+	}
+
+	os.Exit(runServer())
+}
+
+// runServer serves the api until SIGINT or SIGTERM, then drains the in-flight requests, stops the
+// hold sweeper, closes the store, flushes the spans and syncs the logger, and returns the exit code
+func runServer() int {
 	loadConfig(getEnv())
-	configureLogger()
+	logger := configureLogger()
+	defer logger.Sync()
+
+	shutdownTracing, err := configureTracing()
+	if err != nil {
+		zap.L().Fatal("failed to configure tracing", zap.Error(err))
+	}
+	defer flushTracing(shutdownTracing)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	metrics := ledger.NewMetrics(registry)
+
+	store, err := newStore(metrics, false)
+	if err != nil {
+		zap.L().Fatal("failed to create store", zap.Error(err))
+	}
+	defer closeStore(store)
+	store = ledger.NewTracedStore(store, otel.GetTracerProvider())
+	initCashLedger(store)
+
+	sweeper := ledger.NewHoldSweeper(store, viper.GetDuration("holds.sweepInterval"))
+	stopSweeper := startHoldSweeper(sweeper)
+	defer stopSweeper()
+
+	var draining atomic.Bool
+	checkers := map[string]ledger.HealthChecker{
+		"store":       ledger.StoreHealthChecker(store),
+		"holdSweeper": sweeper,
+		"draining": ledger.HealthCheckFunc(func(context.Context) error {
+			if draining.Load() {
+				return errors.New("failed get serving service, got draining service")
+			}
+			return nil
+		}),
+	}
+	auth, err := newAuth(store)
+	if err != nil {
+		zap.L().Fatal("failed to configure authentication", zap.Error(err))
+	}
 
-	router := configureRoutes()
 	port := getHTTPPort()
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%s", port),
-		Handler: router,
+		Handler: configureRoutes(store, registry, metrics, checkers, auth, newRateLimiters()),
 	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.ListenAndServe()
+	}()
 	zap.L().Info(fmt.Sprintf("ledger service started at :%s", port))
-	err := server.ListenAndServe()
-	if err != nil {
-		zap.L().Fatal("failed to listen and serve on server", zap.Error(err), zap.String("port", port))
+
+	select {
+	case err := <-served:
+		zap.L().Error("failed to listen and serve on server", zap.Error(err), zap.String("port", port))
+		return 1
+	case <-ctx.Done():
+		// a second signal terminates the service straight away
+		stop()
+	}
+
+	if err := drain(server, &draining, viper.GetDuration("shutdown.delay"), getShutdownTimeout()); err != nil {
+		zap.L().Error("failed to drain in-flight requests", zap.Error(err))
+		return 1
 	}
+	return 0
 }
 
-// configureRoutes configures service routes
-func configureRoutes() *gin.Engine {
+// configureRoutes configures service routes over the store, with the readiness probe consulting
+// the checkers and the api routes authorised by auth and rate limited by limiters
+func configureRoutes(store ledger.Store, registry *prometheus.Registry, metrics *ledger.Metrics, checkers map[string]ledger.HealthChecker, auth *ledger.Auth, limiters rateLimiters) *gin.Engine {
 	mode := gin.ReleaseMode
 	if getEnv() != "prod" {
 		mode = gin.DebugMode
 	}
 	gin.SetMode(mode)
 	router := gin.New()
-	router.Use(gin.Recovery())
-	router.GET("/healthcheck", func(ctx *gin.Context) {
-		ctx.Writer.WriteHeader(http.StatusOK)
-	})
 
-	uuid := ledger.NewUUIDGenerator()
-	store := ledger.NewStore(uuid, initCashLedger(uuid))
-	ledgerRoutes := router.Group("/ledger/:ledgerId")
-	ledgerRoutes.POST("/transaction", ledger.DoTransaction(store))
-	ledgerRoutes.GET("/balance", ledger.ViewBalance(store))
-	ledgerRoutes.GET("/statement", ledger.ViewTransactionHistory(store))
+	// the metrics and tracing middlewares come first so that requests recovered from a panic are
+	// counted and traced too; scrapes and probes are not traced
+	router.Use(
+		metrics.Middleware(),
+		otelgin.Middleware(serviceName, otelgin.WithFilter(func(r *http.Request) bool {
+			return r.URL.Path != "/metrics" && r.URL.Path != "/livez" && r.URL.Path != "/readyz"
+		})),
+		gin.Recovery(),
+	)
+	router.GET("/livez", ledger.Liveness())
+	router.GET("/readyz", ledger.Readiness(checkers))
+	router.GET("/metrics", gin.WrapH(promhttp.HandlerFor(registry, promhttp.HandlerOpts{})))
+
+	// the api routes run after the tracing middleware so that their log lines carry the trace id;
+	// scrapes and probes are not logged. Clients are rate limited once authenticated, and the
+	// ledgers a request changes once it is authorised.
+	api := router.Group("", ledger.RequestLogger(ledger.NewUUIDGenerator(), zap.L()), auth.Authenticate(), limiters.clients.LimitClients())
+	limitLedgers := limiters.ledgers.LimitLedgers()
+	ledgerRoutes := api.Group("/ledger/:ledgerId")
+	ledgerRoutes.POST("/transaction", auth.AuthorizeTransaction(), limitLedgers, ledger.DoTransaction(store))
+	ledgerRoutes.GET("/balance", auth.Authorize(ledger.PermissionReadBalance), ledger.ViewBalance(store))
+	ledgerRoutes.GET("/statement", auth.Authorize(ledger.PermissionReadStatement), ledger.ViewTransactionHistory(store))
+	ledgerRoutes.POST("/holds", auth.Authorize(ledger.PermissionDebit), limitLedgers, ledger.PlaceHold(store))
+	ledgerRoutes.POST("/transactions/:txId/reverse", auth.AuthorizeReversal(), limitLedgers, ledger.ReverseTransaction(store))
+	ledgerRoutes.GET("/verify", auth.Authorize(ledger.PermissionReadStatement), ledger.VerifyLedger(store))
+	api.POST("/transfers", auth.AuthorizeTransfer(), limitLedgers, ledger.DoTransfer(store))
+	api.POST("/journal-entries", auth.AuthorizeJournalEntry(), limitLedgers, ledger.DoJournalEntry(store))
+
+	holdRoutes := api.Group("/holds/:holdId")
+	holdRoutes.GET("", auth.AuthorizeHold(ledger.PermissionReadBalance), ledger.ViewHold(store))
+	holdRoutes.POST("/capture", auth.AuthorizeHold(ledger.PermissionDebit), ledger.CaptureHold(store))
+	holdRoutes.POST("/release", auth.AuthorizeHold(ledger.PermissionDebit), ledger.ReleaseHold(store))
+
+	ledgersRoutes := api.Group("/ledgers")
+	ledgersRoutes.POST("", auth.Authorize(ledger.PermissionAdmin), ledger.CreateLedger(store))
+	ledgersRoutes.GET("", auth.Authorize(ledger.PermissionReadBalance), ledger.ListLedgers(store))
+	ledgersRoutes.GET("/:ledgerId", auth.Authorize(ledger.PermissionReadBalance), ledger.ViewLedger(store))
+	ledgersRoutes.POST("/:ledgerId/close", auth.Authorize(ledger.PermissionAdmin), ledger.CloseLedger(store))
+	ledgersRoutes.GET("/:ledgerId/events", auth.Authorize(ledger.PermissionReadStatement), ledger.ViewLedgerEvents(store))
+	ledgersRoutes.GET("/:ledgerId/versions/:version", auth.Authorize(ledger.PermissionReadStatement), ledger.ViewLedgerAtVersion(store))
+
+	adminRoutes := api.Group("/admin", auth.Authorize(ledger.PermissionAdmin))
+	adminRoutes.PUT("/ledgers/:ledgerId/policy", ledger.SetBalancePolicy(store))
 	return router
 }
 
-// initCashLedger initialises cash ledger
-func initCashLedger(uuid ledger.UUIDGenerator) map[string]*ledger.Ledger {
-	ledgerId := "304629d2-ba1f-43df-a839-26ceb869645a"
-	cashLedger := ledger.Ledger{
-		ID:   ledgerId,
-		Type: "cash",
-		Transactions: []ledger.Transaction{
-			{
-				ID:             uuid.Generate(),
-				Date:           time.Now().UTC().UnixMilli(),
-				Type:           ledger.Credit,
-				Description:    "Initial transaction",
-				Amount:         100,
-				RunningBalance: 100.00,
-			},
+// newStore creates the store selected by the store.type config, either "memory", "file" or "sql",
+// recording its domain metrics to metrics unless nil. A read-only store reads the file or database
+// as persisted, leaving it untouched.
+func newStore(metrics *ledger.Metrics, readOnly bool) (ledger.Store, error) {
+	uuid := ledger.NewUUIDGenerator()
+	counterAccounts := getCounterAccounts()
+	opts := ledger.StoreOptions{
+		CounterAccounts:      counterAccounts,
+		IdempotencyRetention: viper.GetDuration("idempotency.retention"),
+		SnapshotInterval:     viper.GetInt("store.snapshotInterval"),
+		Metrics:              metrics,
+	}
+
+	storeType := viper.GetString("store.type")
+	switch storeType {
+	case "", "memory":
+		return ledger.NewStore(uuid, initLedgers(counterAccounts), opts), nil
+	case "file":
+		if readOnly {
+			return ledger.NewReadOnlyFileStore(uuid, initLedgers(counterAccounts), opts, viper.GetString("store.path"))
+		}
+		return ledger.NewFileStore(uuid, initLedgers(counterAccounts), opts, viper.GetString("store.path"))
+	case "sql":
+		if readOnly {
+			return ledger.NewReadOnlySQLStore(uuid, initLedgers(counterAccounts), opts, viper.GetString("store.driver"), viper.GetString("store.dsn"))
+		}
+		return ledger.NewSQLStore(uuid, initLedgers(counterAccounts), opts, viper.GetString("store.driver"), viper.GetString("store.dsn"))
+	default:
+		return nil, fmt.Errorf("failed get supported store type, got %q", storeType)
+	}
+}
+
+// cashLedgerId is the id of the seeded cash ledger
+const cashLedgerId = "304629d2-ba1f-43df-a839-26ceb869645a"
+
+// initLedgers initialises the cash ledger and one counter account ledger per configured currency.
+// The file and SQL stores record each configured ledger once, so it keeps the creation time of the
+// first start across restarts.
+func initLedgers(counterAccounts map[ledger.Currency]string) map[string]*ledger.Ledger {
+	createdAt := time.Now().UTC().UnixMilli()
+	ledgers := map[string]*ledger.Ledger{
+		cashLedgerId: {
+			ID:        cashLedgerId,
+			Type:      "cash",
+			Currency:  "EUR",
+			Status:    ledger.LedgerOpen,
+			CreatedAt: createdAt,
 		},
 	}
 
-	return map[string]*ledger.Ledger{
-		ledgerId: &cashLedger,
+	for currency, ledgerId := range counterAccounts {
+		ledgers[ledgerId] = &ledger.Ledger{
+			ID:        ledgerId,
+			Type:      "counter-account",
+			Currency:  currency,
+			Status:    ledger.LedgerOpen,
+			CreatedAt: createdAt,
+		}
+	}
+
+	return ledgers
+}
+
+// initCashLedger records the initial transaction of the cash ledger unless a durable store
+// already holds it
+func initCashLedger(store ledger.Store) {
+	statement, err := store.GetTransactionHistory(context.Background(), cashLedgerId, ledger.StatementQuery{Limit: 1})
+	if err != nil {
+		zap.L().Fatal("failed to get cash ledger history", zap.Error(err), zap.String("ledgerId", cashLedgerId))
+	}
+	if len(statement.Transactions) > 0 {
+		return
+	}
+
+	_, err = store.Credit(context.Background(), cashLedgerId, ledger.TransactionRequestDTO{
+		Type:        ledger.Credit,
+		Description: "Initial transaction",
+		Currency:    "EUR",
+		Amount:      ledger.MustParseMoney("100"),
+	})
+	if err != nil {
+		zap.L().Fatal("failed to initialise cash ledger", zap.Error(err), zap.String("ledgerId", cashLedgerId))
 	}
 }
 
@@ -106,3 +280,70 @@ func getHTTPPort() string {
 	}
 	return port
 }
+
+// getCounterAccounts gets the counter account ledger id for each currency
+func getCounterAccounts() map[ledger.Currency]string {
+	counterAccounts := make(map[ledger.Currency]string)
+	for currency, ledgerId := range viper.GetStringMapString("ledger.counterAccounts") {
+		counterAccounts[ledger.Currency(strings.ToUpper(currency))] = ledgerId
+	}
+	return counterAccounts
+}
+
+// newAuth creates the authentication of the api routes from the API keys of the auth.keys config
+// and, when auth.jwt.jwks is set, the bearer tokens verified by the keys it holds, looking up holds
+// in store. It returns nil when auth.enabled is false.
+func newAuth(store ledger.Store) (*ledger.Auth, error) {
+	if !viper.GetBool("auth.enabled") {
+		zap.L().Warn("authentication disabled, every request is authorised")
+		return nil, nil
+	}
+
+	var keys []ledger.APIKey
+	if err := viper.UnmarshalKey("auth.keys", &keys); err != nil {
+		return nil, fmt.Errorf("failed get api keys, got error: %w", err)
+	}
+
+	var tokens *ledger.TokenVerifier
+	if jwks := viper.GetString("auth.jwt.jwks"); jwks != "" {
+		var err error
+		tokens, err = ledger.NewTokenVerifier(ledger.TokenOptions{
+			JWKS:               jwks,
+			Issuer:             viper.GetString("auth.jwt.issuer"),
+			Audience:           viper.GetString("auth.jwt.audience"),
+			RolesClaim:         viper.GetString("auth.jwt.rolesClaim"),
+			LedgersClaim:       viper.GetString("auth.jwt.ledgersClaim"),
+			RefreshInterval:    viper.GetDuration("auth.jwt.refreshInterval"),
+			MinRefreshInterval: viper.GetDuration("auth.jwt.minRefreshInterval"),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(keys) == 0 && tokens == nil {
+		zap.L().Warn("no api keys or jwks configured, every request is rejected")
+	}
+
+	return ledger.NewAuth(keys, tokens, store)
+}
+
+// rateLimiters are the rate limiters of the api routes, nil when disabled
+type rateLimiters struct {
+	clients *ledger.RateLimiter
+	ledgers *ledger.RateLimiter
+}
+
+// newRateLimiters creates the rate limiters of the clients and of the ledgers configured by the
+// rateLimit.clients and rateLimit.ledgers configs, each disabled unless its rate is positive
+func newRateLimiters() rateLimiters {
+	var clients, ledgers ledger.RateLimit
+	if err := viper.UnmarshalKey("rateLimit.clients", &clients); err != nil {
+		zap.L().Fatal("failed to configure client rate limit", zap.Error(err))
+	}
+	if err := viper.UnmarshalKey("rateLimit.ledgers", &ledgers); err != nil {
+		zap.L().Fatal("failed to configure ledger rate limit", zap.Error(err))
+	}
+
+	return rateLimiters{clients: ledger.NewRateLimiter(clients), ledgers: ledger.NewRateLimiter(ledgers)}
+}
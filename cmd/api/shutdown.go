@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// defaultShutdownTimeout is how long in-flight requests are given to complete on shutdown unless
+// shutdown.timeout is set
+const defaultShutdownTimeout = 30 * time.Second
+
+// getShutdownTimeout gets how long in-flight requests are given to complete on shutdown
+func getShutdownTimeout() time.Duration {
+	timeout := viper.GetDuration("shutdown.timeout")
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	return timeout
+}
+
+// drain fails the readiness probe so that the load balancer stops routing requests to the service,
+// waits delay for it to notice, then stops accepting connections and waits up to timeout for the
+// in-flight requests to complete. Connections still active after timeout are closed, and the
+// requests served on them fail to record their transactions once the store is closed.
+func drain(server *http.Server, draining *atomic.Bool, delay time.Duration, timeout time.Duration) error {
+	draining.Store(true)
+	zap.L().Info("draining ledger service", zap.Duration("delay", delay), zap.Duration("timeout", timeout))
+	time.Sleep(delay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		return errors.Join(fmt.Errorf("failed to complete in-flight requests within %s, got error: %w", timeout, err), server.Close())
+	}
+
+	zap.L().Info("drained ledger service")
+	return nil
+}
+
+// startHoldSweeper runs the hold sweeper in the background and returns a function stopping it and
+// waiting for a sweep in progress to complete
+func startHoldSweeper(sweeper *ledger.HoldSweeper) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sweeper.Run(ctx)
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// closeStore closes the store along with its write-ahead log or database
+func closeStore(store ledger.Store) {
+	if err := store.Close(); err != nil {
+		zap.L().Error("failed to close store", zap.Error(err))
+		return
+	}
+	zap.L().Info("closed store")
+}
+
+// flushTracing exports the spans not yet exported and stops the tracer provider, giving up after
+// a few seconds when the collector is unreachable
+func flushTracing(shutdown func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdown(ctx); err != nil {
+		zap.L().Error("failed to flush spans", zap.Error(err))
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// serviceName is the name the service reports its spans under unless tracing.serviceName is set
+const serviceName = "ledger-service"
+
+// configureTracing installs the W3C trace context propagator, so that incoming traceparent headers
+// are honoured, and a tracer provider exporting spans with the exporter selected by the
+// tracing.exporter config, either "none", "stdout" or "otlp". It returns a function flushing the
+// spans not yet exported and stopping the provider.
+func configureTracing() (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	exporterType := viper.GetString("tracing.exporter")
+	switch exporterType {
+	case "", "none":
+		// the default tracer provider records no spans but still carries the incoming trace context
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "otlp":
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(viper.GetString("tracing.endpoint"))}
+		if viper.GetBool("tracing.insecure") {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(context.Background(), opts...)
+	default:
+		return nil, fmt.Errorf("failed get supported tracing exporter, got %q", exporterType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s tracing exporter, got error: %w", exporterType, err)
+	}
+
+	name := viper.GetString("tracing.serviceName")
+	if name == "" {
+		name = serviceName
+	}
+	sampleRatio := 1.0
+	if viper.IsSet("tracing.sampleRatio") {
+		sampleRatio = viper.GetFloat64("tracing.sampleRatio")
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(name), semconv.DeploymentEnvironment(getEnv()))),
+		// a sampled incoming trace stays sampled, so the spans of the service join the caller's trace
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+)
+
+// verifyUsage describes the verify command
+const verifyUsage = `usage: api verify [ledgerId...]
+
+Opens the configured store read-only and walks the hash chain of the transactions of each given
+ledger, or of every ledger when none is given, checking the rows of the transactions table of a sql
+store against it and reporting the first broken link, then checks the balance of the ledger against
+the running balance of its last transaction. Leading transactions recorded before hashing are
+reported as unhashed. Exits with 1 when a chain is broken and with 2 when the store cannot be
+verified.`
+
+// runVerify runs the verify command and returns its exit code
+func runVerify(args []string) int {
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			fmt.Println(verifyUsage)
+			return 0
+		}
+	}
+
+	loadConfig(getEnv())
+	store, err := newStore(nil, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create store, got error: %s\n", err)
+		return 2
+	}
+	defer store.Close()
+
+	ledgerIds := args
+	if len(ledgerIds) == 0 {
+		if ledgerIds, err = allLedgerIds(store); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to list ledgers, got error: %s\n", err)
+			return 2
+		}
+	}
+
+	code := 0
+	for _, ledgerId := range ledgerIds {
+		verification, err := store.VerifyLedger(context.Background(), ledgerId)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", ledgerId, err)
+			return 2
+		}
+
+		if verification.Verified {
+			line := fmt.Sprintf("%s: verified %d transactions", ledgerId, verification.Checked)
+			if verification.Unhashed > 0 {
+				line += fmt.Sprintf(" (%d unhashed)", verification.Unhashed)
+			}
+			if verification.Head != "" {
+				line += ", head " + verification.Head
+			}
+			fmt.Println(line)
+			continue
+		}
+
+		if mismatch := verification.BalanceMismatch; mismatch != nil {
+			fmt.Printf("%s: balance %s differs from running balance %s of last of %d verified transactions\n",
+				ledgerId, mismatch.Balance, mismatch.RunningBalance, verification.Checked)
+			code = 1
+			continue
+		}
+
+		broken := verification.BrokenLink
+		where := ""
+		if broken.Table != "" {
+			where = " in table " + broken.Table
+		}
+		fmt.Printf("%s: broken link%s at transaction %d (%s) after %d verified, expected hash %s, recorded %q\n",
+			ledgerId, where, broken.Sequence, broken.TransactionID, verification.Checked, broken.ExpectedHash, broken.RecordedHash)
+		code = 1
+	}
+
+	return code
+}
+
+// allLedgerIds returns the id of every ledger of the store
+func allLedgerIds(store ledger.Store) ([]string, error) {
+	var ids []string
+	query := ledger.LedgerQuery{Limit: ledger.MaxLedgerPageSize}
+	for {
+		page, err := store.ListLedgers(context.Background(), query)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range page.Ledgers {
+			ids = append(ids, l.ID)
+		}
+		if page.NextCursor == "" {
+			return ids, nil
+		}
+		query.Cursor = page.NextCursor
+	}
+}
@@ -0,0 +1,344 @@
+package ledger
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// APIKeyHeader is the request header carrying the API key of the client
+const APIKeyHeader = "X-API-Key"
+
+// AllLedgers grants an API key its permissions on every ledger, including the routes that are not
+// scoped to a single ledger, such as listing ledgers
+const AllLedgers = "*"
+
+// Permission represents an operation an API key may be granted on its ledgers
+type Permission string
+
+const (
+	PermissionReadBalance   Permission = "read-balance"
+	PermissionReadStatement Permission = "read-statement"
+	PermissionCredit        Permission = "credit"
+	PermissionDebit         Permission = "debit"
+	PermissionAdmin         Permission = "admin"
+)
+
+// permissions lists every permission an API key may be granted
+var permissions = []Permission{PermissionReadBalance, PermissionReadStatement, PermissionCredit, PermissionDebit, PermissionAdmin}
+
+// APIKey represents a client of the service, identified by the hex SHA-256 hash of its key, and the
+// permissions it is granted on its ledgers
+type APIKey struct {
+	ID          string       `mapstructure:"id"`
+	Hash        string       `mapstructure:"hash"`
+	Permissions []Permission `mapstructure:"permissions"`
+	Ledgers     []string     `mapstructure:"ledgers"`
+}
+
+// HashAPIKey returns the hex SHA-256 hash of the key, as configured for it
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// validate checks the API key is identified, has a well formed hash and is granted known
+// permissions on at least one ledger
+func (k APIKey) validate() error {
+	if k.ID == "" {
+		return errors.New("failed get api key id")
+	}
+
+	if hash, err := hex.DecodeString(k.Hash); err != nil || len(hash) != sha256.Size {
+		return fmt.Errorf("failed get hex sha-256 hash of api key %s", k.ID)
+	}
+
+	if len(k.Permissions) == 0 {
+		return fmt.Errorf("failed get permissions of api key %s", k.ID)
+	}
+	for _, permission := range k.Permissions {
+		if !slices.Contains(permissions, permission) {
+			return fmt.Errorf("failed get known permission of api key %s, got %q", k.ID, permission)
+		}
+	}
+
+	if len(k.Ledgers) == 0 {
+		return fmt.Errorf("failed get ledgers of api key %s", k.ID)
+	}
+
+	return nil
+}
+
+// allows reports whether the key is granted the permission on the ledger
+func (k APIKey) allows(permission Permission, ledgerId string) bool {
+	if !slices.Contains(k.Permissions, permission) {
+		return false
+	}
+
+	return slices.Contains(k.Ledgers, AllLedgers) || (ledgerId != AllLedgers && slices.Contains(k.Ledgers, ledgerId))
+}
+
+// errMalformedPayload is the kind of errors for a request payload that cannot be decoded to be
+// authorised
+var errMalformedPayload = errors.New("malformed payload")
+
+// grant is a permission a request needs on a ledger
+type grant struct {
+	permission Permission
+	ledgerId   string
+}
+
+// clientKey is the context key of the API key of the client
+type clientKey struct{}
+
+// clientFrom returns the API key of the client authenticated for the request
+func clientFrom(ctx context.Context) (APIKey, bool) {
+	key, ok := ctx.Value(clientKey{}).(APIKey)
+	return key, ok
+}
+
+// Auth authenticates requests by their API key or bearer token and authorises them by the
+// permissions of the key, or of the roles of the token, on the ledgers they touch. A nil Auth lets
+// every request through, for when authentication is disabled.
+type Auth struct {
+	keys   map[string]APIKey
+	tokens *TokenVerifier
+	store  Store
+}
+
+// NewAuth creates an Auth accepting the keys and, unless tokens is nil, the bearer tokens it
+// verifies, looking up in store the ledger of the holds requests touch
+func NewAuth(keys []APIKey, tokens *TokenVerifier, store Store) (*Auth, error) {
+	a := &Auth{keys: make(map[string]APIKey, len(keys)), tokens: tokens, store: store}
+	ids := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if err := key.validate(); err != nil {
+			return nil, err
+		}
+		if ids[key.ID] {
+			return nil, fmt.Errorf("failed get unique api key id, got %s twice", key.ID)
+		}
+		ids[key.ID] = true
+
+		key.Hash = strings.ToLower(key.Hash)
+		if _, ok := a.keys[key.Hash]; ok {
+			return nil, fmt.Errorf("failed get unique api key hash, got hash of %s reused", key.ID)
+		}
+		a.keys[key.Hash] = key
+	}
+
+	return a, nil
+}
+
+// Authenticate rejects with 401 the request without a valid bearer token in its Authorization
+// header, when tokens are accepted, or an X-API-Key header that is one of the keys. Otherwise it
+// adds the sub of the token or the id of the key to the request logger as "client".
+func (a *Auth) Authenticate() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if a == nil {
+			ctx.Next()
+			return
+		}
+
+		reqCtx := ctx.Request.Context()
+		var key APIKey
+		if token, ok := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer "); ok && a.tokens != nil {
+			var err error
+			if key, err = a.tokens.verify(reqCtx, token); err != nil {
+				loggerFrom(reqCtx).Warn("rejected unauthenticated request", zap.Error(err))
+				ErrorHandler(ctx, http.StatusUnauthorized, withKind(ErrUnauthenticated, errors.New("failed get valid bearer token")))
+				ctx.Abort()
+				return
+			}
+		} else if key, ok = a.keys[HashAPIKey(ctx.GetHeader(APIKeyHeader))]; !ok {
+			loggerFrom(reqCtx).Warn("rejected unauthenticated request", zap.Bool("keySent", ctx.GetHeader(APIKeyHeader) != ""))
+			ErrorHandler(ctx, http.StatusUnauthorized, withKind(ErrUnauthenticated, fmt.Errorf("failed get valid %s header", APIKeyHeader)))
+			ctx.Abort()
+			return
+		}
+
+		reqCtx = withLogger(reqCtx, loggerFrom(reqCtx).With(zap.String("client", key.ID)))
+		ctx.Request = ctx.Request.WithContext(context.WithValue(reqCtx, clientKey{}, key))
+		ctx.Next()
+	}
+}
+
+// Authorize rejects with 403 the request whose client is not granted the permission on the ledger
+// of the ledgerId path parameter or, on a route without one, on every ledger
+func (a *Auth) Authorize(permission Permission) gin.HandlerFunc {
+	return a.authorize(func(ctx *gin.Context) ([]grant, error) {
+		ledgerId := ctx.Param("ledgerId")
+		if ledgerId == "" {
+			ledgerId = AllLedgers
+		}
+		return []grant{{permission, ledgerId}}, nil
+	})
+}
+
+// AuthorizeTransaction rejects with 403 the credit or debit whose client is not granted the
+// permission of its type on the ledger of the path. A transaction of any other type needs the
+// debit permission, for the handler to reject it.
+func (a *Auth) AuthorizeTransaction() gin.HandlerFunc {
+	return a.authorize(func(ctx *gin.Context) ([]grant, error) {
+		var req struct {
+			Type TransactionType `json:"type"`
+		}
+		if err := peekJSON(ctx, &req); err != nil {
+			return nil, err
+		}
+		return []grant{{permissionOf(req.Type), ctx.Param("ledgerId")}}, nil
+	})
+}
+
+// AuthorizeTransfer rejects with 403 the transfer whose client is not granted the debit permission
+// on its source ledger and the credit permission on its destination ledger
+func (a *Auth) AuthorizeTransfer() gin.HandlerFunc {
+	return a.authorize(func(ctx *gin.Context) ([]grant, error) {
+		var req struct {
+			SourceLedgerID      string `json:"sourceLedgerId"`
+			DestinationLedgerID string `json:"destinationLedgerId"`
+		}
+		if err := peekJSON(ctx, &req); err != nil {
+			return nil, err
+		}
+		return []grant{{PermissionDebit, req.SourceLedgerID}, {PermissionCredit, req.DestinationLedgerID}}, nil
+	})
+}
+
+// AuthorizeJournalEntry rejects with 403 the journal entry whose client is not granted the
+// permission of the type of each leg on the ledger of the leg
+func (a *Auth) AuthorizeJournalEntry() gin.HandlerFunc {
+	return a.authorize(func(ctx *gin.Context) ([]grant, error) {
+		var req struct {
+			Legs []struct {
+				LedgerID string          `json:"ledgerId"`
+				Type     TransactionType `json:"type"`
+			} `json:"legs"`
+		}
+		if err := peekJSON(ctx, &req); err != nil {
+			return nil, err
+		}
+
+		grants := make([]grant, 0, len(req.Legs))
+		for _, leg := range req.Legs {
+			grants = append(grants, grant{permissionOf(leg.Type), leg.LedgerID})
+		}
+		return grants, nil
+	})
+}
+
+// AuthorizeHold rejects with 403 the request whose client is not granted the permission on the
+// ledger of the hold of the holdId path parameter
+func (a *Auth) AuthorizeHold(permission Permission) gin.HandlerFunc {
+	return a.authorize(func(ctx *gin.Context) ([]grant, error) {
+		hold, err := a.store.GetHold(ctx.Request.Context(), ctx.Param("holdId"))
+		if err != nil {
+			return nil, err
+		}
+		return []grant{{permission, hold.LedgerID}}, nil
+	})
+}
+
+// AuthorizeReversal rejects with 403 the reversal whose client is not granted the admin permission
+// on the ledger of the path and, on the ledger of each leg of the journal entry of the transaction,
+// the permission of the type of the leg reversing it
+func (a *Auth) AuthorizeReversal() gin.HandlerFunc {
+	return a.authorize(func(ctx *gin.Context) ([]grant, error) {
+		entry, err := a.store.GetJournalEntry(ctx.Request.Context(), ctx.Param("ledgerId"), ctx.Param("txId"))
+		if err != nil {
+			return nil, err
+		}
+
+		grants := make([]grant, 0, len(entry.Transactions)+1)
+		grants = append(grants, grant{PermissionAdmin, ctx.Param("ledgerId")})
+		for _, tx := range entry.Transactions {
+			// a credit is reversed by a debit and a debit by a credit
+			reversing := Credit
+			if tx.Type == Credit {
+				reversing = Debit
+			}
+			grants = append(grants, grant{permissionOf(reversing), tx.LedgerID})
+		}
+		return grants, nil
+	})
+}
+
+// authorize rejects with 403 the request whose client is not granted every grant the request needs
+func (a *Auth) authorize(grantsOf func(ctx *gin.Context) ([]grant, error)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if a == nil {
+			ctx.Next()
+			return
+		}
+
+		reqCtx := ctx.Request.Context()
+		key, ok := clientFrom(reqCtx)
+		if !ok {
+			ErrorHandler(ctx, http.StatusUnauthorized, withKind(ErrUnauthenticated, fmt.Errorf("failed get valid %s header", APIKeyHeader)))
+			ctx.Abort()
+			return
+		}
+
+		grants, err := grantsOf(ctx)
+		if err != nil {
+			ErrorHandler(ctx, errorStatus(err), fmt.Errorf("failed to authorise request, got error: %w", err))
+			ctx.Abort()
+			return
+		}
+
+		for _, g := range grants {
+			if !key.allows(g.permission, g.ledgerId) {
+				loggerFrom(reqCtx).Warn("rejected unauthorised request",
+					zap.String("permission", string(g.permission)), zap.String("ledgerId", g.ledgerId))
+				ErrorHandler(ctx, http.StatusForbidden, withKind(ErrForbidden,
+					fmt.Errorf("failed get %s permission on ledger: %s", g.permission, g.ledgerId)))
+				ctx.Abort()
+				return
+			}
+		}
+
+		ctx.Next()
+	}
+}
+
+// permissionOf returns the permission a transaction of the type needs
+func permissionOf(t TransactionType) Permission {
+	if t == Credit {
+		return PermissionCredit
+	}
+	return PermissionDebit
+}
+
+// maxPayloadBytes is the size of the largest request payload read to authorise or rate limit it
+const maxPayloadBytes = 1 << 20
+
+// peekJSON decodes the JSON body of the request into v and restores the body for the handler. A
+// body over maxPayloadBytes is rejected without being read further.
+func peekJSON(ctx *gin.Context, v interface{}) error {
+	body, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxPayloadBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return withKind(ErrPayloadTooLarge, fmt.Errorf("failed get request payload within %d bytes", tooLarge.Limit))
+		}
+		return withKind(errMalformedPayload, errors.New("failed get request payload"))
+	}
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return withKind(errMalformedPayload, errors.New("failed get valid request payload"))
+	}
+
+	return nil
+}
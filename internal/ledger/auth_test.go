@@ -0,0 +1,326 @@
+package ledger_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// apiKeys grants an admin key every permission on every ledger, a wallet key to read and debit the
+// wallet ledger only and a wallet admin key to administer, credit and debit the wallet ledger only
+var apiKeys = []ledger.APIKey{
+	{
+		ID:   "admin",
+		Hash: ledger.HashAPIKey("admin-key"),
+		Permissions: []ledger.Permission{
+			ledger.PermissionReadBalance, ledger.PermissionReadStatement,
+			ledger.PermissionCredit, ledger.PermissionDebit, ledger.PermissionAdmin,
+		},
+		Ledgers: []string{ledger.AllLedgers},
+	},
+	{
+		ID:          "wallet",
+		Hash:        ledger.HashAPIKey("wallet-key"),
+		Permissions: []ledger.Permission{ledger.PermissionReadBalance, ledger.PermissionDebit},
+		Ledgers:     []string{"wallet"},
+	},
+	{
+		ID:          "wallet-admin",
+		Hash:        ledger.HashAPIKey("wallet-admin-key"),
+		Permissions: []ledger.Permission{ledger.PermissionCredit, ledger.PermissionDebit, ledger.PermissionAdmin},
+		Ledgers:     []string{"wallet"},
+	},
+}
+
+// echoBody responds with the body of the request, to check authorisation leaves it to the handler
+func echoBody(ctx *gin.Context) {
+	body, _ := io.ReadAll(ctx.Request.Body)
+	ctx.String(http.StatusOK, string(body))
+}
+
+func TestAuth(t *testing.T) {
+	storeInstance := newFundedStore(t)
+	hold, err := storeInstance.PlaceHold(context.Background(), "wallet", holdFor("10"))
+	require.NoError(t, err)
+	savings, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "savings", Currency: "EUR"})
+	require.NoError(t, err)
+	transfer, err := storeInstance.Transfer(context.Background(), ledger.TransferRequestDTO{
+		SourceLedgerID: "wallet", DestinationLedgerID: savings.ID, Currency: "EUR", Amount: ledger.MustParseMoney("5"),
+	})
+	require.NoError(t, err)
+	withdrawal, err := storeInstance.Debit(context.Background(), "wallet", ledger.TransactionRequestDTO{
+		Type: ledger.Debit, Description: "withdrawal", Currency: "EUR", Amount: ledger.MustParseMoney("5"),
+	})
+	require.NoError(t, err)
+	auth, err := ledger.NewAuth(apiKeys, nil, storeInstance)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api := router.Group("", auth.Authenticate())
+	api.GET("/ledger/:ledgerId/balance", auth.Authorize(ledger.PermissionReadBalance), echoBody)
+	api.POST("/ledger/:ledgerId/transaction", auth.AuthorizeTransaction(), echoBody)
+	api.POST("/transfers", auth.AuthorizeTransfer(), echoBody)
+	api.POST("/journal-entries", auth.AuthorizeJournalEntry(), echoBody)
+	api.POST("/holds/:holdId/capture", auth.AuthorizeHold(ledger.PermissionDebit), echoBody)
+	api.POST("/ledger/:ledgerId/transactions/:txId/reverse", auth.AuthorizeReversal(), echoBody)
+	api.GET("/ledgers", auth.Authorize(ledger.PermissionReadBalance), echoBody)
+	api.PUT("/admin/ledgers/:ledgerId/policy", auth.Authorize(ledger.PermissionAdmin), echoBody)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		key          string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "should reject request without api key",
+			method:       http.MethodGet,
+			path:         "/ledger/wallet/balance",
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":"failed get valid X-API-Key header","code":"unauthenticated"}`,
+		},
+		{
+			name:         "should reject request with unknown api key",
+			method:       http.MethodGet,
+			path:         "/ledger/wallet/balance",
+			key:          "unknown-key",
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":"failed get valid X-API-Key header","code":"unauthenticated"}`,
+		},
+		{
+			name:         "should authorise reading balance of granted ledger",
+			method:       http.MethodGet,
+			path:         "/ledger/wallet/balance",
+			key:          "wallet-key",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "should reject reading balance of other ledger",
+			method:       http.MethodGet,
+			path:         "/ledger/savings/balance",
+			key:          "wallet-key",
+			expectedCode: http.StatusForbidden,
+			expectedBody: `{"error":"failed get read-balance permission on ledger: savings","code":"forbidden"}`,
+		},
+		{
+			name:         "should authorise debit and pass body to handler",
+			method:       http.MethodPost,
+			path:         "/ledger/wallet/transaction",
+			key:          "wallet-key",
+			body:         `{"type": "debit", "amount": 5}`,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"type": "debit", "amount": 5}`,
+		},
+		{
+			name:         "should reject credit without credit permission",
+			method:       http.MethodPost,
+			path:         "/ledger/wallet/transaction",
+			key:          "wallet-key",
+			body:         `{"type": "credit", "amount": 5}`,
+			expectedCode: http.StatusForbidden,
+			expectedBody: `{"error":"failed get credit permission on ledger: wallet","code":"forbidden"}`,
+		},
+		{
+			name:         "should reject malformed transaction payload",
+			method:       http.MethodPost,
+			path:         "/ledger/wallet/transaction",
+			key:          "wallet-key",
+			body:         `{"type": `,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"failed to authorise request, got error: failed get valid request payload","code":"invalid_request"}`,
+		},
+		{
+			name:         "should reject transaction payload over size limit",
+			method:       http.MethodPost,
+			path:         "/ledger/wallet/transaction",
+			key:          "wallet-key",
+			body:         `{"type": "debit", "description": "` + strings.Repeat("x", 1<<20) + `"}`,
+			expectedCode: http.StatusRequestEntityTooLarge,
+			expectedBody: `{"error":"failed to authorise request, got error: failed get request payload within 1048576 bytes","code":"payload_too_large"}`,
+		},
+		{
+			name:         "should reject transfer without credit permission on destination",
+			method:       http.MethodPost,
+			path:         "/transfers",
+			key:          "wallet-key",
+			body:         `{"sourceLedgerId": "wallet", "destinationLedgerId": "savings", "amount": 5}`,
+			expectedCode: http.StatusForbidden,
+			expectedBody: `{"error":"failed get credit permission on ledger: savings","code":"forbidden"}`,
+		},
+		{
+			name:         "should authorise transfer with admin key",
+			method:       http.MethodPost,
+			path:         "/transfers",
+			key:          "admin-key",
+			body:         `{"sourceLedgerId": "wallet", "destinationLedgerId": "savings", "amount": 5}`,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"sourceLedgerId": "wallet", "destinationLedgerId": "savings", "amount": 5}`,
+		},
+		{
+			name:         "should reject journal entry with leg on other ledger",
+			method:       http.MethodPost,
+			path:         "/journal-entries",
+			key:          "wallet-key",
+			body:         `{"legs": [{"ledgerId": "wallet", "type": "debit"}, {"ledgerId": "savings", "type": "credit"}]}`,
+			expectedCode: http.StatusForbidden,
+			expectedBody: `{"error":"failed get credit permission on ledger: savings","code":"forbidden"}`,
+		},
+		{
+			name:         "should authorise capture of hold on granted ledger",
+			method:       http.MethodPost,
+			path:         "/holds/" + hold.ID + "/capture",
+			key:          "wallet-key",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "should reject capture of unknown hold",
+			method:       http.MethodPost,
+			path:         "/holds/unknown/capture",
+			key:          "wallet-key",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "should reject reversal of transfer without debit permission on destination",
+			method:       http.MethodPost,
+			path:         "/ledger/wallet/transactions/" + transfer.Debit.ID + "/reverse",
+			key:          "wallet-admin-key",
+			expectedCode: http.StatusForbidden,
+			expectedBody: `{"error":"failed get debit permission on ledger: ` + savings.ID + `","code":"forbidden"}`,
+		},
+		{
+			name:         "should authorise reversal of transfer with admin key",
+			method:       http.MethodPost,
+			path:         "/ledger/wallet/transactions/" + transfer.Debit.ID + "/reverse",
+			key:          "admin-key",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "should reject reversal without admin permission",
+			method:       http.MethodPost,
+			path:         "/ledger/wallet/transactions/" + withdrawal.ID + "/reverse",
+			key:          "wallet-key",
+			expectedCode: http.StatusForbidden,
+			expectedBody: `{"error":"failed get admin permission on ledger: wallet","code":"forbidden"}`,
+		},
+		{
+			name:         "should reject reversal of withdrawal without debit permission on counter account",
+			method:       http.MethodPost,
+			path:         "/ledger/wallet/transactions/" + withdrawal.ID + "/reverse",
+			key:          "wallet-admin-key",
+			expectedCode: http.StatusForbidden,
+			expectedBody: `{"error":"failed get debit permission on ledger: counter-eur","code":"forbidden"}`,
+		},
+		{
+			name:         "should reject reversal of unknown transaction",
+			method:       http.MethodPost,
+			path:         "/ledger/wallet/transactions/unknown/reverse",
+			key:          "admin-key",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "should reject listing ledgers without access to every ledger",
+			method:       http.MethodGet,
+			path:         "/ledgers",
+			key:          "wallet-key",
+			expectedCode: http.StatusForbidden,
+			expectedBody: `{"error":"failed get read-balance permission on ledger: *","code":"forbidden"}`,
+		},
+		{
+			name:         "should reject admin route without admin permission",
+			method:       http.MethodPut,
+			path:         "/admin/ledgers/wallet/policy",
+			key:          "wallet-key",
+			expectedCode: http.StatusForbidden,
+			expectedBody: `{"error":"failed get admin permission on ledger: wallet","code":"forbidden"}`,
+		},
+		{
+			name:         "should authorise admin route with admin permission",
+			method:       http.MethodPut,
+			path:         "/admin/ledgers/wallet/policy",
+			key:          "admin-key",
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.key != "" {
+				req.Header.Set(ledger.APIKeyHeader, tt.key)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestNilAuthAuthorisesEveryRequest(t *testing.T) {
+	var auth *ledger.Auth
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/ledger/:ledgerId/transaction", auth.Authenticate(), auth.AuthorizeTransaction(), echoBody)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ledger/wallet/transaction", strings.NewReader(`{"type": "credit"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"type": "credit"}`, w.Body.String())
+}
+
+func TestNewAuth(t *testing.T) {
+	tests := []struct {
+		name        string
+		key         ledger.APIKey
+		expectedErr string
+	}{
+		{
+			name:        "should reject key without id",
+			key:         ledger.APIKey{Hash: ledger.HashAPIKey("key"), Permissions: []ledger.Permission{ledger.PermissionDebit}, Ledgers: []string{"wallet"}},
+			expectedErr: "failed get api key id",
+		},
+		{
+			name:        "should reject key with malformed hash",
+			key:         ledger.APIKey{ID: "client", Hash: "key", Permissions: []ledger.Permission{ledger.PermissionDebit}, Ledgers: []string{"wallet"}},
+			expectedErr: "failed get hex sha-256 hash of api key client",
+		},
+		{
+			name:        "should reject key with unknown permission",
+			key:         ledger.APIKey{ID: "client", Hash: ledger.HashAPIKey("key"), Permissions: []ledger.Permission{"withdraw"}, Ledgers: []string{"wallet"}},
+			expectedErr: `failed get known permission of api key client, got "withdraw"`,
+		},
+		{
+			name:        "should reject key without ledgers",
+			key:         ledger.APIKey{ID: "client", Hash: ledger.HashAPIKey("key"), Permissions: []ledger.Permission{ledger.PermissionDebit}},
+			expectedErr: "failed get ledgers of api key client",
+		},
+		{
+			name:        "should reject key reusing id",
+			key:         ledger.APIKey{ID: "wallet", Hash: ledger.HashAPIKey("other-key"), Permissions: []ledger.Permission{ledger.PermissionDebit}, Ledgers: []string{"wallet"}},
+			expectedErr: "failed get unique api key id, got wallet twice",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ledger.NewAuth(append(append([]ledger.APIKey{}, apiKeys...), tt.key), nil, newConcurrentStore())
+			assert.EqualError(t, err, tt.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package ledger
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// ChainVerification represents the result of walking the hash chain of a ledger's transactions.
+// Checked is the number of transactions whose hash matched before the first broken link, and Head
+// is the hash of the last of them. Unhashed is the number of leading transactions recorded before
+// transactions were chained, which are chained as they are loaded instead of checked against a
+// recorded hash. BalanceMismatch is set when the chain holds but the balance of the ledger is not
+// the running balance of its last transaction.
+type ChainVerification struct {
+	LedgerID        string           `json:"ledgerId"`
+	Verified        bool             `json:"verified"`
+	Checked         int              `json:"checked"`
+	Unhashed        int              `json:"unhashed,omitempty"`
+	Head            string           `json:"head,omitempty"`
+	BrokenLink      *BrokenLink      `json:"brokenLink,omitempty"`
+	BalanceMismatch *BalanceMismatch `json:"balanceMismatch,omitempty"`
+}
+
+// BalanceMismatch represents a ledger whose balance differs from the running balance of its last
+// transaction, zero when it has none
+type BalanceMismatch struct {
+	Balance        Money `json:"balance"`
+	RunningBalance Money `json:"runningBalance"`
+}
+
+// BrokenLink represents the first transaction of a ledger whose recorded hash does not match the
+// hash of its content chained over the previous transaction. Sequence is the position of the
+// transaction in the ledger, from 1. Table is set when the chain holds but the table the store
+// keeps the transactions in for querying does not match it; ExpectedHash is then the hash of the
+// transaction in the chain and RecordedHash the hash of the transaction as the table records it.
+type BrokenLink struct {
+	Sequence      int    `json:"sequence"`
+	TransactionID string `json:"transactionId"`
+	PreviousHash  string `json:"previousHash"`
+	ExpectedHash  string `json:"expectedHash"`
+	RecordedHash  string `json:"recordedHash"`
+	Table         string `json:"table,omitempty"`
+}
+
+// chainHash returns the hash of the transaction chained over the hash of the previous transaction
+// of its ledger, empty for the first one. It covers every recorded field of the transaction except
+// ReversedBy, which is filled in when read, and the hash itself.
+func chainHash(previousHash string, t Transaction) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%s\x00%d\x00%s\x00%s\x00%d\x00%d\x00%s\x00%s\x00%s\x00%s",
+		previousHash, t.ID, t.LedgerID, t.Date, t.Type, t.Description, t.Amount.units, t.RunningBalance.units,
+		t.JournalEntryID, t.TransferID, t.IdempotencyKey, t.ReversalOf)))
+	return hex.EncodeToString(sum[:])
+}
+
+// head returns the hash of the last transaction of the ledger, empty when it has none
+func (l *Ledger) head() string {
+	if n := len(l.Transactions); n > 0 {
+		return l.Transactions[n-1].Hash
+	}
+	return ""
+}
+
+// VerifyLedger walks the hash chain of the ledger's transactions in posting order and reports the
+// first transaction whose recorded hash does not match its content, then checks the balance of the
+// ledger against the running balance of its last transaction. A store keeping the
+// transactions in a table for querying has every row of the table checked against the chain too.
+func (s *store) VerifyLedger(ctx context.Context, ledgerId string) (ChainVerification, error) {
+	unlock := s.lockLedgers(ledgerId)
+	defer unlock()
+
+	ledger, err := s.getLedger(ledgerId)
+	if err != nil {
+		return ChainVerification{}, fmt.Errorf("failed to verify ledger, got error : %w", err)
+	}
+
+	rows, tabled, err := s.changes.transactions(ctx, ledgerId, len(ledger.Transactions))
+	if err != nil {
+		return ChainVerification{}, fmt.Errorf("failed to verify ledger, got error : failed get recorded transactions, got error : %w", err)
+	}
+
+	verification := ChainVerification{LedgerID: ledgerId, Verified: true, Unhashed: ledger.unhashed}
+	for i, t := range ledger.Transactions {
+		expected := chainHash(verification.Head, t)
+		if t.Hash != expected {
+			verification.BrokenLink = &BrokenLink{
+				Sequence:      i + 1,
+				TransactionID: t.ID,
+				PreviousHash:  verification.Head,
+				ExpectedHash:  expected,
+				RecordedHash:  t.Hash,
+			}
+			break
+		}
+		if tabled {
+			if verification.BrokenLink = rowLink(i, verification.Head, t, rows, i < ledger.unhashed); verification.BrokenLink != nil {
+				break
+			}
+		}
+		verification.Checked++
+		verification.Head = t.Hash
+	}
+	if verification.BrokenLink == nil && len(rows) > len(ledger.Transactions) {
+		// a row the chain lacks
+		extra := rows[len(ledger.Transactions)]
+		verification.BrokenLink = &BrokenLink{
+			Sequence:      len(ledger.Transactions) + 1,
+			TransactionID: extra.ID,
+			PreviousHash:  verification.Head,
+			RecordedHash:  extra.Hash,
+			Table:         transactionsTable,
+		}
+	}
+	if verification.BrokenLink == nil {
+		var running Money
+		if n := len(ledger.Transactions); n > 0 {
+			running = ledger.Transactions[n-1].RunningBalance
+		}
+		if ledger.balance.Cmp(running) != 0 {
+			verification.BalanceMismatch = &BalanceMismatch{Balance: ledger.balance, RunningBalance: running}
+		}
+	}
+	verification.Verified = verification.BrokenLink == nil && verification.BalanceMismatch == nil
+
+	if verification.Verified {
+		loggerFrom(ctx).Info("verified ledger", zap.String("ledgerId", ledgerId), zap.Int("transactions", verification.Checked))
+	} else if verification.BalanceMismatch != nil {
+		loggerFrom(ctx).Warn("found balance of ledger not matching its transactions",
+			zap.String("ledgerId", ledgerId),
+			zap.Stringer("balance", verification.BalanceMismatch.Balance),
+			zap.Stringer("runningBalance", verification.BalanceMismatch.RunningBalance))
+	} else {
+		loggerFrom(ctx).Warn("found broken link in ledger",
+			zap.String("ledgerId", ledgerId),
+			zap.Int("sequence", verification.BrokenLink.Sequence),
+			zap.String("transactionId", verification.BrokenLink.TransactionID),
+			zap.String("table", verification.BrokenLink.Table))
+	}
+	return verification, nil
+}
+
+// transactionsTable is the table the SQL store keeps the transactions in for querying
+const transactionsTable = "transactions"
+
+// rowLink checks the row of the transactions table at index i against the transaction of the
+// chain at the same position and returns the broken link when they differ. A transaction recorded
+// before transactions were chained has no hash in its row either.
+func rowLink(i int, previousHash string, t Transaction, rows []Transaction, unhashed bool) *BrokenLink {
+	link := &BrokenLink{Sequence: i + 1, TransactionID: t.ID, PreviousHash: previousHash, ExpectedHash: t.Hash, Table: transactionsTable}
+	if i >= len(rows) {
+		return link
+	}
+
+	row := rows[i]
+	content := chainHash(previousHash, row)
+	recorded := row.Hash
+	if recorded == "" && unhashed {
+		recorded = content
+	}
+	if content != t.Hash {
+		// the content of the row differs from the transaction
+		link.RecordedHash = content
+		return link
+	}
+	if recorded != t.Hash {
+		link.RecordedHash = row.Hash
+		return link
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package ledger_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreChainsTransactions(t *testing.T) {
+	storeInstance := newFundedStore(t)
+	savings, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "savings", Currency: "EUR"})
+	require.NoError(t, err)
+
+	transfer, err := storeInstance.Transfer(context.Background(), ledger.TransferRequestDTO{
+		SourceLedgerID: "wallet", DestinationLedgerID: savings.ID, Currency: "EUR", Amount: ledger.MustParseMoney("30"),
+	})
+	require.NoError(t, err)
+	hold, err := storeInstance.PlaceHold(context.Background(), "wallet", holdFor("10"))
+	require.NoError(t, err)
+	_, err = storeInstance.CaptureHold(context.Background(), hold.ID, ledger.CaptureRequestDTO{})
+	require.NoError(t, err)
+	// the reversal fills in ReversedBy of the transfer, which the hash does not cover
+	_, err = storeInstance.ReverseTransaction(context.Background(), "wallet", transfer.Debit.ID, ledger.ReversalRequestDTO{})
+	require.NoError(t, err)
+
+	for _, id := range []string{"wallet", savings.ID, "counter-eur"} {
+		transactions := allTransactions(t, storeInstance, id)
+		hashes := make(map[string]bool)
+		for _, tx := range transactions {
+			assert.Len(t, tx.Hash, 64)
+			hashes[tx.Hash] = true
+		}
+		assert.Len(t, hashes, len(transactions))
+
+		verification, err := storeInstance.VerifyLedger(context.Background(), id)
+		require.NoError(t, err)
+		assert.True(t, verification.Verified, id)
+		assert.Nil(t, verification.BrokenLink)
+		assert.Equal(t, len(transactions), verification.Checked)
+		assert.Equal(t, transactions[len(transactions)-1].Hash, verification.Head)
+	}
+
+	_, err = storeInstance.VerifyLedger(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ledger.ErrNotFound)
+}
+
+func TestStoreChainsTransactionsRecordedBeforeHashing(t *testing.T) {
+	ledgers := newLedgersWithCounterAccount()
+	ledgers["wallet"] = &ledger.Ledger{
+		ID:       "wallet",
+		Type:     "cash",
+		Currency: "EUR",
+		Transactions: []ledger.Transaction{
+			{ID: "tx1", LedgerID: "wallet", Type: ledger.Credit, Amount: ledger.MustParseMoney("50"), RunningBalance: ledger.MustParseMoney("50")},
+			{ID: "tx2", LedgerID: "wallet", Type: ledger.Debit, Amount: ledger.MustParseMoney("20"), RunningBalance: ledger.MustParseMoney("30")},
+		},
+	}
+	storeInstance := ledger.NewStore(ledger.NewUUIDGenerator(), ledgers, counterAccountOptions)
+
+	_, err := storeInstance.Credit(context.Background(), "wallet", ledger.TransactionRequestDTO{
+		Type: ledger.Credit, Description: "deposit", Currency: "EUR", Amount: ledger.MustParseMoney("5"),
+	})
+	require.NoError(t, err)
+
+	verification, err := storeInstance.VerifyLedger(context.Background(), "wallet")
+	require.NoError(t, err)
+	assert.True(t, verification.Verified)
+	assert.Equal(t, 3, verification.Checked)
+	for _, tx := range allTransactions(t, storeInstance, "wallet") {
+		assert.NotEmpty(t, tx.Hash, tx.ID)
+	}
+}
@@ -0,0 +1,255 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxStaleRetries is how many times an operation is run again after another writer changed one of
+// its ledgers before the conflict is returned
+const maxStaleRetries = 3
+
+// errReadOnly is returned for a change to a store opened read-only
+var errReadOnly = errors.New("failed to record change to read-only store")
+
+// errStaleLedger is the kind of errors for a change built on a ledger that another writer to the
+// same database has changed since it was loaded
+var errStaleLedger = errors.New("stale ledger")
+
+// changeKind identifies what a change does to the store
+type changeKind string
+
+const (
+	changeLedgerCreated   changeKind = "ledger-created"
+	changeLedgerClosed    changeKind = "ledger-closed"
+	changeLedgerPolicySet changeKind = "ledger-policy-set"
+	changeJournalEntry    changeKind = "journal-entry"
+	changeHoldPlaced      changeKind = "hold-placed"
+	changeHoldCaptured    changeKind = "hold-captured"
+	changeHoldReleased    changeKind = "hold-released"
+	changeHoldExpired     changeKind = "hold-expired"
+)
+
+// change is a single durable change to the store. Ledger is set for ledger changes, Hold for hold
+// changes with the hold as it is after the change, and Entry for journal entries and captured
+// holds; Idempotency is set when the journal entry was requested with a key. Date is set for
+// changes without a date of their own, such as a new balance policy.
+type change struct {
+	Kind        changeKind         `json:"kind"`
+	Ledger      *Ledger            `json:"ledger,omitempty"`
+	Hold        *Hold              `json:"hold,omitempty"`
+	Entry       *JournalEntry      `json:"entry,omitempty"`
+	Idempotency *idempotentRequest `json:"idempotency,omitempty"`
+	Date        int64              `json:"date,omitempty"`
+}
+
+// idempotentRequest identifies the request, by ledger and idempotency key, that produced a journal entry
+type idempotentRequest struct {
+	LedgerID    string `json:"ledgerId"`
+	Key         string `json:"key"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// changeLog durably records the changes to the store, along with the ledger events each change
+// adds, before they are applied in memory, and the snapshots of the ledgers the store takes, from
+// which the ledgers are restored on startup. A change log shared with other writers fails a change
+// with errStaleLedger when one of its ledgers changed since it was loaded. reload then brings the
+// ledger up to date with the changes of the other writers, or loads a ledger they created, and
+// holdLedger finds the ledger of a hold they placed, empty when no writer did. A change log keeping
+// the transactions in a table for querying returns the first count transactions of a ledger from
+// it with transactions, so that they are verified against the chain of the ledger.
+type changeLog interface {
+	append(c change, events []Event) error
+	recordSnapshot(snapshot LedgerState) error
+	reload(ctx context.Context, s *store, ledgerId string) error
+	holdLedger(ctx context.Context, holdId string) (string, error)
+	transactions(ctx context.Context, ledgerId string, count int) ([]Transaction, bool, error)
+	ping(ctx context.Context) error
+	close() error
+}
+
+// memoryChangeLog keeps no record of changes, so the store only lives as long as the process
+type memoryChangeLog struct{}
+
+func (memoryChangeLog) append(change, []Event) error { return nil }
+
+func (memoryChangeLog) recordSnapshot(LedgerState) error { return nil }
+
+func (memoryChangeLog) reload(context.Context, *store, string) error { return nil }
+
+func (memoryChangeLog) holdLedger(context.Context, string) (string, error) { return "", nil }
+
+func (memoryChangeLog) transactions(context.Context, string, int) ([]Transaction, bool, error) {
+	return nil, false, nil
+}
+
+func (memoryChangeLog) ping(context.Context) error { return nil }
+
+func (memoryChangeLog) close() error { return nil }
+
+// Ping checks that the store can still record changes
+func (s *store) Ping(ctx context.Context) error {
+	if err := s.changes.ping(ctx); err != nil {
+		return fmt.Errorf("failed to ping store, got error : %w", err)
+	}
+
+	return nil
+}
+
+// Close releases the resources held by the store
+func (s *store) Close() error {
+	if err := s.changes.close(); err != nil {
+		return fmt.Errorf("failed to close store, got error : %w", err)
+	}
+
+	return nil
+}
+
+// commit turns the change into the events it adds to the history of each ledger, durably records
+// them and then applies them to the in-memory ledgers.
+// The caller must hold the locks of the ledgers the change touches, unless it creates a ledger.
+func (s *store) commit(c change) error {
+	events, err := s.eventsOf(c)
+	if err != nil {
+		return fmt.Errorf("failed to record %s, got error : %w", c.Kind, err)
+	}
+
+	if err := s.changes.append(c, events); err != nil {
+		if errors.Is(err, errStaleLedger) {
+			err = errors.Join(err, s.reloadLedgers(events))
+		}
+		return fmt.Errorf("failed to record %s, got error : %w", c.Kind, err)
+	}
+
+	if err := s.applyChange(c, events); err != nil {
+		return err
+	}
+
+	// only committed transactions are counted, not the ones replayed on startup
+	for _, e := range events {
+		if e.Transaction != nil {
+			ledger, err := s.getLedger(e.LedgerID)
+			if err != nil {
+				return err
+			}
+			s.metrics.recordTransaction(ledger, *e.Transaction)
+		}
+	}
+
+	s.recordSnapshots(events)
+	return nil
+}
+
+// recordSnapshots records the snapshots the events of a committed change took. The change is
+// already durable, so a snapshot that fails to be recorded only leaves more events to fold on
+// startup. The caller must hold the locks of the ledgers, unless it created a ledger.
+func (s *store) recordSnapshots(events []Event) {
+	for _, e := range events {
+		if e.Version%int64(s.snapshotInterval) != 0 {
+			continue
+		}
+
+		var snapshot LedgerState
+		if e.Type == EventLedgerOpened {
+			// a created ledger is not locked, so its snapshot is taken from the event opening it
+			snapshot = LedgerState{Ledger: e.Ledger.metadata()}
+		} else {
+			ledger, err := s.getLedger(e.LedgerID)
+			if err != nil {
+				continue
+			}
+			snapshot = ledger.snapshots[len(ledger.snapshots)-1]
+		}
+		if err := s.changes.recordSnapshot(snapshot); err != nil {
+			zap.L().Warn("failed to record ledger snapshot", zap.String("ledgerId", e.LedgerID), zap.Int64("version", e.Version), zap.Error(err))
+		}
+	}
+}
+
+// reloadLedgers brings the ledgers of the events up to date with the changes other writers
+// recorded on them, so that a stale change can be built again on their current state.
+// The caller must hold the locks of the ledgers.
+func (s *store) reloadLedgers(events []Event) error {
+	var ids []string
+	for _, e := range events {
+		if !slices.Contains(ids, e.LedgerID) {
+			ids = append(ids, e.LedgerID)
+		}
+	}
+
+	for _, id := range ids {
+		if err := s.changes.reload(context.Background(), s, id); err != nil {
+			return fmt.Errorf("failed to reload ledger %s, got error : %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// reloadLedger brings the ledger up to date with the changes other writers recorded on it, or loads
+// it when another writer created it. A ledger that fails to reload is left as loaded, as a change
+// built on it still fails while it is stale. The caller must hold the lock of a known ledger.
+func (s *store) reloadLedger(ledgerId string) {
+	if err := s.changes.reload(context.Background(), s, ledgerId); err != nil {
+		zap.L().Warn("failed to reload ledger, keeping ledger as loaded", zap.String("ledgerId", ledgerId), zap.Error(err))
+	}
+}
+
+// retryStale runs op, and runs it again while it fails on a ledger another writer changed
+// meanwhile. commit reloads the ledger before failing, so the next run builds the change on its
+// current state. Each store operation committing a change runs its unexported counterpart through
+// it, at most maxStaleRetries times again.
+func retryStale[T any](op func() (T, error)) (T, error) {
+	result, err := op()
+	for retries := 0; retries < maxStaleRetries && errors.Is(err, errStaleLedger); retries++ {
+		result, err = op()
+	}
+	return result, err
+}
+
+// applyChange applies the events of the change and remembers the idempotency key of a journal entry
+func (s *store) applyChange(c change, events []Event) error {
+	for _, e := range events {
+		if err := s.applyEvent(e); err != nil {
+			return err
+		}
+	}
+
+	s.rememberIdempotency(c)
+	return nil
+}
+
+// rememberIdempotency remembers the idempotency key of the change if it is a journal entry
+// requested with one
+func (s *store) rememberIdempotency(c change) {
+	if c.Kind != changeJournalEntry || c.Idempotency == nil {
+		return
+	}
+
+	for _, tx := range c.Entry.Transactions {
+		if tx.LedgerID == c.Idempotency.LedgerID {
+			s.idempotency.remember(c.Idempotency.LedgerID, c.Idempotency.Key, c.Idempotency.Fingerprint, tx, time.UnixMilli(c.Entry.Date))
+			return
+		}
+	}
+}
+
+// indexTransaction records the ledger of the transaction against its journal entry in entries and,
+// for a reversal, links the reversed transaction to it. The caller must hold mu and the ledger lock.
+func indexTransaction(entries map[string][]string, ledger *Ledger, tx Transaction) {
+	if tx.JournalEntryID != "" && !slices.Contains(entries[tx.JournalEntryID], tx.LedgerID) {
+		entries[tx.JournalEntryID] = append(entries[tx.JournalEntryID], tx.LedgerID)
+	}
+
+	if tx.ReversalOf != "" {
+		if ledger.reversedBy == nil {
+			ledger.reversedBy = make(map[string]string)
+		}
+		ledger.reversedBy[tx.ReversalOf] = tx.ID
+	}
+}
@@ -0,0 +1,49 @@
+package ledger
+
+import "fmt"
+
+// Currency is an ISO-4217 alphabetic currency code, e.g. "EUR"
+type Currency string
+
+// currencyMinorUnits holds the number of decimal places for each supported currency
+var currencyMinorUnits = map[Currency]int{
+	"AUD": 2,
+	"BHD": 3,
+	"CAD": 2,
+	"CHF": 2,
+	"CZK": 2,
+	"DKK": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"HKD": 2,
+	"HUF": 2,
+	"ISK": 0,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"NOK": 2,
+	"NZD": 2,
+	"OMR": 3,
+	"PLN": 2,
+	"SEK": 2,
+	"SGD": 2,
+	"USD": 2,
+}
+
+// Validate returns an error if the currency is not a supported ISO-4217 code
+func (c Currency) Validate() error {
+	if _, ok := currencyMinorUnits[c]; !ok {
+		return fmt.Errorf("failed get supported currency: %q", string(c))
+	}
+	return nil
+}
+
+// MinorUnits returns the number of decimal places allowed for amounts in the currency
+func (c Currency) MinorUnits() int {
+	return currencyMinorUnits[c]
+}
+
+// Allows reports whether the amount has no more decimal places than the currency permits
+func (c Currency) Allows(m Money) bool {
+	return m.Precision() <= c.MinorUnits()
+}
@@ -0,0 +1,43 @@
+package ledger_test
+
+import (
+	"testing"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCurrency(t *testing.T) {
+	tests := []struct {
+		name          string
+		currency      ledger.Currency
+		amount        string
+		expectError   bool
+		expectedUnits int
+		expectAllowed bool
+	}{
+		{name: "EUR allows cents", currency: "EUR", amount: "10.25", expectedUnits: 2, expectAllowed: true},
+		{name: "EUR rejects fractions of a cent", currency: "EUR", amount: "10.255", expectedUnits: 2, expectAllowed: false},
+		{name: "GBP allows whole pounds", currency: "GBP", amount: "10", expectedUnits: 2, expectAllowed: true},
+		{name: "JPY allows whole yen", currency: "JPY", amount: "1000", expectedUnits: 0, expectAllowed: true},
+		{name: "JPY rejects fractions of a yen", currency: "JPY", amount: "1000.5", expectedUnits: 0, expectAllowed: false},
+		{name: "KWD allows three decimal places", currency: "KWD", amount: "1.125", expectedUnits: 3, expectAllowed: true},
+		{name: "Lower case code is not supported", currency: "eur", amount: "1", expectError: true},
+		{name: "Unknown code is not supported", currency: "XYZ", amount: "1", expectError: true},
+		{name: "Empty code is not supported", currency: "", amount: "1", expectError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.currency.Validate()
+
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedUnits, tc.currency.MinorUnits())
+				assert.Equal(t, tc.expectAllowed, tc.currency.Allows(ledger.MustParseMoney(tc.amount)))
+			}
+		})
+	}
+}
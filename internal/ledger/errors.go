@@ -0,0 +1,46 @@
+package ledger
+
+import "errors"
+
+// The kinds of failure reported by the store. Store errors keep their descriptive message and are
+// matched to their kind with errors.Is.
+var (
+	// ErrNotFound is the kind of errors for an unknown ledger, transaction or hold
+	ErrNotFound = errors.New("not found")
+	// ErrInsufficientFunds is the kind of errors for a debit or hold the balance policy of the ledger rejects
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrLedgerClosed is the kind of errors for a change to a closed ledger
+	ErrLedgerClosed = errors.New("ledger closed")
+	// ErrValidation is the kind of errors for a request the store rejects as invalid
+	ErrValidation = errors.New("validation failed")
+	// ErrConflict is the kind of errors for a request conflicting with the current state, such as
+	// capturing a released hold or reversing a transaction twice
+	ErrConflict = errors.New("conflict")
+	// ErrUnauthenticated is the kind of errors for a request without a valid API key
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrForbidden is the kind of errors for a request its API key is not granted the permission for
+	ErrForbidden = errors.New("forbidden")
+	// ErrRateLimited is the kind of errors for a request of a client or to a ledger over its rate limit
+	ErrRateLimited = errors.New("rate limited")
+	// ErrPayloadTooLarge is the kind of errors for a request payload over the size read to route it
+	ErrPayloadTooLarge = errors.New("payload too large")
+)
+
+// kindError gives an error a kind without changing its message
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string { return e.err.Error() }
+
+func (e *kindError) Unwrap() []error { return []error{e.kind, e.err} }
+
+// withKind marks the error as a failure of the kind, so that errors.Is reports the kind through
+// any later wrapping
+func withKind(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &kindError{kind: kind, err: err}
+}
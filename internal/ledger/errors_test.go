@@ -0,0 +1,133 @@
+package ledger_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreErrorKinds(t *testing.T) {
+	debit := func(amount string) ledger.TransactionRequestDTO {
+		return ledger.TransactionRequestDTO{Type: ledger.Debit, Description: "payment", Currency: "EUR", Amount: ledger.MustParseMoney(amount)}
+	}
+
+	tests := []struct {
+		name         string
+		operation    func(ledger.Store) error
+		expectedKind error
+	}{
+		{
+			name: "Unknown ledger",
+			operation: func(s ledger.Store) error {
+				_, err := s.GetLastBalance(context.Background(), "unknown")
+				return err
+			},
+			expectedKind: ledger.ErrNotFound,
+		},
+		{
+			name: "Unknown hold",
+			operation: func(s ledger.Store) error {
+				_, err := s.CaptureHold(context.Background(), "unknown", ledger.CaptureRequestDTO{})
+				return err
+			},
+			expectedKind: ledger.ErrNotFound,
+		},
+		{
+			name: "Unknown transaction",
+			operation: func(s ledger.Store) error {
+				_, err := s.ReverseTransaction(context.Background(), "wallet", "unknown", ledger.ReversalRequestDTO{})
+				return err
+			},
+			expectedKind: ledger.ErrNotFound,
+		},
+		{
+			name: "Debit beyond the balance policy",
+			operation: func(s ledger.Store) error {
+				_, err := s.Debit(context.Background(), "wallet", debit("100.01"))
+				return err
+			},
+			expectedKind: ledger.ErrInsufficientFunds,
+		},
+		{
+			name: "Hold beyond the balance policy",
+			operation: func(s ledger.Store) error {
+				_, err := s.PlaceHold(context.Background(), "wallet", holdFor("100.01"))
+				return err
+			},
+			expectedKind: ledger.ErrInsufficientFunds,
+		},
+		{
+			name: "Debit from closed ledger",
+			operation: func(s ledger.Store) error {
+				if _, err := s.CloseLedger(context.Background(), "wallet"); err != nil {
+					return err
+				}
+				_, err := s.Debit(context.Background(), "wallet", debit("1"))
+				return err
+			},
+			expectedKind: ledger.ErrLedgerClosed,
+		},
+		{
+			name: "Debit in other currency than the ledger",
+			operation: func(s ledger.Store) error {
+				trd := debit("1")
+				trd.Currency = "GBP"
+				_, err := s.Debit(context.Background(), "wallet", trd)
+				return err
+			},
+			expectedKind: ledger.ErrValidation,
+		},
+		{
+			name: "Unbalanced journal entry",
+			operation: func(s ledger.Store) error {
+				_, err := s.Post(context.Background(), ledger.JournalEntryRequestDTO{
+					Currency: "EUR",
+					Legs: []ledger.LegDTO{
+						{LedgerID: "wallet", Type: ledger.Debit, Amount: ledger.MustParseMoney("2")},
+						{LedgerID: "counter-eur", Type: ledger.Credit, Amount: ledger.MustParseMoney("1")},
+					},
+				})
+				return err
+			},
+			expectedKind: ledger.ErrValidation,
+		},
+		{
+			name: "Capture of released hold",
+			operation: func(s ledger.Store) error {
+				hold, err := s.PlaceHold(context.Background(), "wallet", holdFor("10"))
+				if err != nil {
+					return err
+				}
+				if _, err := s.ReleaseHold(context.Background(), hold.ID); err != nil {
+					return err
+				}
+				_, err = s.CaptureHold(context.Background(), hold.ID, ledger.CaptureRequestDTO{})
+				return err
+			},
+			expectedKind: ledger.ErrConflict,
+		},
+		{
+			name: "Closing a counter account",
+			operation: func(s ledger.Store) error {
+				_, err := s.CloseLedger(context.Background(), "counter-eur")
+				return err
+			},
+			expectedKind: ledger.ErrConflict,
+		},
+	}
+
+	kinds := []error{ledger.ErrNotFound, ledger.ErrInsufficientFunds, ledger.ErrLedgerClosed, ledger.ErrValidation, ledger.ErrConflict}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.operation(newFundedStore(t))
+			require.Error(t, err)
+			for _, kind := range kinds {
+				assert.Equal(t, kind == tt.expectedKind, errors.Is(err, kind), "kind %q", kind)
+			}
+		})
+	}
+}
@@ -0,0 +1,634 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultSnapshotInterval is the number of events between two snapshots of a ledger when no
+	// interval is configured
+	DefaultSnapshotInterval = 100
+	// DefaultEventPageSize is the number of events listed when no limit is requested
+	DefaultEventPageSize = 100
+	// MaxEventPageSize is the largest number of events listed in a single page
+	MaxEventPageSize = 1000
+)
+
+// EventType represents what happened to a ledger
+type EventType string
+
+const (
+	EventLedgerOpened EventType = "ledger-opened"
+	EventCredited     EventType = "credited"
+	EventDebited      EventType = "debited"
+	EventHoldPlaced   EventType = "hold-placed"
+	EventHoldCaptured EventType = "hold-captured"
+	EventHoldReleased EventType = "hold-released"
+	EventHoldExpired  EventType = "hold-expired"
+	EventPolicySet    EventType = "policy-set"
+	EventLedgerClosed EventType = "ledger-closed"
+)
+
+// Event represents one fact in the append-only history of a ledger. The events of a ledger are
+// numbered by Version from 1 without gaps, and the state of the ledger at any version is the fold
+// of its events up to that version. Ledger is set for the ledger events with the ledger as it is
+// after the event, Transaction for credits and debits, and Hold for hold events.
+type Event struct {
+	LedgerID    string       `json:"ledgerId"`
+	Version     int64        `json:"version"`
+	Type        EventType    `json:"type"`
+	Date        int64        `json:"date"`
+	Ledger      *Ledger      `json:"ledger,omitempty"`
+	Transaction *Transaction `json:"transaction,omitempty"`
+	Hold        *Hold        `json:"hold,omitempty"`
+	// unhashed marks the event of a transaction recorded before transactions were chained
+	unhashed bool
+}
+
+// LedgerState represents a ledger as it was after one version of its history: its metadata, its
+// posted balance and the sum of its active holds
+type LedgerState struct {
+	Ledger  Ledger `json:"ledger"`
+	Balance Money  `json:"balance"`
+	Held    Money  `json:"held"`
+}
+
+// EventQuery represents the page of a ledger's events; After is the version of the last event of
+// the previous page
+type EventQuery struct {
+	After int64
+	Limit int
+}
+
+// EventPage represents one page of the events of a ledger in version order
+type EventPage struct {
+	LedgerID  string  `json:"ledgerId"`
+	Events    []Event `json:"events"`
+	NextAfter int64   `json:"nextAfter,omitempty"`
+}
+
+// Projection builds a read model from the history of the ledgers, one event at a time
+type Projection interface {
+	Apply(e Event) error
+}
+
+// ProjectionFunc adapts a function to a Projection
+type ProjectionFunc func(e Event) error
+
+// Apply calls f with the event
+func (f ProjectionFunc) Apply(e Event) error {
+	return f(e)
+}
+
+// apply folds the event into the state of the ledger. Every change to the state of a ledger goes
+// through apply, both as changes are committed and when the ledger is rebuilt from its history.
+func (l *Ledger) apply(e Event) error {
+	if e.Version != l.Version+1 {
+		return fmt.Errorf("failed get event version %d of ledger %s, got %d", l.Version+1, e.LedgerID, e.Version)
+	}
+
+	switch e.Type {
+	case EventLedgerOpened:
+		if e.Ledger == nil {
+			return fmt.Errorf("failed get ledger for %s", e.Type)
+		}
+		*l = e.Ledger.metadata()
+
+	case EventCredited, EventDebited:
+		if e.Transaction == nil {
+			return fmt.Errorf("failed get transaction for %s", e.Type)
+		}
+		l.balance = e.Transaction.RunningBalance
+
+	case EventHoldPlaced:
+		if e.Hold == nil {
+			return fmt.Errorf("failed get hold for %s", e.Type)
+		}
+		l.held = l.held.Add(e.Hold.Amount)
+
+	case EventHoldCaptured, EventHoldReleased, EventHoldExpired:
+		if e.Hold == nil {
+			return fmt.Errorf("failed get hold for %s", e.Type)
+		}
+		l.held = l.held.Sub(e.Hold.Amount)
+
+	case EventPolicySet:
+		if e.Ledger == nil {
+			return fmt.Errorf("failed get ledger for %s", e.Type)
+		}
+		l.Policy = e.Ledger.Policy
+
+	case EventLedgerClosed:
+		if e.Ledger == nil {
+			return fmt.Errorf("failed get ledger for %s", e.Type)
+		}
+		l.Status = LedgerClosed
+		l.ClosedAt = e.Ledger.ClosedAt
+
+	default:
+		return fmt.Errorf("failed get known event type, got %q", e.Type)
+	}
+
+	l.Version = e.Version
+	return nil
+}
+
+// state returns the state of the ledger at its current version
+func (l *Ledger) state() LedgerState {
+	return LedgerState{Ledger: l.metadata(), Balance: l.balance, Held: l.held}
+}
+
+// matches reports whether the balance and held amount of the state agree with the events up to its
+// version: the running balance of the last transaction and the amounts of the holds still active
+func (st LedgerState) matches(events []Event) bool {
+	var balance, held Money
+	for _, e := range events[:st.Ledger.Version] {
+		switch e.Type {
+		case EventCredited, EventDebited:
+			if e.Transaction != nil {
+				balance = e.Transaction.RunningBalance
+			}
+		case EventHoldPlaced:
+			if e.Hold != nil {
+				held = held.Add(e.Hold.Amount)
+			}
+		case EventHoldCaptured, EventHoldReleased, EventHoldExpired:
+			if e.Hold != nil {
+				held = held.Sub(e.Hold.Amount)
+			}
+		}
+	}
+	return balance.Cmp(st.Balance) == 0 && held.Cmp(st.Held) == 0
+}
+
+// restore returns a ledger holding the state, from which later events can be folded
+func (st LedgerState) restore() *Ledger {
+	ledger := st.Ledger.metadata()
+	ledger.balance = st.Balance
+	ledger.held = st.Held
+	return &ledger
+}
+
+// eventsOf returns the events the change adds to the history of each ledger it touches, numbered
+// after the current version of the ledger. The caller must hold the locks of the ledgers the change
+// touches, unless it creates a ledger.
+func (s *store) eventsOf(c change) ([]Event, error) {
+	return changeEvents(c, func(ledgerId string) (int64, error) {
+		ledger, err := s.getLedger(ledgerId)
+		if err != nil {
+			return 0, err
+		}
+		return ledger.Version, nil
+	})
+}
+
+// changeEvents returns the events the change adds to the history of each ledger it touches,
+// numbered after the version of the ledger returned by version
+func changeEvents(c change, version func(ledgerId string) (int64, error)) ([]Event, error) {
+	switch c.Kind {
+	case changeLedgerCreated, changeLedgerClosed, changeLedgerPolicySet:
+		if c.Ledger == nil {
+			return nil, fmt.Errorf("failed get ledger for %s", c.Kind)
+		}
+	case changeJournalEntry:
+		if c.Entry == nil {
+			return nil, fmt.Errorf("failed get journal entry for %s", c.Kind)
+		}
+	case changeHoldPlaced, changeHoldReleased, changeHoldExpired:
+		if c.Hold == nil {
+			return nil, fmt.Errorf("failed get hold for %s", c.Kind)
+		}
+	case changeHoldCaptured:
+		if c.Hold == nil || c.Entry == nil {
+			return nil, fmt.Errorf("failed get hold and journal entry for %s", c.Kind)
+		}
+	default:
+		return nil, fmt.Errorf("failed get known change kind, got %q", c.Kind)
+	}
+
+	versions := make(map[string]int64)
+	if c.Kind == changeLedgerCreated {
+		// the ledger does not exist yet, and the caller holds mu so it cannot be looked up
+		versions[c.Ledger.ID] = 0
+	}
+	next := func(ledgerId string) (int64, error) {
+		current, ok := versions[ledgerId]
+		if !ok {
+			var err error
+			if current, err = version(ledgerId); err != nil {
+				return 0, err
+			}
+		}
+		versions[ledgerId] = current + 1
+		return current + 1, nil
+	}
+
+	var events []Event
+	ledgerEvent := func(eventType EventType, date int64) error {
+		version, err := next(c.Ledger.ID)
+		if err != nil {
+			return err
+		}
+		ledger := c.Ledger.metadata()
+		ledger.Version = version
+		events = append(events, Event{LedgerID: ledger.ID, Version: version, Type: eventType, Date: date, Ledger: &ledger})
+		return nil
+	}
+	entryEvents := func() error {
+		for _, tx := range c.Entry.Transactions {
+			version, err := next(tx.LedgerID)
+			if err != nil {
+				return err
+			}
+			eventType := EventCredited
+			if tx.Type == Debit {
+				eventType = EventDebited
+			}
+			events = append(events, Event{LedgerID: tx.LedgerID, Version: version, Type: eventType, Date: tx.Date, Transaction: &tx})
+		}
+		return nil
+	}
+	holdEvent := func(eventType EventType, date int64) error {
+		version, err := next(c.Hold.LedgerID)
+		if err != nil {
+			return err
+		}
+		hold := *c.Hold
+		events = append(events, Event{LedgerID: hold.LedgerID, Version: version, Type: eventType, Date: date, Hold: &hold})
+		return nil
+	}
+
+	var err error
+	switch c.Kind {
+	case changeLedgerCreated:
+		err = ledgerEvent(EventLedgerOpened, c.Ledger.CreatedAt)
+	case changeLedgerClosed:
+		err = ledgerEvent(EventLedgerClosed, c.Ledger.ClosedAt)
+	case changeLedgerPolicySet:
+		err = ledgerEvent(EventPolicySet, c.Date)
+	case changeJournalEntry:
+		err = entryEvents()
+	case changeHoldPlaced:
+		err = holdEvent(EventHoldPlaced, c.Hold.CreatedAt)
+	case changeHoldCaptured:
+		if err = entryEvents(); err == nil {
+			err = holdEvent(EventHoldCaptured, c.Hold.ClosedAt)
+		}
+	case changeHoldReleased:
+		err = holdEvent(EventHoldReleased, c.Hold.ClosedAt)
+	case changeHoldExpired:
+		err = holdEvent(EventHoldExpired, c.Hold.ClosedAt)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// historyEvents returns the events recreating a ledger from its metadata, its transactions and its
+// holds, for ledgers whose history predates their event stream. The ledger is opened with its
+// current policy and the holds follow the transactions, those without a hash marked unhashed.
+func historyEvents(ledger Ledger, transactions []Transaction, holds []Hold) []Event {
+	events := make([]Event, 0, len(transactions)+2*len(holds)+2)
+	add := func(e Event) {
+		e.LedgerID = ledger.ID
+		e.Version = int64(len(events) + 1)
+		events = append(events, e)
+	}
+
+	opened := ledger.metadata()
+	opened.Status = LedgerOpen
+	opened.ClosedAt = 0
+	opened.Version = 1
+	add(Event{Type: EventLedgerOpened, Date: opened.CreatedAt, Ledger: &opened})
+
+	for _, tx := range transactions {
+		eventType := EventCredited
+		if tx.Type == Debit {
+			eventType = EventDebited
+		}
+		add(Event{Type: eventType, Date: tx.Date, Transaction: &tx, unhashed: tx.Hash == ""})
+	}
+
+	for _, hold := range holds {
+		placed := hold
+		placed.Status = HoldActive
+		placed.ClosedAt = 0
+		placed.CapturedAmount = Money{}
+		placed.TransactionID = ""
+		add(Event{Type: EventHoldPlaced, Date: placed.CreatedAt, Hold: &placed})
+
+		switch hold.Status {
+		case HoldCaptured:
+			add(Event{Type: EventHoldCaptured, Date: hold.ClosedAt, Hold: &hold})
+		case HoldReleased:
+			add(Event{Type: EventHoldReleased, Date: hold.ClosedAt, Hold: &hold})
+		case HoldExpired:
+			add(Event{Type: EventHoldExpired, Date: hold.ClosedAt, Hold: &hold})
+		}
+	}
+
+	if ledger.Status == LedgerClosed {
+		closed := ledger.metadata()
+		closed.Version = int64(len(events) + 1)
+		add(Event{Type: EventLedgerClosed, Date: closed.ClosedAt, Ledger: &closed})
+	}
+
+	return events
+}
+
+// applyEvent folds the event into its ledger and records it in the history of the ledger. Every
+// snapshotInterval events a snapshot of the ledger is taken. The caller must hold the lock of the
+// ledger for any event but the one opening it.
+func (s *store) applyEvent(e Event) error {
+	if e.Type == EventLedgerOpened {
+		return s.openLedger(e)
+	}
+
+	ledger, err := s.getLedger(e.LedgerID)
+	if err != nil {
+		return err
+	}
+	return s.foldEvent(ledger, e)
+}
+
+// openLedger folds the event opening a ledger and only then adds the ledger to the store, so that
+// mu is held just to insert it. A configured ledger is rebuilt in place by newStore, so it keeps
+// its address.
+func (s *store) openLedger(e Event) error {
+	s.mu.RLock()
+	ledger := s.ledgers[e.LedgerID]
+	s.mu.RUnlock()
+	if ledger == nil {
+		ledger = &Ledger{}
+	}
+
+	if err := s.foldEvent(ledger, e); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.addLedger(ledger)
+	s.mu.Unlock()
+	return nil
+}
+
+// foldEvent folds the event into the ledger, records it and snapshots the ledger every interval
+func (s *store) foldEvent(ledger *Ledger, e Event) error {
+	if err := ledger.apply(e); err != nil {
+		return err
+	}
+	if err := s.recordEvent(ledger, e); err != nil {
+		return err
+	}
+	if e.Version%int64(s.snapshotInterval) == 0 {
+		ledger.snapshots = append(ledger.snapshots, ledger.state())
+	}
+
+	return nil
+}
+
+// addLedger adds the ledger to the store with its lock. The caller must hold mu.
+func (s *store) addLedger(ledger *Ledger) {
+	s.ledgers[ledger.ID] = ledger
+	if _, ok := s.locks[ledger.ID]; !ok {
+		s.locks[ledger.ID] = &sync.Mutex{}
+	}
+}
+
+// recordEvent appends the event, already folded into the ledger, to the history of the ledger and
+// updates the read models kept alongside: the transactions of the ledger, the holds and the
+// journal entry index
+func (s *store) recordEvent(ledger *Ledger, e Event) error {
+	switch e.Type {
+	case EventCredited, EventDebited:
+		if e.Transaction.Hash == "" && e.unhashed && ledger.unhashed == len(ledger.Transactions) {
+			// a transaction recorded before transactions were chained is chained as it is loaded,
+			// unless a chained transaction precedes it. Any other transaction without a hash is
+			// left a broken link of the chain
+			sealed := *e.Transaction
+			sealed.Hash = chainHash(ledger.head(), sealed)
+			e.Transaction = &sealed
+			ledger.unhashed++
+		}
+		s.mu.Lock()
+		ledger.Transactions = append(ledger.Transactions, *e.Transaction)
+		indexTransaction(s.entries, ledger, *e.Transaction)
+		s.mu.Unlock()
+
+	case EventHoldPlaced:
+		hold := *e.Hold
+		s.mu.Lock()
+		s.holds[hold.ID] = &hold
+		s.activeHolds[hold.ID] = hold.ExpiresAt
+		s.mu.Unlock()
+
+	case EventHoldCaptured, EventHoldReleased, EventHoldExpired:
+		s.mu.Lock()
+		hold, ok := s.holds[e.Hold.ID]
+		delete(s.activeHolds, e.Hold.ID)
+		s.mu.Unlock()
+		if !ok {
+			return fmt.Errorf("failed get hold: %s", e.Hold.ID)
+		}
+		// only the closing fields change, as the ledger and currency are read without the ledger
+		// lock by lockHold
+		hold.Status = e.Hold.Status
+		hold.ClosedAt = e.Hold.ClosedAt
+		hold.CapturedAmount = e.Hold.CapturedAmount
+		hold.TransactionID = e.Hold.TransactionID
+	}
+
+	s.metrics.recordLedgerEvent(ledger, e)
+	ledger.events = append(ledger.events, e)
+	return nil
+}
+
+// loadLedger adds a ledger to the store from the events recorded on it, in version order, and the
+// snapshots recorded of it. The ledger is restored from its latest snapshot matching its events and
+// only the events after it are folded; the events up to it are kept as the history of the ledger. Ledgers are only
+// loaded on startup, before the store is shared.
+func (s *store) loadLedger(events []Event, snapshots []LedgerState) error {
+	if len(events) == 0 || events[0].Type != EventLedgerOpened {
+		return errors.New("failed get ledger history opening with ledger-opened event")
+	}
+
+	// a snapshot whose balance or held amount its events do not account for is ignored
+	var valid []LedgerState
+	for _, snapshot := range snapshots {
+		if v := snapshot.Ledger.Version; v < 1 || v > int64(len(events)) {
+			continue
+		}
+		if !snapshot.matches(events) {
+			zap.L().Warn("ignoring ledger snapshot not matching its events",
+				zap.String("ledgerId", events[0].LedgerID), zap.Int64("version", snapshot.Ledger.Version))
+			continue
+		}
+		valid = append(valid, snapshot)
+	}
+	if len(valid) == 0 {
+		for _, e := range events {
+			if err := s.applyEvent(e); err != nil {
+				return fmt.Errorf("failed to apply event %d of ledger %s, got error : %w", e.Version, e.LedgerID, err)
+			}
+		}
+		return nil
+	}
+
+	latest := valid[len(valid)-1]
+	ledger := latest.restore()
+	s.mu.Lock()
+	s.addLedger(ledger)
+	s.mu.Unlock()
+	for i, e := range events[:latest.Ledger.Version] {
+		if e.Version != int64(i+1) {
+			return fmt.Errorf("failed get event version %d of ledger %s, got %d", i+1, e.LedgerID, e.Version)
+		}
+		if err := s.recordEvent(ledger, e); err != nil {
+			return fmt.Errorf("failed to record event %d of ledger %s, got error : %w", e.Version, e.LedgerID, err)
+		}
+	}
+	ledger.snapshots = append(ledger.snapshots, valid...)
+
+	for _, e := range events[latest.Ledger.Version:] {
+		if err := s.applyEvent(e); err != nil {
+			return fmt.Errorf("failed to apply event %d of ledger %s, got error : %w", e.Version, e.LedgerID, err)
+		}
+	}
+
+	return nil
+}
+
+// loadLedgers adds every ledger of the streams to the store with loadLedger, in id order. streams
+// maps each ledger to its events and snapshots to the snapshots recorded of it in version order.
+func (s *store) loadLedgers(streams map[string][]Event, snapshots map[string][]LedgerState) error {
+	ids := make([]string, 0, len(streams))
+	for id := range streams {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if err := s.loadLedger(streams[id], snapshots[id]); err != nil {
+			return fmt.Errorf("failed to load ledger %s, got error : %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// GetLedgerEvents returns a page of the events of the ledger in version order
+func (s *store) GetLedgerEvents(ctx context.Context, ledgerId string, query EventQuery) (EventPage, error) {
+	if query.After < 0 {
+		return EventPage{}, withKind(ErrValidation, fmt.Errorf("failed to get ledger events, got error : failed get after of at least 0, got %d", query.After))
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultEventPageSize
+	}
+	if limit > MaxEventPageSize {
+		limit = MaxEventPageSize
+	}
+
+	unlock := s.lockLedgers(ledgerId)
+	defer unlock()
+
+	ledger, err := s.getLedger(ledgerId)
+	if err != nil {
+		return EventPage{}, fmt.Errorf("failed to get ledger events, got error : %w", err)
+	}
+
+	// the event of version v is at index v-1
+	from := min(query.After, ledger.Version)
+	to := min(from+int64(limit), ledger.Version)
+	page := EventPage{LedgerID: ledgerId, Events: slices.Clone(ledger.events[from:to])}
+	if page.Events == nil {
+		page.Events = []Event{}
+	}
+	if to < ledger.Version {
+		page.NextAfter = to
+	}
+
+	loggerFrom(ctx).Info("got ledger events", zap.String("ledgerId", ledgerId), zap.Int64("after", query.After), zap.Int("events", len(page.Events)))
+	return page, nil
+}
+
+// GetLedgerAtVersion rebuilds the state of the ledger at the version by folding its events onto
+// the latest snapshot taken at or before that version
+func (s *store) GetLedgerAtVersion(ctx context.Context, ledgerId string, version int64) (LedgerState, error) {
+	unlock := s.lockLedgers(ledgerId)
+	defer unlock()
+
+	ledger, err := s.getLedger(ledgerId)
+	if err != nil {
+		return LedgerState{}, fmt.Errorf("failed to get ledger at version %d, got error : %w", version, err)
+	}
+
+	if version < 1 || version > ledger.Version {
+		return LedgerState{}, withKind(ErrNotFound, fmt.Errorf("failed get version %d of ledger %s, got versions 1 to %d", version, ledgerId, ledger.Version))
+	}
+
+	rebuilt := &Ledger{}
+	i := sort.Search(len(ledger.snapshots), func(i int) bool {
+		return ledger.snapshots[i].Ledger.Version > version
+	})
+	if i > 0 {
+		rebuilt = ledger.snapshots[i-1].restore()
+	}
+
+	for _, e := range ledger.events[rebuilt.Version:version] {
+		if err := rebuilt.apply(e); err != nil {
+			return LedgerState{}, fmt.Errorf("failed to get ledger at version %d, got error : %w", version, err)
+		}
+	}
+
+	loggerFrom(ctx).Info("rebuilt ledger at version", zap.String("ledgerId", ledgerId), zap.Int64("version", version))
+	return rebuilt.state(), nil
+}
+
+// Project feeds the events of every ledger to the projection, ledger by ledger in id order and
+// each ledger's events in version order. The events recorded on a ledger while the projection
+// runs are only fed to it if the ledger has not been projected yet.
+func (s *store) Project(ctx context.Context, projection Projection) error {
+	s.mu.RLock()
+	ids := make([]string, 0, len(s.ledgers))
+	for id := range s.ledgers {
+		ids = append(ids, id)
+	}
+	s.mu.RUnlock()
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("failed to project events, got error : %w", err)
+		}
+
+		ledger, err := s.getLedger(id)
+		if err != nil {
+			return fmt.Errorf("failed to project events, got error : %w", err)
+		}
+
+		// events are only ever appended, so the events up to the current version can be read
+		// after the lock is released
+		unlock := s.lockLedgers(id)
+		events := ledger.events[:ledger.Version:ledger.Version]
+		unlock()
+
+		for _, e := range events {
+			if err := projection.Apply(e); err != nil {
+				return fmt.Errorf("failed to project event %d of ledger %s, got error : %w", e.Version, id, err)
+			}
+		}
+	}
+
+	return nil
+}
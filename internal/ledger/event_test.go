@@ -0,0 +1,277 @@
+package ledger_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventTypes returns the type of every event of the ledger in version order, checking the versions
+// follow on each other from 1
+func eventTypes(t *testing.T, storeInstance ledger.Store, ledgerId string) []ledger.EventType {
+	page, err := storeInstance.GetLedgerEvents(context.Background(), ledgerId, ledger.EventQuery{Limit: ledger.MaxEventPageSize})
+	require.NoError(t, err)
+
+	types := make([]ledger.EventType, 0, len(page.Events))
+	for i, e := range page.Events {
+		assert.Equal(t, int64(i+1), e.Version, "version of event %d", i)
+		assert.Equal(t, ledgerId, e.LedgerID)
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+// ledgerStates returns the state of the ledger at every version of its history, with its current
+// balance last
+func ledgerStates(t *testing.T, storeInstance ledger.Store, ledgerId string) []any {
+	current, err := storeInstance.GetLedger(context.Background(), ledgerId)
+	require.NoError(t, err)
+
+	states := make([]any, 0, current.Version+1)
+	for version := int64(1); version <= current.Version; version++ {
+		state, err := storeInstance.GetLedgerAtVersion(context.Background(), ledgerId, version)
+		require.NoError(t, err)
+		states = append(states, state)
+	}
+	balance, err := storeInstance.GetLastBalance(context.Background(), ledgerId)
+	require.NoError(t, err)
+	return append(states, balance)
+}
+
+func TestStoreRecordsLedgerEvents(t *testing.T) {
+	storeInstance := newFundedStore(t)
+
+	captured, err := storeInstance.PlaceHold(context.Background(), "wallet", holdFor("10"))
+	require.NoError(t, err)
+	_, err = storeInstance.CaptureHold(context.Background(), captured.ID, ledger.CaptureRequestDTO{})
+	require.NoError(t, err)
+	released, err := storeInstance.PlaceHold(context.Background(), "wallet", holdFor("5"))
+	require.NoError(t, err)
+	_, err = storeInstance.ReleaseHold(context.Background(), released.ID)
+	require.NoError(t, err)
+	_, err = storeInstance.SetBalancePolicy(context.Background(), "wallet", ledger.BalancePolicy{Type: ledger.PolicyPositive})
+	require.NoError(t, err)
+	closed, err := storeInstance.CloseLedger(context.Background(), "wallet")
+	require.NoError(t, err)
+
+	assert.Equal(t, []ledger.EventType{
+		ledger.EventLedgerOpened,
+		ledger.EventCredited,
+		ledger.EventHoldPlaced,
+		ledger.EventDebited,
+		ledger.EventHoldCaptured,
+		ledger.EventHoldPlaced,
+		ledger.EventHoldReleased,
+		ledger.EventPolicySet,
+		ledger.EventLedgerClosed,
+	}, eventTypes(t, storeInstance, "wallet"))
+	assert.Equal(t, int64(9), closed.Version)
+
+	assert.Equal(t, []ledger.EventType{
+		ledger.EventLedgerOpened,
+		ledger.EventDebited,
+		ledger.EventCredited,
+	}, eventTypes(t, storeInstance, "counter-eur"))
+}
+
+func TestStoreRebuildsLedgerHistoryAsEvents(t *testing.T) {
+	ledgers := newLedgersWithCounterAccount()
+	ledgers["wallet"] = &ledger.Ledger{
+		ID:       "wallet",
+		Type:     "cash",
+		Currency: "EUR",
+		Status:   ledger.LedgerClosed,
+		Transactions: []ledger.Transaction{
+			{ID: "tx1", LedgerID: "wallet", Type: ledger.Credit, Amount: ledger.MustParseMoney("50"), RunningBalance: ledger.MustParseMoney("50")},
+			{ID: "tx2", LedgerID: "wallet", Type: ledger.Debit, Amount: ledger.MustParseMoney("20"), RunningBalance: ledger.MustParseMoney("30")},
+		},
+	}
+	storeInstance := ledger.NewStore(ledger.NewUUIDGenerator(), ledgers, counterAccountOptions)
+
+	assert.Equal(t, []ledger.EventType{
+		ledger.EventLedgerOpened,
+		ledger.EventCredited,
+		ledger.EventDebited,
+		ledger.EventLedgerClosed,
+	}, eventTypes(t, storeInstance, "wallet"))
+
+	wallet, err := storeInstance.GetLedger(context.Background(), "wallet")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), wallet.Version)
+	assert.Equal(t, ledger.LedgerClosed, wallet.Status)
+
+	balance, err := storeInstance.GetLastBalance(context.Background(), "wallet")
+	require.NoError(t, err)
+	assert.Equal(t, "30", balance.Balance.String())
+	assert.Len(t, allTransactions(t, storeInstance, "wallet"), 2)
+}
+
+func TestStoreGetLedgerAtVersion(t *testing.T) {
+	ledgers := newLedgersWithCounterAccount()
+	ledgers["wallet"] = &ledger.Ledger{ID: "wallet", Type: "cash", Currency: "EUR"}
+	opts := counterAccountOptions
+	opts.SnapshotInterval = 3
+	storeInstance := ledger.NewStore(ledger.NewUUIDGenerator(), ledgers, opts)
+
+	// states holds the state of the wallet after every version, as read while it was current
+	states := make(map[int64]ledger.LedgerState)
+	record := func() {
+		wallet, err := storeInstance.GetLedger(context.Background(), "wallet")
+		require.NoError(t, err)
+		balance, err := storeInstance.GetLastBalance(context.Background(), "wallet")
+		require.NoError(t, err)
+		states[wallet.Version] = ledger.LedgerState{
+			Ledger:  wallet,
+			Balance: balance.Balance,
+			Held:    balance.Balance.Sub(*balance.Available),
+		}
+	}
+
+	record()
+	for _, amount := range []string{"10", "20.5", "30"} {
+		_, err := storeInstance.Credit(context.Background(), "wallet", ledger.TransactionRequestDTO{
+			Type: ledger.Credit, Description: "deposit", Currency: "EUR", Amount: ledger.MustParseMoney(amount),
+		})
+		require.NoError(t, err)
+		record()
+
+		hold, err := storeInstance.PlaceHold(context.Background(), "wallet", holdFor("5"))
+		require.NoError(t, err)
+		record()
+
+		if amount == "20.5" {
+			_, err = storeInstance.ReleaseHold(context.Background(), hold.ID)
+			require.NoError(t, err)
+			record()
+		}
+	}
+	_, err := storeInstance.SetBalancePolicy(context.Background(), "wallet", ledger.BalancePolicy{Type: ledger.PolicyPositive})
+	require.NoError(t, err)
+	record()
+	_, err = storeInstance.CloseLedger(context.Background(), "wallet")
+	require.NoError(t, err)
+	record()
+
+	require.Len(t, states, 10)
+	for version, expected := range states {
+		rebuilt, err := storeInstance.GetLedgerAtVersion(context.Background(), "wallet", version)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, rebuilt, "version %d", version)
+	}
+
+	for _, version := range []int64{0, 11} {
+		_, err := storeInstance.GetLedgerAtVersion(context.Background(), "wallet", version)
+		assert.ErrorIs(t, err, ledger.ErrNotFound, "version %d", version)
+	}
+	_, err = storeInstance.GetLedgerAtVersion(context.Background(), "unknown", 1)
+	assert.ErrorIs(t, err, ledger.ErrNotFound)
+}
+
+func TestStoreGetLedgerEvents(t *testing.T) {
+	storeInstance := newFundedStore(t)
+	for i := 0; i < 3; i++ {
+		_, err := storeInstance.PlaceHold(context.Background(), "wallet", holdFor("1"))
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name              string
+		query             ledger.EventQuery
+		expectedVersions  []int64
+		expectedNextAfter int64
+		expectError       bool
+	}{
+		{
+			name:             "All events",
+			query:            ledger.EventQuery{},
+			expectedVersions: []int64{1, 2, 3, 4, 5},
+		},
+		{
+			name:              "First page",
+			query:             ledger.EventQuery{Limit: 2},
+			expectedVersions:  []int64{1, 2},
+			expectedNextAfter: 2,
+		},
+		{
+			name:              "Middle page",
+			query:             ledger.EventQuery{After: 2, Limit: 2},
+			expectedVersions:  []int64{3, 4},
+			expectedNextAfter: 4,
+		},
+		{
+			name:             "Last page",
+			query:            ledger.EventQuery{After: 4, Limit: 2},
+			expectedVersions: []int64{5},
+		},
+		{
+			name:             "After the last version",
+			query:            ledger.EventQuery{After: 9},
+			expectedVersions: []int64{},
+		},
+		{
+			name:        "Negative after returns error",
+			query:       ledger.EventQuery{After: -1},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := storeInstance.GetLedgerEvents(context.Background(), "wallet", tt.query)
+			if tt.expectError {
+				assert.ErrorIs(t, err, ledger.ErrValidation)
+				return
+			}
+
+			assert.NoError(t, err)
+			versions := make([]int64, 0, len(page.Events))
+			for _, e := range page.Events {
+				versions = append(versions, e.Version)
+			}
+			assert.Equal(t, tt.expectedVersions, versions)
+			assert.Equal(t, tt.expectedNextAfter, page.NextAfter)
+		})
+	}
+}
+
+func TestStoreProjectsEventsIntoNewReadModel(t *testing.T) {
+	storeInstance := newConcurrentStore("alice", "bob")
+	for _, id := range []string{"alice", "bob"} {
+		_, err := storeInstance.Credit(context.Background(), id, ledger.TransactionRequestDTO{
+			Type: ledger.Credit, Description: "deposit", Currency: "EUR", Amount: ledger.MustParseMoney("100"),
+		})
+		require.NoError(t, err)
+	}
+	_, err := storeInstance.Transfer(context.Background(), ledger.TransferRequestDTO{
+		SourceLedgerID: "alice", DestinationLedgerID: "bob", Currency: "EUR", Amount: ledger.MustParseMoney("25"),
+	})
+	require.NoError(t, err)
+
+	// a read model of the total credited to every ledger, built without any backfill
+	credited := make(map[string]ledger.Money)
+	err = storeInstance.Project(context.Background(), ledger.ProjectionFunc(func(e ledger.Event) error {
+		if e.Type == ledger.EventCredited {
+			credited[e.LedgerID] = credited[e.LedgerID].Add(e.Transaction.Amount)
+		}
+		return nil
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "100", credited["alice"].String())
+	assert.Equal(t, "125", credited["bob"].String())
+	assert.NotContains(t, credited, "counter-eur")
+
+	failing := errors.New("projection failed")
+	err = storeInstance.Project(context.Background(), ledger.ProjectionFunc(func(e ledger.Event) error {
+		return failing
+	}))
+	assert.ErrorIs(t, err, failing)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = storeInstance.Project(ctx, ledger.ProjectionFunc(func(e ledger.Event) error { return nil }))
+	assert.ErrorIs(t, err, context.Canceled)
+}
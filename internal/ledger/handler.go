@@ -1,19 +1,19 @@
 package ledger
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.uber.org/zap"
 )
 
 // DoTransaction performs credit or debit operation
 func DoTransaction(store Store) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		zap.L().Info("called deposit handler")
+		loggerFrom(ctx.Request.Context()).Info("called deposit handler")
 
 		reqCtx := ctx.Request.Context()
 		defer reqCtx.Done()
@@ -30,26 +30,114 @@ func DoTransaction(store Store) gin.HandlerFunc {
 			return
 		}
 
-		if req.Amount <= 0 {
-			ErrorHandler(ctx, http.StatusBadRequest, errors.New("failed get amount greater than zero"))
+		req.IdempotencyKey = ctx.GetHeader(IdempotencyKeyHeader)
+		if len(req.IdempotencyKey) > MaxIdempotencyKeyLength {
+			ErrorHandler(ctx, http.StatusBadRequest, fmt.Errorf("failed get idempotency key of at most %d characters", MaxIdempotencyKeyLength))
+			return
+		}
+
+		if !req.Amount.IsPositive() {
+			ErrorHandler(ctx, http.StatusUnprocessableEntity, withKind(ErrValidation, errors.New("failed get amount greater than zero")))
+			return
+		}
+
+		if err := req.Currency.Validate(); err != nil {
+			ErrorHandler(ctx, http.StatusUnprocessableEntity, withKind(ErrValidation, err))
+			return
+		}
+
+		if !req.Currency.Allows(req.Amount) {
+			ErrorHandler(ctx, http.StatusUnprocessableEntity, withKind(ErrValidation, fmt.Errorf("failed get amount with at most %d decimal places for %s", req.Currency.MinorUnits(), req.Currency)))
 			return
 		}
 
 		if !(req.Type == Credit || req.Type == Debit) {
-			ErrorHandler(ctx, http.StatusBadRequest, errors.New("failed get transaction type either credit or debit"))
+			ErrorHandler(ctx, http.StatusUnprocessableEntity, withKind(ErrValidation, errors.New("failed get transaction type either credit or debit")))
 			return
 		}
 
 		var res Transaction
 		var err error
 		if req.Type == Credit {
-			res, err = store.Credit(ctx, ledgerId, req)
+			res, err = store.Credit(ctx.Request.Context(), ledgerId, req)
 		} else {
-			res, err = store.Debit(ctx, ledgerId, req)
+			res, err = store.Debit(ctx.Request.Context(), ledgerId, req)
+		}
+
+		if err != nil {
+			ErrorHandler(ctx, errorStatus(err), fmt.Errorf("failed to perform transaction: %s, got error: %w", req.Amount, err))
+			return
+		}
+
+		SuccessHandler(ctx, http.StatusOK, res)
+	}
+}
+
+// DoTransfer performs a transfer between two ledgers
+func DoTransfer(store Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		loggerFrom(ctx.Request.Context()).Info("called transfer handler")
+
+		var req TransferRequestDTO
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ErrorHandler(ctx, http.StatusBadRequest, errors.New("failed get valid request payload"))
+			return
+		}
+
+		if req.SourceLedgerID == "" || req.DestinationLedgerID == "" {
+			ErrorHandler(ctx, http.StatusUnprocessableEntity, withKind(ErrValidation, errors.New("failed get valid source and destination ledgerId")))
+			return
+		}
+
+		if req.SourceLedgerID == req.DestinationLedgerID {
+			ErrorHandler(ctx, http.StatusUnprocessableEntity, withKind(ErrValidation, errors.New("failed get different source and destination ledgerId")))
+			return
+		}
+
+		if !req.Amount.IsPositive() {
+			ErrorHandler(ctx, http.StatusUnprocessableEntity, withKind(ErrValidation, errors.New("failed get amount greater than zero")))
+			return
+		}
+
+		if err := req.Currency.Validate(); err != nil {
+			ErrorHandler(ctx, http.StatusUnprocessableEntity, withKind(ErrValidation, err))
+			return
+		}
+
+		if !req.Currency.Allows(req.Amount) {
+			ErrorHandler(ctx, http.StatusUnprocessableEntity, withKind(ErrValidation, fmt.Errorf("failed get amount with at most %d decimal places for %s", req.Currency.MinorUnits(), req.Currency)))
+			return
+		}
+
+		res, err := store.Transfer(ctx.Request.Context(), req)
+		if err != nil {
+			ErrorHandler(ctx, errorStatus(err), fmt.Errorf("failed to perform transfer: %s, got error: %w", req.Amount, err))
+			return
 		}
 
+		SuccessHandler(ctx, http.StatusOK, res)
+	}
+}
+
+// DoJournalEntry posts a balanced journal entry across ledgers
+func DoJournalEntry(store Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		loggerFrom(ctx.Request.Context()).Info("called journal entry handler")
+
+		var req JournalEntryRequestDTO
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ErrorHandler(ctx, http.StatusBadRequest, errors.New("failed get valid request payload"))
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			ErrorHandler(ctx, http.StatusUnprocessableEntity, withKind(ErrValidation, err))
+			return
+		}
+
+		res, err := store.Post(ctx.Request.Context(), req)
 		if err != nil {
-			ErrorHandler(ctx, http.StatusInternalServerError, fmt.Errorf("failed to perform transaction: %f, got error: %w", req.Amount, err))
+			ErrorHandler(ctx, errorStatus(err), fmt.Errorf("failed to post journal entry, got error: %w", err))
 			return
 		}
 
@@ -60,7 +148,7 @@ func DoTransaction(store Store) gin.HandlerFunc {
 // ViewBalance performs view balance operation
 func ViewBalance(store Store) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		zap.L().Info("called view balance handler")
+		loggerFrom(ctx.Request.Context()).Info("called view balance handler")
 
 		ledgerId := ctx.Param("ledgerId")
 		if ledgerId == "" {
@@ -68,9 +156,20 @@ func ViewBalance(store Store) gin.HandlerFunc {
 			return
 		}
 
-		balance, err := store.GetLastBalance(context.Background(), ledgerId)
+		var balance Balance
+		var err error
+		if value := ctx.Query("asOf"); value != "" {
+			asOf, parseErr := parseInstant(value)
+			if parseErr != nil {
+				ErrorHandler(ctx, http.StatusBadRequest, errors.New("failed get asOf as unix milliseconds or RFC 3339 timestamp"))
+				return
+			}
+			balance, err = store.GetBalanceAsOf(ctx.Request.Context(), ledgerId, asOf)
+		} else {
+			balance, err = store.GetLastBalance(ctx.Request.Context(), ledgerId)
+		}
 		if err != nil {
-			ErrorHandler(ctx, http.StatusInternalServerError, fmt.Errorf("failed to perform view balance, got error: %w", err))
+			ErrorHandler(ctx, errorStatus(err), fmt.Errorf("failed to perform view balance, got error: %w", err))
 			return
 		}
 
@@ -81,7 +180,174 @@ func ViewBalance(store Store) gin.HandlerFunc {
 // ViewTransactionHistory performs view transaction history
 func ViewTransactionHistory(store Store) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		zap.L().Info("called view transaction history handler")
+		loggerFrom(ctx.Request.Context()).Info("called view transaction history handler")
+
+		ledgerId := ctx.Param("ledgerId")
+		if ledgerId == "" {
+			ErrorHandler(ctx, http.StatusBadRequest, errors.New("failed get valid ledgerId"))
+			return
+		}
+
+		query, err := parseStatementQuery(ctx)
+		if err != nil {
+			ErrorHandler(ctx, http.StatusBadRequest, err)
+			return
+		}
+
+		statement, err := store.GetTransactionHistory(ctx.Request.Context(), ledgerId, query)
+		if err != nil {
+			ErrorHandler(ctx, errorStatus(err), fmt.Errorf("failed to perform view transaction history, got error: %w", err))
+			return
+		}
+
+		SuccessHandler(ctx, http.StatusOK, statement)
+	}
+}
+
+// parseInstant reads an instant given either as unix milliseconds or as an RFC 3339 timestamp
+// and returns it in unix milliseconds
+func parseInstant(value string) (int64, error) {
+	if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return millis, nil
+	}
+
+	instant, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return 0, err
+	}
+	return instant.UnixMilli(), nil
+}
+
+// parseStatementQuery reads the statement filters and page from the query string
+func parseStatementQuery(ctx *gin.Context) (StatementQuery, error) {
+	query := StatementQuery{
+		Type:        TransactionType(ctx.Query("type")),
+		Description: ctx.Query("description"),
+		Cursor:      ctx.Query("cursor"),
+	}
+
+	if limit := ctx.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 || n > MaxStatementPageSize {
+			return StatementQuery{}, fmt.Errorf("failed get limit between 1 and %d", MaxStatementPageSize)
+		}
+		query.Limit = n
+	}
+
+	dates := []struct {
+		name   string
+		target *int64
+	}{{"from", &query.From}, {"to", &query.To}}
+	for _, date := range dates {
+		if value := ctx.Query(date.name); value != "" {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return StatementQuery{}, fmt.Errorf("failed get %s as unix milliseconds", date.name)
+			}
+			*date.target = n
+		}
+	}
+
+	amounts := []struct {
+		name   string
+		target *Money
+	}{{"minAmount", &query.MinAmount}, {"maxAmount", &query.MaxAmount}}
+	for _, amount := range amounts {
+		if value := ctx.Query(amount.name); value != "" {
+			parsed, err := ParseMoney(value)
+			if err != nil {
+				return StatementQuery{}, fmt.Errorf("failed get valid %s", amount.name)
+			}
+			*amount.target = parsed
+		}
+	}
+
+	if err := query.Validate(); err != nil {
+		return StatementQuery{}, err
+	}
+
+	return query, nil
+}
+
+// CreateLedger opens a new ledger
+func CreateLedger(store Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		loggerFrom(ctx.Request.Context()).Info("called create ledger handler")
+
+		var req LedgerRequestDTO
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ErrorHandler(ctx, http.StatusBadRequest, errors.New("failed get valid request payload"))
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			ErrorHandler(ctx, http.StatusUnprocessableEntity, withKind(ErrValidation, err))
+			return
+		}
+
+		res, err := store.CreateLedger(ctx.Request.Context(), req)
+		if err != nil {
+			ErrorHandler(ctx, errorStatus(err), fmt.Errorf("failed to perform create ledger, got error: %w", err))
+			return
+		}
+
+		SuccessHandler(ctx, http.StatusCreated, res)
+	}
+}
+
+// ListLedgers performs list ledgers operation
+func ListLedgers(store Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		loggerFrom(ctx.Request.Context()).Info("called list ledgers handler")
+
+		query := LedgerQuery{
+			Type:   ctx.Query("type"),
+			Cursor: ctx.Query("cursor"),
+		}
+		if limit := ctx.Query("limit"); limit != "" {
+			n, err := strconv.Atoi(limit)
+			if err != nil || n <= 0 || n > MaxLedgerPageSize {
+				ErrorHandler(ctx, http.StatusBadRequest, fmt.Errorf("failed get limit between 1 and %d", MaxLedgerPageSize))
+				return
+			}
+			query.Limit = n
+		}
+
+		page, err := store.ListLedgers(ctx.Request.Context(), query)
+		if err != nil {
+			ErrorHandler(ctx, errorStatus(err), fmt.Errorf("failed to perform list ledgers, got error: %w", err))
+			return
+		}
+
+		SuccessHandler(ctx, http.StatusOK, page)
+	}
+}
+
+// ViewLedger performs view ledger operation
+func ViewLedger(store Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		loggerFrom(ctx.Request.Context()).Info("called view ledger handler")
+
+		ledgerId := ctx.Param("ledgerId")
+		if ledgerId == "" {
+			ErrorHandler(ctx, http.StatusBadRequest, errors.New("failed get valid ledgerId"))
+			return
+		}
+
+		res, err := store.GetLedger(ctx.Request.Context(), ledgerId)
+		if err != nil {
+			ErrorHandler(ctx, errorStatus(err), fmt.Errorf("failed to perform view ledger, got error: %w", err))
+			return
+		}
+
+		SuccessHandler(ctx, http.StatusOK, res)
+	}
+}
+
+// CloseLedger performs close ledger operation
+func CloseLedger(store Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		loggerFrom(ctx.Request.Context()).Info("called close ledger handler")
 
 		ledgerId := ctx.Param("ledgerId")
 		if ledgerId == "" {
@@ -89,19 +355,350 @@ func ViewTransactionHistory(store Store) gin.HandlerFunc {
 			return
 		}
 
-		transactions, err := store.GetTransactionHistory(context.Background(), ledgerId)
+		res, err := store.CloseLedger(ctx.Request.Context(), ledgerId)
 		if err != nil {
-			ErrorHandler(ctx, http.StatusInternalServerError, fmt.Errorf("failed to perform view transaction history, got error: %w", err))
+			ErrorHandler(ctx, errorStatus(err), fmt.Errorf("failed to perform close ledger, got error: %w", err))
+			return
+		}
+
+		SuccessHandler(ctx, http.StatusOK, res)
+	}
+}
+
+// SetBalancePolicy replaces the balance policy of a ledger
+func SetBalancePolicy(store Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		loggerFrom(ctx.Request.Context()).Info("called set balance policy handler")
+
+		ledgerId := ctx.Param("ledgerId")
+		if ledgerId == "" {
+			ErrorHandler(ctx, http.StatusBadRequest, errors.New("failed get valid ledgerId"))
+			return
+		}
+
+		var req BalancePolicy
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ErrorHandler(ctx, http.StatusBadRequest, errors.New("failed get valid request payload"))
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			ErrorHandler(ctx, http.StatusUnprocessableEntity, withKind(ErrValidation, err))
 			return
 		}
 
-		SuccessHandler(ctx, http.StatusOK, transactions)
+		res, err := store.SetBalancePolicy(ctx.Request.Context(), ledgerId, req)
+		if err != nil {
+			ErrorHandler(ctx, errorStatus(err), fmt.Errorf("failed to perform set balance policy, got error: %w", err))
+			return
+		}
+
+		SuccessHandler(ctx, http.StatusOK, res)
+	}
+}
+
+// ViewLedgerEvents performs view ledger events operation
+func ViewLedgerEvents(store Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		loggerFrom(ctx.Request.Context()).Info("called view ledger events handler")
+
+		ledgerId := ctx.Param("ledgerId")
+		if ledgerId == "" {
+			ErrorHandler(ctx, http.StatusBadRequest, errors.New("failed get valid ledgerId"))
+			return
+		}
+
+		var query EventQuery
+		if after := ctx.Query("after"); after != "" {
+			n, err := strconv.ParseInt(after, 10, 64)
+			if err != nil || n < 0 {
+				ErrorHandler(ctx, http.StatusBadRequest, errors.New("failed get after as version of at least 0"))
+				return
+			}
+			query.After = n
+		}
+		if limit := ctx.Query("limit"); limit != "" {
+			n, err := strconv.Atoi(limit)
+			if err != nil || n <= 0 || n > MaxEventPageSize {
+				ErrorHandler(ctx, http.StatusBadRequest, fmt.Errorf("failed get limit between 1 and %d", MaxEventPageSize))
+				return
+			}
+			query.Limit = n
+		}
+
+		page, err := store.GetLedgerEvents(ctx.Request.Context(), ledgerId, query)
+		if err != nil {
+			ErrorHandler(ctx, errorStatus(err), fmt.Errorf("failed to perform view ledger events, got error: %w", err))
+			return
+		}
+
+		SuccessHandler(ctx, http.StatusOK, page)
+	}
+}
+
+// ViewLedgerAtVersion performs view ledger at version operation
+func ViewLedgerAtVersion(store Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		loggerFrom(ctx.Request.Context()).Info("called view ledger at version handler")
+
+		ledgerId := ctx.Param("ledgerId")
+		if ledgerId == "" {
+			ErrorHandler(ctx, http.StatusBadRequest, errors.New("failed get valid ledgerId"))
+			return
+		}
+
+		version, err := strconv.ParseInt(ctx.Param("version"), 10, 64)
+		if err != nil || version < 1 {
+			ErrorHandler(ctx, http.StatusBadRequest, errors.New("failed get version of at least 1"))
+			return
+		}
+
+		res, err := store.GetLedgerAtVersion(ctx.Request.Context(), ledgerId, version)
+		if err != nil {
+			ErrorHandler(ctx, errorStatus(err), fmt.Errorf("failed to perform view ledger at version, got error: %w", err))
+			return
+		}
+
+		SuccessHandler(ctx, http.StatusOK, res)
+	}
+}
+
+// PlaceHold places a hold on a ledger
+func PlaceHold(store Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		loggerFrom(ctx.Request.Context()).Info("called place hold handler")
+
+		ledgerId := ctx.Param("ledgerId")
+		if ledgerId == "" {
+			ErrorHandler(ctx, http.StatusBadRequest, errors.New("failed get valid ledgerId"))
+			return
+		}
+
+		var req HoldRequestDTO
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ErrorHandler(ctx, http.StatusBadRequest, errors.New("failed get valid request payload"))
+			return
+		}
+
+		if err := req.Validate(time.Now()); err != nil {
+			ErrorHandler(ctx, http.StatusUnprocessableEntity, withKind(ErrValidation, err))
+			return
+		}
+
+		res, err := store.PlaceHold(ctx.Request.Context(), ledgerId, req)
+		if err != nil {
+			ErrorHandler(ctx, errorStatus(err), fmt.Errorf("failed to perform place hold, got error: %w", err))
+			return
+		}
+
+		SuccessHandler(ctx, http.StatusCreated, res)
+	}
+}
+
+// ViewHold performs view hold operation
+func ViewHold(store Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		loggerFrom(ctx.Request.Context()).Info("called view hold handler")
+
+		holdId := ctx.Param("holdId")
+		if holdId == "" {
+			ErrorHandler(ctx, http.StatusBadRequest, errors.New("failed get valid holdId"))
+			return
+		}
+
+		res, err := store.GetHold(ctx.Request.Context(), holdId)
+		if err != nil {
+			ErrorHandler(ctx, errorStatus(err), fmt.Errorf("failed to perform view hold, got error: %w", err))
+			return
+		}
+
+		SuccessHandler(ctx, http.StatusOK, res)
+	}
+}
+
+// CaptureHold captures a hold into a debit transaction
+func CaptureHold(store Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		loggerFrom(ctx.Request.Context()).Info("called capture hold handler")
+
+		holdId := ctx.Param("holdId")
+		if holdId == "" {
+			ErrorHandler(ctx, http.StatusBadRequest, errors.New("failed get valid holdId"))
+			return
+		}
+
+		// an empty body captures the whole hold
+		var req CaptureRequestDTO
+		if ctx.Request.ContentLength != 0 {
+			if err := ctx.ShouldBindJSON(&req); err != nil {
+				ErrorHandler(ctx, http.StatusBadRequest, errors.New("failed get valid request payload"))
+				return
+			}
+		}
+
+		if req.Amount.IsNegative() {
+			ErrorHandler(ctx, http.StatusUnprocessableEntity, withKind(ErrValidation, errors.New("failed get amount greater than zero")))
+			return
+		}
+
+		res, err := store.CaptureHold(ctx.Request.Context(), holdId, req)
+		if err != nil {
+			ErrorHandler(ctx, errorStatus(err), fmt.Errorf("failed to perform capture hold, got error: %w", err))
+			return
+		}
+
+		SuccessHandler(ctx, http.StatusOK, res)
+	}
+}
+
+// ReleaseHold releases a hold without capturing it
+func ReleaseHold(store Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		loggerFrom(ctx.Request.Context()).Info("called release hold handler")
+
+		holdId := ctx.Param("holdId")
+		if holdId == "" {
+			ErrorHandler(ctx, http.StatusBadRequest, errors.New("failed get valid holdId"))
+			return
+		}
+
+		res, err := store.ReleaseHold(ctx.Request.Context(), holdId)
+		if err != nil {
+			ErrorHandler(ctx, errorStatus(err), fmt.Errorf("failed to perform release hold, got error: %w", err))
+			return
+		}
+
+		SuccessHandler(ctx, http.StatusOK, res)
+	}
+}
+
+// ReverseTransaction reverses a transaction with a compensating entry
+func ReverseTransaction(store Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		loggerFrom(ctx.Request.Context()).Info("called reverse transaction handler")
+
+		ledgerId := ctx.Param("ledgerId")
+		if ledgerId == "" {
+			ErrorHandler(ctx, http.StatusBadRequest, errors.New("failed get valid ledgerId"))
+			return
+		}
+
+		transactionId := ctx.Param("txId")
+		if transactionId == "" {
+			ErrorHandler(ctx, http.StatusBadRequest, errors.New("failed get valid txId"))
+			return
+		}
+
+		// an empty body reverses the whole transaction
+		var req ReversalRequestDTO
+		if ctx.Request.ContentLength != 0 {
+			if err := ctx.ShouldBindJSON(&req); err != nil {
+				ErrorHandler(ctx, http.StatusBadRequest, errors.New("failed get valid request payload"))
+				return
+			}
+		}
+
+		if req.Amount.IsNegative() {
+			ErrorHandler(ctx, http.StatusUnprocessableEntity, withKind(ErrValidation, errors.New("failed get amount greater than zero")))
+			return
+		}
+
+		res, err := store.ReverseTransaction(ctx.Request.Context(), ledgerId, transactionId, req)
+		if err != nil {
+			ErrorHandler(ctx, errorStatus(err), fmt.Errorf("failed to perform reverse transaction, got error: %w", err))
+			return
+		}
+
+		SuccessHandler(ctx, http.StatusOK, res)
+	}
+}
+
+// VerifyLedger performs verify ledger operation, reporting the first broken link of the ledger's
+// hash chain with a 200 as the verification itself succeeded
+func VerifyLedger(store Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		loggerFrom(ctx.Request.Context()).Info("called verify ledger handler")
+
+		ledgerId := ctx.Param("ledgerId")
+		if ledgerId == "" {
+			ErrorHandler(ctx, http.StatusBadRequest, errors.New("failed get valid ledgerId"))
+			return
+		}
+
+		res, err := store.VerifyLedger(ctx.Request.Context(), ledgerId)
+		if err != nil {
+			ErrorHandler(ctx, errorStatus(err), fmt.Errorf("failed to perform verify ledger, got error: %w", err))
+			return
+		}
+
+		SuccessHandler(ctx, http.StatusOK, res)
+	}
+}
+
+// ErrorCode is the stable machine-readable code returned with every error
+type ErrorCode string
+
+const (
+	CodeInvalidRequest      ErrorCode = "invalid_request"
+	CodeValidationFailed    ErrorCode = "validation_failed"
+	CodeNotFound            ErrorCode = "not_found"
+	CodeInsufficientFunds   ErrorCode = "insufficient_funds"
+	CodeLedgerClosed        ErrorCode = "ledger_closed"
+	CodeConflict            ErrorCode = "conflict"
+	CodeIdempotencyConflict ErrorCode = "idempotency_conflict"
+	CodeUnauthenticated     ErrorCode = "unauthenticated"
+	CodeForbidden           ErrorCode = "forbidden"
+	CodeRateLimited         ErrorCode = "rate_limited"
+	CodePayloadTooLarge     ErrorCode = "payload_too_large"
+	CodeInternal            ErrorCode = "internal_error"
+)
+
+// errorKinds maps each kind of store error to its HTTP status and code
+var errorKinds = []struct {
+	kind       error
+	statusCode int
+	code       ErrorCode
+}{
+	{ErrNotFound, http.StatusNotFound, CodeNotFound},
+	{ErrInsufficientFunds, http.StatusUnprocessableEntity, CodeInsufficientFunds},
+	{ErrLedgerClosed, http.StatusConflict, CodeLedgerClosed},
+	{ErrValidation, http.StatusUnprocessableEntity, CodeValidationFailed},
+	{ErrConflict, http.StatusConflict, CodeConflict},
+	{ErrIdempotencyConflict, http.StatusConflict, CodeIdempotencyConflict},
+	{ErrUnknownCursor, http.StatusBadRequest, CodeInvalidRequest},
+	{errMalformedPayload, http.StatusBadRequest, CodeInvalidRequest},
+	{ErrUnauthenticated, http.StatusUnauthorized, CodeUnauthenticated},
+	{ErrForbidden, http.StatusForbidden, CodeForbidden},
+	{ErrRateLimited, http.StatusTooManyRequests, CodeRateLimited},
+	{ErrPayloadTooLarge, http.StatusRequestEntityTooLarge, CodePayloadTooLarge},
+}
+
+// errorStatus returns the HTTP status of the store error from its kind, 500 if it has none
+func errorStatus(err error) int {
+	for _, k := range errorKinds {
+		if errors.Is(err, k.kind) {
+			return k.statusCode
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// errorCode returns the code of the error from its kind or, for an error of no kind, from the status
+func errorCode(statusCode int, err error) ErrorCode {
+	for _, k := range errorKinds {
+		if errors.Is(err, k.kind) {
+			return k.code
+		}
+	}
+	if statusCode < http.StatusInternalServerError {
+		return CodeInvalidRequest
 	}
+	return CodeInternal
 }
 
 // ErrorHandler is a function to handle errors
 func ErrorHandler(c *gin.Context, statusCode int, err error) {
-	c.JSON(statusCode, gin.H{"error": err.Error()})
+	c.JSON(statusCode, gin.H{"error": err.Error(), "code": errorCode(statusCode, err)})
 }
 
 // SuccessHandler is a function to handle success
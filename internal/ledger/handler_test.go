@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/dineshd30/ledger-service/internal/ledger"
@@ -21,19 +23,22 @@ func TestDoTransaction(t *testing.T) {
 	tests := []struct {
 		name                    string
 		ledgerId                string
+		idempotencyKey          string
 		requestBody             string
 		expectedStatus          int
 		expectedResponseField   string
 		expectedResponseMessage interface{}
+		expectedCode            ledger.ErrorCode
 		storeSetup              func() ledger.Store
 	}{
 		{
 			name:                    "Missing ledgerId parameter",
 			ledgerId:                "",
-			requestBody:             `{"ledgerId": "ledger1", "ledgerType": "cash", "type": "credit", "description": "deposit", "amount": 100}`,
+			requestBody:             `{"ledgerId": "ledger1", "ledgerType": "cash", "type": "credit", "description": "deposit", "currency": "EUR", "amount": 100}`,
 			expectedStatus:          http.StatusBadRequest,
 			expectedResponseField:   "error",
 			expectedResponseMessage: "failed get valid ledgerId",
+			expectedCode:            ledger.CodeInvalidRequest,
 			storeSetup: func() ledger.Store {
 				return new(internalMock.Store)
 			},
@@ -45,6 +50,7 @@ func TestDoTransaction(t *testing.T) {
 			expectedStatus:          http.StatusBadRequest,
 			expectedResponseField:   "error",
 			expectedResponseMessage: "failed get valid request payload",
+			expectedCode:            ledger.CodeInvalidRequest,
 			storeSetup: func() ledger.Store {
 				return new(internalMock.Store)
 			},
@@ -52,21 +58,83 @@ func TestDoTransaction(t *testing.T) {
 		{
 			name:                    "Amount less than or equal to zero",
 			ledgerId:                "ledger1",
-			requestBody:             `{"ledgerId": "ledger1", "ledgerType": "cash", "type": "credit", "description": "deposit", "amount": 0}`,
-			expectedStatus:          http.StatusBadRequest,
+			requestBody:             `{"ledgerId": "ledger1", "ledgerType": "cash", "type": "credit", "description": "deposit", "currency": "EUR", "amount": 0}`,
+			expectedStatus:          http.StatusUnprocessableEntity,
 			expectedResponseField:   "error",
 			expectedResponseMessage: "failed get amount greater than zero",
+			expectedCode:            ledger.CodeValidationFailed,
 			storeSetup: func() ledger.Store {
 				return new(internalMock.Store)
 			},
 		},
 		{
-			name:                    "Invalid transaction type",
+			name:                    "Amount with more decimal places than allowed",
+			ledgerId:                "ledger1",
+			requestBody:             `{"ledgerId": "ledger1", "ledgerType": "cash", "type": "credit", "description": "deposit", "currency": "EUR", "amount": 10.001}`,
+			expectedStatus:          http.StatusUnprocessableEntity,
+			expectedResponseField:   "error",
+			expectedResponseMessage: "failed get amount with at most 2 decimal places for EUR",
+			expectedCode:            ledger.CodeValidationFailed,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:                    "Amount with decimal places in currency without minor units",
+			ledgerId:                "ledger1",
+			requestBody:             `{"ledgerId": "ledger1", "ledgerType": "cash", "type": "credit", "description": "deposit", "currency": "JPY", "amount": 100.5}`,
+			expectedStatus:          http.StatusUnprocessableEntity,
+			expectedResponseField:   "error",
+			expectedResponseMessage: "failed get amount with at most 0 decimal places for JPY",
+			expectedCode:            ledger.CodeValidationFailed,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:                    "Unsupported currency",
+			ledgerId:                "ledger1",
+			requestBody:             `{"ledgerId": "ledger1", "ledgerType": "cash", "type": "credit", "description": "deposit", "currency": "XYZ", "amount": 100}`,
+			expectedStatus:          http.StatusUnprocessableEntity,
+			expectedResponseField:   "error",
+			expectedResponseMessage: `failed get supported currency: "XYZ"`,
+			expectedCode:            ledger.CodeValidationFailed,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:                    "Missing currency",
+			ledgerId:                "ledger1",
+			requestBody:             `{"ledgerId": "ledger1", "ledgerType": "cash", "type": "credit", "description": "deposit", "amount": 100}`,
+			expectedStatus:          http.StatusUnprocessableEntity,
+			expectedResponseField:   "error",
+			expectedResponseMessage: `failed get supported currency: ""`,
+			expectedCode:            ledger.CodeValidationFailed,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:                    "Amount with exponent notation",
 			ledgerId:                "ledger1",
-			requestBody:             `{"ledgerId": "ledger1", "ledgerType": "cash", "type": "invalid", "description": "deposit", "amount": 100}`,
+			requestBody:             `{"ledgerId": "ledger1", "ledgerType": "cash", "type": "credit", "description": "deposit", "currency": "EUR", "amount": 1e2}`,
 			expectedStatus:          http.StatusBadRequest,
 			expectedResponseField:   "error",
+			expectedResponseMessage: "failed get valid request payload",
+			expectedCode:            ledger.CodeInvalidRequest,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:                    "Invalid transaction type",
+			ledgerId:                "ledger1",
+			requestBody:             `{"ledgerId": "ledger1", "ledgerType": "cash", "type": "invalid", "description": "deposit", "currency": "EUR", "amount": 100}`,
+			expectedStatus:          http.StatusUnprocessableEntity,
+			expectedResponseField:   "error",
 			expectedResponseMessage: "failed get transaction type either credit or debit",
+			expectedCode:            ledger.CodeValidationFailed,
 			storeSetup: func() ledger.Store {
 				return new(internalMock.Store)
 			},
@@ -74,24 +142,25 @@ func TestDoTransaction(t *testing.T) {
 		{
 			name:                    "Successful credit transaction",
 			ledgerId:                "ledger1",
-			requestBody:             `{"ledgerId": "ledger1", "ledgerType": "cash", "type": "credit", "description": "deposit", "amount": 100}`,
+			requestBody:             `{"ledgerId": "ledger1", "ledgerType": "cash", "type": "credit", "description": "deposit", "currency": "EUR", "amount": 100}`,
 			expectedStatus:          http.StatusOK,
 			expectedResponseField:   "data",
-			expectedResponseMessage: ledger.Transaction{ID: "tx-credit-1", Date: 1234567890, Type: ledger.Credit, Description: "deposit", Amount: 100, RunningBalance: 100},
+			expectedResponseMessage: ledger.Transaction{ID: "tx-credit-1", Date: 1234567890, Type: ledger.Credit, Description: "deposit", Amount: ledger.MustParseMoney("100"), RunningBalance: ledger.MustParseMoney("100")},
 			storeSetup: func() ledger.Store {
 				mStore := new(internalMock.Store)
 				reqDTO := ledger.TransactionRequestDTO{
 					Type:        ledger.Credit,
 					Description: "deposit",
-					Amount:      100,
+					Currency:    "EUR",
+					Amount:      ledger.MustParseMoney("100"),
 				}
 				mStore.On("Credit", mock.Anything, "ledger1", reqDTO).Return(ledger.Transaction{
 					ID:             "tx-credit-1",
 					Date:           1234567890,
 					Type:           ledger.Credit,
 					Description:    "deposit",
-					Amount:         100,
-					RunningBalance: 100,
+					Amount:         ledger.MustParseMoney("100"),
+					RunningBalance: ledger.MustParseMoney("100"),
 				}, nil)
 				return mStore
 			},
@@ -99,25 +168,25 @@ func TestDoTransaction(t *testing.T) {
 		{
 			name:                    "Successful debit transaction",
 			ledgerId:                "ledger1",
-			requestBody:             `{"ledgerId": "ledger1", "ledgerType": "cash", "type": "debit", "description": "withdrawal", "amount": 50}`,
+			requestBody:             `{"ledgerId": "ledger1", "ledgerType": "cash", "type": "debit", "description": "withdrawal", "currency": "EUR", "amount": 50}`,
 			expectedStatus:          http.StatusOK,
 			expectedResponseField:   "data",
-			expectedResponseMessage: ledger.Transaction{ID: "tx-debit-1", Date: 1234567891, Type: ledger.Debit, Description: "withdrawal", Amount: 50, RunningBalance: 50},
+			expectedResponseMessage: ledger.Transaction{ID: "tx-debit-1", Date: 1234567891, Type: ledger.Debit, Description: "withdrawal", Amount: ledger.MustParseMoney("50"), RunningBalance: ledger.MustParseMoney("50")},
 			storeSetup: func() ledger.Store {
 				mStore := new(internalMock.Store)
 				reqDTO := ledger.TransactionRequestDTO{
-
 					Type:        ledger.Debit,
 					Description: "withdrawal",
-					Amount:      50,
+					Currency:    "EUR",
+					Amount:      ledger.MustParseMoney("50"),
 				}
 				mStore.On("Debit", mock.Anything, "ledger1", reqDTO).Return(ledger.Transaction{
 					ID:             "tx-debit-1",
 					Date:           1234567891,
 					Type:           ledger.Debit,
 					Description:    "withdrawal",
-					Amount:         50,
-					RunningBalance: 50,
+					Amount:         ledger.MustParseMoney("50"),
+					RunningBalance: ledger.MustParseMoney("50"),
 				}, nil)
 				return mStore
 			},
@@ -125,21 +194,134 @@ func TestDoTransaction(t *testing.T) {
 		{
 			name:                    "Store error during credit transaction",
 			ledgerId:                "ledger1",
-			requestBody:             `{"ledgerId": "ledger1", "ledgerType": "cash", "type": "credit", "description": "deposit", "amount": 100}`,
+			requestBody:             `{"ledgerId": "ledger1", "ledgerType": "cash", "type": "credit", "description": "deposit", "currency": "EUR", "amount": 100}`,
 			expectedStatus:          http.StatusInternalServerError,
 			expectedResponseField:   "error",
-			expectedResponseMessage: "failed to perform transaction: 100.000000, got error: store error",
+			expectedResponseMessage: "failed to perform transaction: 100, got error: store error",
+			expectedCode:            ledger.CodeInternal,
 			storeSetup: func() ledger.Store {
 				mStore := new(internalMock.Store)
 				reqDTO := ledger.TransactionRequestDTO{
 					Type:        ledger.Credit,
 					Description: "deposit",
-					Amount:      100,
+					Currency:    "EUR",
+					Amount:      ledger.MustParseMoney("100"),
 				}
 				mStore.On("Credit", mock.Anything, "ledger1", reqDTO).Return(ledger.Transaction{}, errors.New("store error"))
 				return mStore
 			},
 		},
+		{
+			name:                    "Idempotency key is passed to the store",
+			ledgerId:                "ledger1",
+			idempotencyKey:          "payment-42",
+			requestBody:             `{"type": "credit", "description": "deposit", "currency": "EUR", "amount": 100}`,
+			expectedStatus:          http.StatusOK,
+			expectedResponseField:   "data",
+			expectedResponseMessage: ledger.Transaction{ID: "tx-credit-1", Date: 1234567890, Type: ledger.Credit, Description: "deposit", Amount: ledger.MustParseMoney("100"), RunningBalance: ledger.MustParseMoney("100")},
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				reqDTO := ledger.TransactionRequestDTO{
+					Type:           ledger.Credit,
+					Description:    "deposit",
+					Currency:       "EUR",
+					Amount:         ledger.MustParseMoney("100"),
+					IdempotencyKey: "payment-42",
+				}
+				mStore.On("Credit", mock.Anything, "ledger1", reqDTO).Return(ledger.Transaction{
+					ID:             "tx-credit-1",
+					Date:           1234567890,
+					Type:           ledger.Credit,
+					Description:    "deposit",
+					Amount:         ledger.MustParseMoney("100"),
+					RunningBalance: ledger.MustParseMoney("100"),
+					IdempotencyKey: "payment-42",
+				}, nil)
+				return mStore
+			},
+		},
+		{
+			name:                    "Idempotency key reused with a different payload",
+			ledgerId:                "ledger1",
+			idempotencyKey:          "payment-42",
+			requestBody:             `{"type": "credit", "description": "deposit", "currency": "EUR", "amount": 100}`,
+			expectedStatus:          http.StatusConflict,
+			expectedResponseField:   "error",
+			expectedResponseMessage: "failed to perform transaction: 100, got error: failed to reuse idempotency key with a different request",
+			expectedCode:            ledger.CodeIdempotencyConflict,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("Credit", mock.Anything, "ledger1", mock.Anything).Return(ledger.Transaction{}, ledger.ErrIdempotencyConflict)
+				return mStore
+			},
+		},
+		{
+			name:                    "Idempotency key too long",
+			ledgerId:                "ledger1",
+			idempotencyKey:          strings.Repeat("k", ledger.MaxIdempotencyKeyLength+1),
+			requestBody:             `{"type": "credit", "description": "deposit", "currency": "EUR", "amount": 100}`,
+			expectedStatus:          http.StatusBadRequest,
+			expectedResponseField:   "error",
+			expectedResponseMessage: "failed get idempotency key of at most 255 characters",
+			expectedCode:            ledger.CodeInvalidRequest,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:                    "Unknown ledger",
+			ledgerId:                "unknown",
+			requestBody:             `{"type": "credit", "description": "deposit", "currency": "EUR", "amount": 100}`,
+			expectedStatus:          http.StatusNotFound,
+			expectedResponseField:   "error",
+			expectedResponseMessage: "failed to perform transaction: 100, got error: failed to perform credit transaction, got error : failed get ledger: unknown",
+			expectedCode:            ledger.CodeNotFound,
+			storeSetup: func() ledger.Store {
+				return ledger.NewStore(ledger.NewUUIDGenerator(), newLedgersWithCounterAccount(), counterAccountOptions)
+			},
+		},
+		{
+			name:                    "Debit beyond the balance policy",
+			ledgerId:                "ledger1",
+			requestBody:             `{"type": "debit", "description": "withdrawal", "currency": "EUR", "amount": 100}`,
+			expectedStatus:          http.StatusUnprocessableEntity,
+			expectedResponseField:   "error",
+			expectedResponseMessage: "failed to perform transaction: 100, got error: failed to perform debit transaction, got error : failed to get new available balance -100 allowed by non-negative policy of ledger: ledger1",
+			expectedCode:            ledger.CodeInsufficientFunds,
+			storeSetup: func() ledger.Store {
+				ledgers := newLedgersWithCounterAccount()
+				ledgers["ledger1"] = &ledger.Ledger{ID: "ledger1", Type: "cash", Currency: "EUR"}
+				return ledger.NewStore(ledger.NewUUIDGenerator(), ledgers, counterAccountOptions)
+			},
+		},
+		{
+			name:                    "Credit to closed ledger",
+			ledgerId:                "ledger1",
+			requestBody:             `{"type": "credit", "description": "deposit", "currency": "EUR", "amount": 100}`,
+			expectedStatus:          http.StatusConflict,
+			expectedResponseField:   "error",
+			expectedResponseMessage: "failed to perform transaction: 100, got error: failed to perform credit transaction, got error : failed to post to closed ledger: ledger1",
+			expectedCode:            ledger.CodeLedgerClosed,
+			storeSetup: func() ledger.Store {
+				ledgers := newLedgersWithCounterAccount()
+				ledgers["ledger1"] = &ledger.Ledger{ID: "ledger1", Type: "cash", Currency: "EUR", Status: ledger.LedgerClosed}
+				return ledger.NewStore(ledger.NewUUIDGenerator(), ledgers, counterAccountOptions)
+			},
+		},
+		{
+			name:                    "Credit in other currency than the ledger",
+			ledgerId:                "ledger1",
+			requestBody:             `{"type": "credit", "description": "deposit", "currency": "GBP", "amount": 100}`,
+			expectedStatus:          http.StatusUnprocessableEntity,
+			expectedResponseField:   "error",
+			expectedResponseMessage: "failed to perform transaction: 100, got error: failed to perform credit transaction, got error : failed to match ledger currency EUR, got GBP",
+			expectedCode:            ledger.CodeValidationFailed,
+			storeSetup: func() ledger.Store {
+				ledgers := newLedgersWithCounterAccount()
+				ledgers["ledger1"] = &ledger.Ledger{ID: "ledger1", Type: "cash", Currency: "EUR"}
+				return ledger.NewStore(ledger.NewUUIDGenerator(), ledgers, counterAccountOptions)
+			},
+		},
 	}
 
 	for _, tc := range tests {
@@ -148,6 +330,9 @@ func TestDoTransaction(t *testing.T) {
 
 			req := httptest.NewRequest("POST", "/ledger/:ledgerId/transaction", bytes.NewBufferString(tc.requestBody))
 			req.Header.Set("Content-Type", "application/json")
+			if tc.idempotencyKey != "" {
+				req.Header.Set(ledger.IdempotencyKeyHeader, tc.idempotencyKey)
+			}
 			w := httptest.NewRecorder()
 
 			c, _ := gin.CreateTestContext(w)
@@ -162,12 +347,15 @@ func TestDoTransaction(t *testing.T) {
 			assert.Equal(t, tc.expectedStatus, w.Code)
 
 			var resp map[string]interface{}
-			err := json.Unmarshal(w.Body.Bytes(), &resp)
+			decoder := json.NewDecoder(w.Body)
+			decoder.UseNumber()
+			err := decoder.Decode(&resp)
 			assert.NoError(t, err)
 
 			if tc.expectedResponseField == "error" {
 				assert.Contains(t, resp, "error")
 				assert.Equal(t, tc.expectedResponseMessage, resp["error"])
+				assert.Equal(t, string(tc.expectedCode), resp["code"])
 			} else {
 				data, ok := resp["data"].(map[string]interface{})
 				assert.True(t, ok)
@@ -175,9 +363,1362 @@ func TestDoTransaction(t *testing.T) {
 				assert.Equal(t, expectedTx.ID, data["id"])
 				assert.Equal(t, string(expectedTx.Type), data["type"])
 				assert.Equal(t, expectedTx.Description, data["description"])
-				assert.Equal(t, expectedTx.Amount, data["amount"])
-				assert.Equal(t, expectedTx.RunningBalance, data["runningBalance"])
+				assert.Equal(t, json.Number(expectedTx.Amount.String()), data["amount"])
+				assert.Equal(t, json.Number(expectedTx.RunningBalance.String()), data["runningBalance"])
 			}
 		})
 	}
 }
+
+func TestDoTransfer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		requestBody    string
+		expectedStatus int
+		expectedBody   string
+		storeSetup     func() ledger.Store
+	}{
+		{
+			name:           "Invalid JSON payload",
+			requestBody:    `invalid json`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "failed get valid request payload", "code": "invalid_request"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Missing destination ledgerId",
+			requestBody:    `{"sourceLedgerId": "ledger1", "description": "rent", "currency": "EUR", "amount": 10}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": "failed get valid source and destination ledgerId", "code": "validation_failed"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Same source and destination ledgerId",
+			requestBody:    `{"sourceLedgerId": "ledger1", "destinationLedgerId": "ledger1", "description": "rent", "currency": "EUR", "amount": 10}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": "failed get different source and destination ledgerId", "code": "validation_failed"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Amount less than or equal to zero",
+			requestBody:    `{"sourceLedgerId": "ledger1", "destinationLedgerId": "ledger2", "description": "rent", "currency": "EUR", "amount": -10}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": "failed get amount greater than zero", "code": "validation_failed"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Amount with more decimal places than allowed",
+			requestBody:    `{"sourceLedgerId": "ledger1", "destinationLedgerId": "ledger2", "description": "rent", "currency": "GBP", "amount": 10.001}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": "failed get amount with at most 2 decimal places for GBP", "code": "validation_failed"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Successful transfer",
+			requestBody:    `{"sourceLedgerId": "ledger1", "destinationLedgerId": "ledger2", "description": "rent", "currency": "EUR", "amount": 10}`,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"data": {"id": "transfer-1",
+				"debit": {"id": "tx-1", "ledgerId": "ledger1", "journalEntryId": "transfer-1", "date": 1234567890, "type": "debit", "description": "rent", "amount": 10, "runningBalance": 90, "transferId": "transfer-1"},
+				"credit": {"id": "tx-2", "ledgerId": "ledger2", "journalEntryId": "transfer-1", "date": 1234567890, "type": "credit", "description": "rent", "amount": 10, "runningBalance": 10, "transferId": "transfer-1"}
+			}}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				reqDTO := ledger.TransferRequestDTO{
+					SourceLedgerID:      "ledger1",
+					DestinationLedgerID: "ledger2",
+					Description:         "rent",
+					Currency:            "EUR",
+					Amount:              ledger.MustParseMoney("10"),
+				}
+				mStore.On("Transfer", mock.Anything, reqDTO).Return(ledger.Transfer{
+					ID: "transfer-1",
+					Debit: ledger.Transaction{
+						ID:             "tx-1",
+						LedgerID:       "ledger1",
+						JournalEntryID: "transfer-1",
+						Date:           1234567890,
+						Type:           ledger.Debit,
+						Description:    "rent",
+						Amount:         ledger.MustParseMoney("10"),
+						RunningBalance: ledger.MustParseMoney("90"),
+						TransferID:     "transfer-1",
+					},
+					Credit: ledger.Transaction{
+						ID:             "tx-2",
+						LedgerID:       "ledger2",
+						JournalEntryID: "transfer-1",
+						Date:           1234567890,
+						Type:           ledger.Credit,
+						Description:    "rent",
+						Amount:         ledger.MustParseMoney("10"),
+						RunningBalance: ledger.MustParseMoney("10"),
+						TransferID:     "transfer-1",
+					},
+				}, nil)
+				return mStore
+			},
+		},
+		{
+			name:           "Store error during transfer",
+			requestBody:    `{"sourceLedgerId": "ledger1", "destinationLedgerId": "ledger2", "description": "rent", "currency": "EUR", "amount": 10}`,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error": "failed to perform transfer: 10, got error: store error", "code": "internal_error"}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("Transfer", mock.Anything, mock.Anything).Return(ledger.Transfer{}, errors.New("store error"))
+				return mStore
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := tc.storeSetup()
+
+			req := httptest.NewRequest("POST", "/transfers", bytes.NewBufferString(tc.requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler := ledger.DoTransfer(store)
+			handler(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.JSONEq(t, tc.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestDoJournalEntry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		requestBody    string
+		expectedStatus int
+		expectedBody   string
+		storeSetup     func() ledger.Store
+	}{
+		{
+			name:           "Invalid JSON payload",
+			requestBody:    `invalid json`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "failed get valid request payload", "code": "invalid_request"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Single leg",
+			requestBody:    `{"description": "fee", "currency": "EUR", "legs": [{"ledgerId": "ledger1", "type": "debit", "amount": 1}]}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": "failed get at least two legs", "code": "validation_failed"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name: "Unbalanced legs",
+			requestBody: `{"description": "fee", "currency": "EUR", "legs": [
+				{"ledgerId": "ledger1", "type": "debit", "amount": 1.5},
+				{"ledgerId": "fees", "type": "credit", "amount": 1}
+			]}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": "failed get balanced legs, got credits 1 and debits 1.5", "code": "validation_failed"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name: "Successful journal entry",
+			requestBody: `{"description": "fee", "currency": "EUR", "legs": [
+				{"ledgerId": "ledger1", "type": "debit", "amount": 1.5},
+				{"ledgerId": "fees", "type": "credit", "amount": 1.5}
+			]}`,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"data": {"id": "entry-1", "date": 1234567890, "description": "fee", "currency": "EUR", "transactions": [
+				{"id": "tx-1", "ledgerId": "ledger1", "journalEntryId": "entry-1", "date": 1234567890, "type": "debit", "description": "fee", "amount": 1.5, "runningBalance": 98.5},
+				{"id": "tx-2", "ledgerId": "fees", "journalEntryId": "entry-1", "date": 1234567890, "type": "credit", "description": "fee", "amount": 1.5, "runningBalance": 1.5}
+			]}}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				reqDTO := ledger.JournalEntryRequestDTO{
+					Description: "fee",
+					Currency:    "EUR",
+					Legs: []ledger.LegDTO{
+						{LedgerID: "ledger1", Type: ledger.Debit, Amount: ledger.MustParseMoney("1.5")},
+						{LedgerID: "fees", Type: ledger.Credit, Amount: ledger.MustParseMoney("1.5")},
+					},
+				}
+				mStore.On("Post", mock.Anything, reqDTO).Return(ledger.JournalEntry{
+					ID:          "entry-1",
+					Date:        1234567890,
+					Description: "fee",
+					Currency:    "EUR",
+					Transactions: []ledger.Transaction{
+						{
+							ID:             "tx-1",
+							LedgerID:       "ledger1",
+							JournalEntryID: "entry-1",
+							Date:           1234567890,
+							Type:           ledger.Debit,
+							Description:    "fee",
+							Amount:         ledger.MustParseMoney("1.5"),
+							RunningBalance: ledger.MustParseMoney("98.5"),
+						},
+						{
+							ID:             "tx-2",
+							LedgerID:       "fees",
+							JournalEntryID: "entry-1",
+							Date:           1234567890,
+							Type:           ledger.Credit,
+							Description:    "fee",
+							Amount:         ledger.MustParseMoney("1.5"),
+							RunningBalance: ledger.MustParseMoney("1.5"),
+						},
+					},
+				}, nil)
+				return mStore
+			},
+		},
+		{
+			name: "Store error during journal entry",
+			requestBody: `{"description": "fee", "currency": "EUR", "legs": [
+				{"ledgerId": "ledger1", "type": "debit", "amount": 1.5},
+				{"ledgerId": "fees", "type": "credit", "amount": 1.5}
+			]}`,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error": "failed to post journal entry, got error: store error", "code": "internal_error"}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("Post", mock.Anything, mock.Anything).Return(ledger.JournalEntry{}, errors.New("store error"))
+				return mStore
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := tc.storeSetup()
+
+			req := httptest.NewRequest("POST", "/journal-entries", bytes.NewBufferString(tc.requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+
+			handler := ledger.DoJournalEntry(store)
+			handler(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.JSONEq(t, tc.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestLedgerLifecycleHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	policy := ledger.BalancePolicy{Type: ledger.PolicyNonNegative}
+	overdraft := ledger.BalancePolicy{Type: ledger.PolicyOverdraft, OverdraftLimit: ledger.MustParseMoney("500")}
+	openLedger := ledger.Ledger{ID: "ledger1", Type: "cash", Currency: "EUR", Status: ledger.LedgerOpen, CreatedAt: 1234567890, Policy: policy, Version: 1}
+	closedLedger := ledger.Ledger{ID: "ledger1", Type: "cash", Currency: "EUR", Status: ledger.LedgerClosed, CreatedAt: 1234567890, ClosedAt: 1234567899, Policy: policy, Version: 2}
+	overdraftLedger := ledger.Ledger{ID: "ledger1", Type: "settlement", Currency: "EUR", Status: ledger.LedgerOpen, CreatedAt: 1234567890, Policy: overdraft, Version: 1}
+	openLedgerJSON := `{"id": "ledger1", "type": "cash", "currency": "EUR", "status": "open", "createdAt": 1234567890, "policy": {"type": "non-negative"}, "version": 1}`
+	overdraftLedgerJSON := `{"id": "ledger1", "type": "settlement", "currency": "EUR", "status": "open", "createdAt": 1234567890,
+		"policy": {"type": "overdraft", "overdraftLimit": 500}, "version": 1}`
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		params         gin.Params
+		requestBody    string
+		handler        func(ledger.Store) gin.HandlerFunc
+		expectedStatus int
+		expectedBody   string
+		storeSetup     func() ledger.Store
+	}{
+		{
+			name:           "Create ledger with invalid JSON payload",
+			method:         "POST",
+			target:         "/ledgers",
+			requestBody:    `invalid json`,
+			handler:        ledger.CreateLedger,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "failed get valid request payload", "code": "invalid_request"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Create ledger without type",
+			method:         "POST",
+			target:         "/ledgers",
+			requestBody:    `{"currency": "EUR"}`,
+			handler:        ledger.CreateLedger,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": "failed get valid ledger type", "code": "validation_failed"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Successful create ledger",
+			method:         "POST",
+			target:         "/ledgers",
+			requestBody:    `{"type": "cash", "currency": "EUR"}`,
+			handler:        ledger.CreateLedger,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"data": ` + openLedgerJSON + `}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("CreateLedger", mock.Anything, ledger.LedgerRequestDTO{Type: "cash", Currency: "EUR"}).Return(openLedger, nil)
+				return mStore
+			},
+		},
+		{
+			name:           "Successful create ledger with overdraft policy",
+			method:         "POST",
+			target:         "/ledgers",
+			requestBody:    `{"type": "settlement", "currency": "EUR", "policy": {"type": "overdraft", "overdraftLimit": 500}}`,
+			handler:        ledger.CreateLedger,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"data": ` + overdraftLedgerJSON + `}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("CreateLedger", mock.Anything, ledger.LedgerRequestDTO{Type: "settlement", Currency: "EUR", Policy: overdraft}).Return(overdraftLedger, nil)
+				return mStore
+			},
+		},
+		{
+			name:           "Create ledger with overdraft policy without limit",
+			method:         "POST",
+			target:         "/ledgers",
+			requestBody:    `{"type": "settlement", "currency": "EUR", "policy": {"type": "overdraft"}}`,
+			handler:        ledger.CreateLedger,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": "failed get overdraft limit greater than zero", "code": "validation_failed"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Create ledger with overdraft limit in minor units the currency lacks",
+			method:         "POST",
+			target:         "/ledgers",
+			requestBody:    `{"type": "settlement", "currency": "JPY", "policy": {"type": "overdraft", "overdraftLimit": 0.5}}`,
+			handler:        ledger.CreateLedger,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": "failed get overdraft limit with at most 0 decimal places for JPY", "code": "validation_failed"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Successful set balance policy",
+			method:         "PUT",
+			target:         "/admin/ledgers/ledger1/policy",
+			params:         gin.Params{{Key: "ledgerId", Value: "ledger1"}},
+			requestBody:    `{"type": "overdraft", "overdraftLimit": 500}`,
+			handler:        ledger.SetBalancePolicy,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"data": ` + overdraftLedgerJSON + `}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("SetBalancePolicy", mock.Anything, "ledger1", overdraft).Return(overdraftLedger, nil)
+				return mStore
+			},
+		},
+		{
+			name:           "Set unknown balance policy",
+			method:         "PUT",
+			target:         "/admin/ledgers/ledger1/policy",
+			params:         gin.Params{{Key: "ledgerId", Value: "ledger1"}},
+			requestBody:    `{"type": "generous"}`,
+			handler:        ledger.SetBalancePolicy,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": "failed get policy type either positive, non-negative, overdraft or unlimited", "code": "validation_failed"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Set balance policy with overdraft limit for other policy",
+			method:         "PUT",
+			target:         "/admin/ledgers/ledger1/policy",
+			params:         gin.Params{{Key: "ledgerId", Value: "ledger1"}},
+			requestBody:    `{"type": "non-negative", "overdraftLimit": 500}`,
+			handler:        ledger.SetBalancePolicy,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": "failed get overdraft limit only for overdraft policy", "code": "validation_failed"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Store error during set balance policy",
+			method:         "PUT",
+			target:         "/admin/ledgers/ledger1/policy",
+			params:         gin.Params{{Key: "ledgerId", Value: "ledger1"}},
+			requestBody:    `{"type": "unlimited"}`,
+			handler:        ledger.SetBalancePolicy,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error": "failed to perform set balance policy, got error: store error", "code": "internal_error"}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("SetBalancePolicy", mock.Anything, "ledger1", ledger.BalancePolicy{Type: ledger.PolicyUnlimited}).Return(ledger.Ledger{}, errors.New("store error"))
+				return mStore
+			},
+		},
+		{
+			name:           "List ledgers with invalid limit",
+			method:         "GET",
+			target:         "/ledgers?limit=abc",
+			handler:        ledger.ListLedgers,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "failed get limit between 1 and 200", "code": "invalid_request"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Successful list ledgers",
+			method:         "GET",
+			target:         "/ledgers?type=cash&limit=1&cursor=ledger0",
+			handler:        ledger.ListLedgers,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"data": {"ledgers": [` + openLedgerJSON + `], "nextCursor": "ledger1"}}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("ListLedgers", mock.Anything, ledger.LedgerQuery{Type: "cash", Cursor: "ledger0", Limit: 1}).Return(ledger.LedgerPage{
+					Ledgers:    []ledger.Ledger{openLedger},
+					NextCursor: "ledger1",
+				}, nil)
+				return mStore
+			},
+		},
+		{
+			name:           "Successful view ledger",
+			method:         "GET",
+			target:         "/ledgers/ledger1",
+			params:         gin.Params{{Key: "ledgerId", Value: "ledger1"}},
+			handler:        ledger.ViewLedger,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"data": ` + openLedgerJSON + `}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("GetLedger", mock.Anything, "ledger1").Return(openLedger, nil)
+				return mStore
+			},
+		},
+		{
+			name:           "Close ledger without ledgerId",
+			method:         "POST",
+			target:         "/ledgers//close",
+			handler:        ledger.CloseLedger,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "failed get valid ledgerId", "code": "invalid_request"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Successful close ledger",
+			method:         "POST",
+			target:         "/ledgers/ledger1/close",
+			params:         gin.Params{{Key: "ledgerId", Value: "ledger1"}},
+			handler:        ledger.CloseLedger,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"data": {"id": "ledger1", "type": "cash", "currency": "EUR", "status": "closed", "createdAt": 1234567890, "closedAt": 1234567899, "policy": {"type": "non-negative"}, "version": 2}}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("CloseLedger", mock.Anything, "ledger1").Return(closedLedger, nil)
+				return mStore
+			},
+		},
+		{
+			name:           "Store error during close ledger",
+			method:         "POST",
+			target:         "/ledgers/ledger1/close",
+			params:         gin.Params{{Key: "ledgerId", Value: "ledger1"}},
+			handler:        ledger.CloseLedger,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error": "failed to perform close ledger, got error: store error", "code": "internal_error"}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("CloseLedger", mock.Anything, "ledger1").Return(ledger.Ledger{}, errors.New("store error"))
+				return mStore
+			},
+		},
+		{
+			name:           "Successful view ledger events",
+			method:         "GET",
+			target:         "/ledgers/ledger1/events?after=1&limit=1",
+			params:         gin.Params{{Key: "ledgerId", Value: "ledger1"}},
+			handler:        ledger.ViewLedgerEvents,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"data": {"ledgerId": "ledger1", "nextAfter": 2, "events": [{"ledgerId": "ledger1", "version": 2,
+				"type": "ledger-closed", "date": 1234567899, "ledger": {"id": "ledger1", "type": "cash", "currency": "EUR",
+				"status": "closed", "createdAt": 1234567890, "closedAt": 1234567899, "policy": {"type": "non-negative"}, "version": 2}}]}}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("GetLedgerEvents", mock.Anything, "ledger1", ledger.EventQuery{After: 1, Limit: 1}).Return(ledger.EventPage{
+					LedgerID:  "ledger1",
+					NextAfter: 2,
+					Events: []ledger.Event{
+						{LedgerID: "ledger1", Version: 2, Type: ledger.EventLedgerClosed, Date: 1234567899, Ledger: &closedLedger},
+					},
+				}, nil)
+				return mStore
+			},
+		},
+		{
+			name:           "View ledger events with invalid after",
+			method:         "GET",
+			target:         "/ledgers/ledger1/events?after=-1",
+			params:         gin.Params{{Key: "ledgerId", Value: "ledger1"}},
+			handler:        ledger.ViewLedgerEvents,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "failed get after as version of at least 0", "code": "invalid_request"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "View ledger events with too large limit",
+			method:         "GET",
+			target:         "/ledgers/ledger1/events?limit=1001",
+			params:         gin.Params{{Key: "ledgerId", Value: "ledger1"}},
+			handler:        ledger.ViewLedgerEvents,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "failed get limit between 1 and 1000", "code": "invalid_request"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "View events of unknown ledger",
+			method:         "GET",
+			target:         "/ledgers/unknown/events",
+			params:         gin.Params{{Key: "ledgerId", Value: "unknown"}},
+			handler:        ledger.ViewLedgerEvents,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error": "failed to perform view ledger events, got error: failed to get ledger events, got error : failed get ledger: unknown", "code": "not_found"}`,
+			storeSetup: func() ledger.Store {
+				return ledger.NewStore(ledger.NewUUIDGenerator(), newLedgersWithCounterAccount(), counterAccountOptions)
+			},
+		},
+		{
+			name:           "Successful view ledger at version",
+			method:         "GET",
+			target:         "/ledgers/ledger1/versions/1",
+			params:         gin.Params{{Key: "ledgerId", Value: "ledger1"}, {Key: "version", Value: "1"}},
+			handler:        ledger.ViewLedgerAtVersion,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"data": {"ledger": ` + openLedgerJSON + `, "balance": 12.5, "held": 2}}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("GetLedgerAtVersion", mock.Anything, "ledger1", int64(1)).Return(ledger.LedgerState{
+					Ledger:  openLedger,
+					Balance: ledger.MustParseMoney("12.5"),
+					Held:    ledger.MustParseMoney("2"),
+				}, nil)
+				return mStore
+			},
+		},
+		{
+			name:           "View ledger at invalid version",
+			method:         "GET",
+			target:         "/ledgers/ledger1/versions/0",
+			params:         gin.Params{{Key: "ledgerId", Value: "ledger1"}, {Key: "version", Value: "0"}},
+			handler:        ledger.ViewLedgerAtVersion,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "failed get version of at least 1", "code": "invalid_request"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "View ledger at version it has not reached",
+			method:         "GET",
+			target:         "/ledgers/counter-eur/versions/2",
+			params:         gin.Params{{Key: "ledgerId", Value: "counter-eur"}, {Key: "version", Value: "2"}},
+			handler:        ledger.ViewLedgerAtVersion,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error": "failed to perform view ledger at version, got error: failed get version 2 of ledger counter-eur, got versions 1 to 1", "code": "not_found"}`,
+			storeSetup: func() ledger.Store {
+				return ledger.NewStore(ledger.NewUUIDGenerator(), newLedgersWithCounterAccount(), counterAccountOptions)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := tc.storeSetup()
+
+			req := httptest.NewRequest(tc.method, tc.target, bytes.NewBufferString(tc.requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			c, _ := gin.CreateTestContext(w)
+			c.Params = tc.params
+			c.Request = req
+
+			handler := tc.handler(store)
+			handler(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.JSONEq(t, tc.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestHoldHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	activeHold := ledger.Hold{
+		ID:          "hold1",
+		LedgerID:    "ledger1",
+		Description: "card authorisation",
+		Currency:    "EUR",
+		Amount:      ledger.MustParseMoney("25"),
+		Status:      ledger.HoldActive,
+		CreatedAt:   1234567890,
+		ExpiresAt:   4102444800000,
+	}
+	activeHoldJSON := `{"id": "hold1", "ledgerId": "ledger1", "description": "card authorisation", "currency": "EUR", "amount": 25,
+		"status": "active", "createdAt": 1234567890, "expiresAt": 4102444800000, "capturedAmount": 0}`
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		params         gin.Params
+		requestBody    string
+		handler        func(ledger.Store) gin.HandlerFunc
+		expectedStatus int
+		expectedBody   string
+		storeSetup     func() ledger.Store
+	}{
+		{
+			name:           "Place hold",
+			method:         "POST",
+			target:         "/ledger/ledger1/holds",
+			params:         gin.Params{{Key: "ledgerId", Value: "ledger1"}},
+			requestBody:    `{"description": "card authorisation", "currency": "EUR", "amount": 25, "expiresAt": 4102444800000}`,
+			handler:        ledger.PlaceHold,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"data": ` + activeHoldJSON + `}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("PlaceHold", mock.Anything, "ledger1", ledger.HoldRequestDTO{
+					Description: "card authorisation",
+					Currency:    "EUR",
+					Amount:      ledger.MustParseMoney("25"),
+					ExpiresAt:   4102444800000,
+				}).Return(activeHold, nil)
+				return mStore
+			},
+		},
+		{
+			name:           "Place hold expiring in the past",
+			method:         "POST",
+			target:         "/ledger/ledger1/holds",
+			params:         gin.Params{{Key: "ledgerId", Value: "ledger1"}},
+			requestBody:    `{"description": "card authorisation", "currency": "EUR", "amount": 25, "expiresAt": 1234567890}`,
+			handler:        ledger.PlaceHold,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": "failed get expiresAt in the future", "code": "validation_failed"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Place hold with invalid JSON payload",
+			method:         "POST",
+			target:         "/ledger/ledger1/holds",
+			params:         gin.Params{{Key: "ledgerId", Value: "ledger1"}},
+			requestBody:    `invalid json`,
+			handler:        ledger.PlaceHold,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "failed get valid request payload", "code": "invalid_request"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Store error during place hold",
+			method:         "POST",
+			target:         "/ledger/ledger1/holds",
+			params:         gin.Params{{Key: "ledgerId", Value: "ledger1"}},
+			requestBody:    `{"description": "card authorisation", "currency": "EUR", "amount": 25, "expiresAt": 4102444800000}`,
+			handler:        ledger.PlaceHold,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error": "failed to perform place hold, got error: store error", "code": "internal_error"}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("PlaceHold", mock.Anything, "ledger1", mock.Anything).Return(ledger.Hold{}, errors.New("store error"))
+				return mStore
+			},
+		},
+		{
+			name:           "View hold",
+			method:         "GET",
+			target:         "/holds/hold1",
+			params:         gin.Params{{Key: "holdId", Value: "hold1"}},
+			handler:        ledger.ViewHold,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"data": ` + activeHoldJSON + `}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("GetHold", mock.Anything, "hold1").Return(activeHold, nil)
+				return mStore
+			},
+		},
+		{
+			name:           "Capture part of hold",
+			method:         "POST",
+			target:         "/holds/hold1/capture",
+			params:         gin.Params{{Key: "holdId", Value: "hold1"}},
+			requestBody:    `{"amount": 20}`,
+			handler:        ledger.CaptureHold,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"data": {
+				"hold": {"id": "hold1", "ledgerId": "ledger1", "description": "card authorisation", "currency": "EUR", "amount": 25,
+					"status": "captured", "createdAt": 1234567890, "expiresAt": 4102444800000, "closedAt": 1234567899,
+					"capturedAmount": 20, "transactionId": "tx-1"},
+				"transaction": {"id": "tx-1", "ledgerId": "ledger1", "journalEntryId": "entry-1", "date": 1234567899, "type": "debit",
+					"description": "card authorisation", "amount": 20, "runningBalance": 80}
+			}}`,
+			storeSetup: func() ledger.Store {
+				captured := activeHold
+				captured.Status = ledger.HoldCaptured
+				captured.ClosedAt = 1234567899
+				captured.CapturedAmount = ledger.MustParseMoney("20")
+				captured.TransactionID = "tx-1"
+				mStore := new(internalMock.Store)
+				mStore.On("CaptureHold", mock.Anything, "hold1", ledger.CaptureRequestDTO{Amount: ledger.MustParseMoney("20")}).Return(ledger.HoldCapture{
+					Hold: captured,
+					Transaction: ledger.Transaction{
+						ID:             "tx-1",
+						LedgerID:       "ledger1",
+						JournalEntryID: "entry-1",
+						Date:           1234567899,
+						Type:           ledger.Debit,
+						Description:    "card authorisation",
+						Amount:         ledger.MustParseMoney("20"),
+						RunningBalance: ledger.MustParseMoney("80"),
+					},
+				}, nil)
+				return mStore
+			},
+		},
+		{
+			name:           "Capture with negative amount",
+			method:         "POST",
+			target:         "/holds/hold1/capture",
+			params:         gin.Params{{Key: "holdId", Value: "hold1"}},
+			requestBody:    `{"amount": -1}`,
+			handler:        ledger.CaptureHold,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": "failed get amount greater than zero", "code": "validation_failed"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Release hold that is no longer active",
+			method:         "POST",
+			target:         "/holds/hold1/release",
+			params:         gin.Params{{Key: "holdId", Value: "hold1"}},
+			handler:        ledger.ReleaseHold,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error": "failed to perform release hold, got error: failed to release hold, got error : failed get active hold, got released hold: hold1", "code": "internal_error"}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("ReleaseHold", mock.Anything, "hold1").Return(ledger.Hold{}, errors.New("failed to release hold, got error : failed get active hold, got released hold: hold1"))
+				return mStore
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := tc.storeSetup()
+
+			req := httptest.NewRequest(tc.method, tc.target, bytes.NewBufferString(tc.requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			c, _ := gin.CreateTestContext(w)
+			c.Params = tc.params
+			c.Request = req
+
+			handler := tc.handler(store)
+			handler(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.JSONEq(t, tc.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestReverseTransaction(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	reversal := ledger.Reversal{
+		ID: "entry-2",
+		Original: ledger.Transaction{
+			ID:             "tx-1",
+			LedgerID:       "ledger1",
+			JournalEntryID: "entry-1",
+			Date:           1234567890,
+			Type:           ledger.Debit,
+			Description:    "payment",
+			Amount:         ledger.MustParseMoney("40"),
+			RunningBalance: ledger.MustParseMoney("60"),
+			ReversedBy:     "tx-3",
+		},
+		Transaction: ledger.Transaction{
+			ID:             "tx-3",
+			LedgerID:       "ledger1",
+			JournalEntryID: "entry-2",
+			Date:           1234567899,
+			Type:           ledger.Credit,
+			Description:    "refund",
+			Amount:         ledger.MustParseMoney("15"),
+			RunningBalance: ledger.MustParseMoney("75"),
+			ReversalOf:     "tx-1",
+		},
+	}
+	reversalJSON := `{"data": {
+		"id": "entry-2",
+		"original": {"id": "tx-1", "ledgerId": "ledger1", "journalEntryId": "entry-1", "date": 1234567890, "type": "debit",
+			"description": "payment", "amount": 40, "runningBalance": 60, "reversedBy": "tx-3"},
+		"transaction": {"id": "tx-3", "ledgerId": "ledger1", "journalEntryId": "entry-2", "date": 1234567899, "type": "credit",
+			"description": "refund", "amount": 15, "runningBalance": 75, "reversalOf": "tx-1"}
+	}}`
+	params := gin.Params{{Key: "ledgerId", Value: "ledger1"}, {Key: "txId", Value: "tx-1"}}
+
+	tests := []struct {
+		name           string
+		params         gin.Params
+		requestBody    string
+		expectedStatus int
+		expectedBody   string
+		storeSetup     func() ledger.Store
+	}{
+		{
+			name:           "Partial reversal",
+			params:         params,
+			requestBody:    `{"description": "refund", "amount": 15}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   reversalJSON,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("ReverseTransaction", mock.Anything, "ledger1", "tx-1", ledger.ReversalRequestDTO{
+					Description: "refund",
+					Amount:      ledger.MustParseMoney("15"),
+				}).Return(reversal, nil)
+				return mStore
+			},
+		},
+		{
+			name:           "Empty body reverses the whole transaction",
+			params:         params,
+			expectedStatus: http.StatusOK,
+			expectedBody:   reversalJSON,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("ReverseTransaction", mock.Anything, "ledger1", "tx-1", ledger.ReversalRequestDTO{}).Return(reversal, nil)
+				return mStore
+			},
+		},
+		{
+			name:           "Missing txId",
+			params:         gin.Params{{Key: "ledgerId", Value: "ledger1"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "failed get valid txId", "code": "invalid_request"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Reversal with negative amount",
+			params:         params,
+			requestBody:    `{"amount": -15}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": "failed get amount greater than zero", "code": "validation_failed"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Reversal with invalid JSON payload",
+			params:         params,
+			requestBody:    `invalid json`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "failed get valid request payload", "code": "invalid_request"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Reversal of transaction already reversed",
+			params:         params,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error": "failed to perform reverse transaction, got error: failed to reverse transaction, got error : failed to reverse transaction tx-1 again, got reversal tx-3", "code": "internal_error"}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("ReverseTransaction", mock.Anything, "ledger1", "tx-1", ledger.ReversalRequestDTO{}).
+					Return(ledger.Reversal{}, errors.New("failed to reverse transaction, got error : failed to reverse transaction tx-1 again, got reversal tx-3"))
+				return mStore
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := tc.storeSetup()
+
+			req := httptest.NewRequest("POST", "/ledger/ledger1/transactions/tx-1/reverse", bytes.NewBufferString(tc.requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			c, _ := gin.CreateTestContext(w)
+			c.Params = tc.params
+			c.Request = req
+
+			handler := ledger.ReverseTransaction(store)
+			handler(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.JSONEq(t, tc.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestViewBalance(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		ledgerId       string
+		rawQuery       string
+		expectedStatus int
+		expectedBody   string
+		storeSetup     func() ledger.Store
+	}{
+		{
+			name:           "Missing ledgerId parameter",
+			ledgerId:       "",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "failed get valid ledgerId", "code": "invalid_request"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Successful view balance",
+			ledgerId:       "ledger1",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"data": {"ledgerId": "ledger1", "currency": "GBP", "balance": 146.32}}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("GetLastBalance", mock.Anything, "ledger1").Return(ledger.Balance{
+					LedgerID: "ledger1",
+					Currency: "GBP",
+					Balance:  ledger.MustParseMoney("146.32"),
+				}, nil)
+				return mStore
+			},
+		},
+		{
+			name:           "View balance of unknown ledger",
+			ledgerId:       "unknown",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error": "failed to perform view balance, got error: failed to get last balance, got error : failed get ledger: unknown", "code": "not_found"}`,
+			storeSetup: func() ledger.Store {
+				return ledger.NewStore(ledger.NewUUIDGenerator(), newLedgersWithCounterAccount(), counterAccountOptions)
+			},
+		},
+		{
+			name:           "Store error during view balance",
+			ledgerId:       "ledger1",
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error": "failed to perform view balance, got error: store error", "code": "internal_error"}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("GetLastBalance", mock.Anything, "ledger1").Return(ledger.Balance{}, errors.New("store error"))
+				return mStore
+			},
+		},
+		{
+			name:           "Successful view balance as of unix milliseconds",
+			ledgerId:       "ledger1",
+			rawQuery:       "asOf=1743465599999",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"data": {"ledgerId": "ledger1", "currency": "EUR", "balance": 42.5, "asOf": 1743465599999}}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("GetBalanceAsOf", mock.Anything, "ledger1", int64(1743465599999)).Return(ledger.Balance{
+					LedgerID: "ledger1",
+					Currency: "EUR",
+					Balance:  ledger.MustParseMoney("42.5"),
+					AsOf:     1743465599999,
+				}, nil)
+				return mStore
+			},
+		},
+		{
+			name:           "Successful view balance as of RFC 3339 timestamp",
+			ledgerId:       "ledger1",
+			rawQuery:       "asOf=2025-03-31T23:59:59.999Z",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"data": {"ledgerId": "ledger1", "currency": "EUR", "balance": 42.5, "asOf": 1743465599999}}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("GetBalanceAsOf", mock.Anything, "ledger1", int64(1743465599999)).Return(ledger.Balance{
+					LedgerID: "ledger1",
+					Currency: "EUR",
+					Balance:  ledger.MustParseMoney("42.5"),
+					AsOf:     1743465599999,
+				}, nil)
+				return mStore
+			},
+		},
+		{
+			name:           "Invalid asOf",
+			ledgerId:       "ledger1",
+			rawQuery:       "asOf=end-of-month",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "failed get asOf as unix milliseconds or RFC 3339 timestamp", "code": "invalid_request"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := tc.storeSetup()
+
+			req := httptest.NewRequest("GET", "/ledger/:ledgerId/balance?"+tc.rawQuery, nil)
+			w := httptest.NewRecorder()
+
+			c, _ := gin.CreateTestContext(w)
+			if tc.ledgerId != "" {
+				c.Params = []gin.Param{{Key: "ledgerId", Value: tc.ledgerId}}
+			}
+			c.Request = req
+
+			handler := ledger.ViewBalance(store)
+			handler(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.JSONEq(t, tc.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestViewTransactionHistory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		ledgerId       string
+		rawQuery       string
+		expectedStatus int
+		expectedBody   string
+		storeSetup     func() ledger.Store
+	}{
+		{
+			name:           "Missing ledgerId parameter",
+			ledgerId:       "",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "failed get valid ledgerId", "code": "invalid_request"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "View transaction history of unknown ledger",
+			ledgerId:       "unknown",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error": "failed to perform view transaction history, got error: failed to get transaction history, got error : failed get ledger: unknown", "code": "not_found"}`,
+			storeSetup: func() ledger.Store {
+				return ledger.NewStore(ledger.NewUUIDGenerator(), newLedgersWithCounterAccount(), counterAccountOptions)
+			},
+		},
+		{
+			name:           "Successful view transaction history",
+			ledgerId:       "ledger1",
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"data": {"ledgerId": "ledger1", "currency": "JPY", "transactions": [
+				{"id": "tx-1", "ledgerId": "ledger1", "journalEntryId": "entry-1", "date": 1234567890, "type": "credit", "description": "deposit", "amount": 500, "runningBalance": 500}
+			]}}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("GetTransactionHistory", mock.Anything, "ledger1", ledger.StatementQuery{}).Return(ledger.Statement{
+					LedgerID: "ledger1",
+					Currency: "JPY",
+					Transactions: []ledger.Transaction{
+						{
+							ID:             "tx-1",
+							LedgerID:       "ledger1",
+							JournalEntryID: "entry-1",
+							Date:           1234567890,
+							Type:           ledger.Credit,
+							Description:    "deposit",
+							Amount:         ledger.MustParseMoney("500"),
+							RunningBalance: ledger.MustParseMoney("500"),
+						},
+					},
+				}, nil)
+				return mStore
+			},
+		},
+		{
+			name:           "Store error during view transaction history",
+			ledgerId:       "ledger1",
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error": "failed to perform view transaction history, got error: store error", "code": "internal_error"}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("GetTransactionHistory", mock.Anything, "ledger1", ledger.StatementQuery{}).Return(ledger.Statement{}, errors.New("store error"))
+				return mStore
+			},
+		},
+		{
+			name:           "Filtered page of transaction history",
+			ledgerId:       "ledger1",
+			rawQuery:       "from=1000&to=2000&type=debit&minAmount=1.5&maxAmount=100&description=rent&cursor=tx-0&limit=1",
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"data": {"ledgerId": "ledger1", "currency": "EUR", "nextCursor": "tx-1", "transactions": [
+				{"id": "tx-1", "ledgerId": "ledger1", "journalEntryId": "entry-1", "date": 1500, "type": "debit", "description": "rent", "amount": 50, "runningBalance": 10}
+			]}}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				query := ledger.StatementQuery{
+					From:        1000,
+					To:          2000,
+					Type:        ledger.Debit,
+					MinAmount:   ledger.MustParseMoney("1.5"),
+					MaxAmount:   ledger.MustParseMoney("100"),
+					Description: "rent",
+					Cursor:      "tx-0",
+					Limit:       1,
+				}
+				mStore.On("GetTransactionHistory", mock.Anything, "ledger1", query).Return(ledger.Statement{
+					LedgerID: "ledger1",
+					Currency: "EUR",
+					Transactions: []ledger.Transaction{
+						{
+							ID:             "tx-1",
+							LedgerID:       "ledger1",
+							JournalEntryID: "entry-1",
+							Date:           1500,
+							Type:           ledger.Debit,
+							Description:    "rent",
+							Amount:         ledger.MustParseMoney("50"),
+							RunningBalance: ledger.MustParseMoney("10"),
+						},
+					},
+					NextCursor: "tx-1",
+				}, nil)
+				return mStore
+			},
+		},
+		{
+			name:           "Invalid limit",
+			ledgerId:       "ledger1",
+			rawQuery:       "limit=501",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "failed get limit between 1 and 500", "code": "invalid_request"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Invalid from",
+			ledgerId:       "ledger1",
+			rawQuery:       "from=yesterday",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "failed get from as unix milliseconds", "code": "invalid_request"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "From not before to",
+			ledgerId:       "ledger1",
+			rawQuery:       "from=2000&to=1000",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "failed get from before to", "code": "invalid_request"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Invalid type",
+			ledgerId:       "ledger1",
+			rawQuery:       "type=refund",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "failed get transaction type either credit or debit", "code": "invalid_request"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Invalid amount range",
+			ledgerId:       "ledger1",
+			rawQuery:       "minAmount=10&maxAmount=5",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "failed get minAmount not greater than maxAmount", "code": "invalid_request"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Unknown cursor",
+			ledgerId:       "ledger1",
+			rawQuery:       "cursor=tx-unknown",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "failed to perform view transaction history, got error: failed to get transaction history, got error : failed get transaction of the ledger for cursor: tx-unknown", "code": "invalid_request"}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("GetTransactionHistory", mock.Anything, "ledger1", ledger.StatementQuery{Cursor: "tx-unknown"}).
+					Return(ledger.Statement{}, fmt.Errorf("failed to get transaction history, got error : %w: tx-unknown", ledger.ErrUnknownCursor))
+				return mStore
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := tc.storeSetup()
+
+			req := httptest.NewRequest("GET", "/ledger/:ledgerId/statement?"+tc.rawQuery, nil)
+			w := httptest.NewRecorder()
+
+			c, _ := gin.CreateTestContext(w)
+			if tc.ledgerId != "" {
+				c.Params = []gin.Param{{Key: "ledgerId", Value: tc.ledgerId}}
+			}
+			c.Request = req
+
+			handler := ledger.ViewTransactionHistory(store)
+			handler(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.JSONEq(t, tc.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestVerifyLedger(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		ledgerId       string
+		expectedStatus int
+		expectedBody   string
+		storeSetup     func() ledger.Store
+	}{
+		{
+			name:           "Missing ledgerId parameter",
+			ledgerId:       "",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "failed get valid ledgerId", "code": "invalid_request"}`,
+			storeSetup: func() ledger.Store {
+				return new(internalMock.Store)
+			},
+		},
+		{
+			name:           "Verified ledger",
+			ledgerId:       "ledger1",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"data": {"ledgerId": "ledger1", "verified": true, "checked": 2, "head": "hash-2"}}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("VerifyLedger", mock.Anything, "ledger1").Return(ledger.ChainVerification{
+					LedgerID: "ledger1",
+					Verified: true,
+					Checked:  2,
+					Head:     "hash-2",
+				}, nil)
+				return mStore
+			},
+		},
+		{
+			name:           "Broken link is reported as verification result",
+			ledgerId:       "ledger1",
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"data": {"ledgerId": "ledger1", "verified": false, "checked": 1, "head": "hash-1", "brokenLink": {
+				"sequence": 2, "transactionId": "tx-2", "previousHash": "hash-1", "expectedHash": "hash-2", "recordedHash": "altered"}}}`,
+			storeSetup: func() ledger.Store {
+				mStore := new(internalMock.Store)
+				mStore.On("VerifyLedger", mock.Anything, "ledger1").Return(ledger.ChainVerification{
+					LedgerID: "ledger1",
+					Checked:  1,
+					Head:     "hash-1",
+					BrokenLink: &ledger.BrokenLink{
+						Sequence:      2,
+						TransactionID: "tx-2",
+						PreviousHash:  "hash-1",
+						ExpectedHash:  "hash-2",
+						RecordedHash:  "altered",
+					},
+				}, nil)
+				return mStore
+			},
+		},
+		{
+			name:           "Verify unknown ledger",
+			ledgerId:       "unknown",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error": "failed to perform verify ledger, got error: failed to verify ledger, got error : failed get ledger: unknown", "code": "not_found"}`,
+			storeSetup: func() ledger.Store {
+				return ledger.NewStore(ledger.NewUUIDGenerator(), newLedgersWithCounterAccount(), counterAccountOptions)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := tc.storeSetup()
+
+			req := httptest.NewRequest("GET", "/ledger/:ledgerId/verify", nil)
+			w := httptest.NewRecorder()
+
+			c, _ := gin.CreateTestContext(w)
+			if tc.ledgerId != "" {
+				c.Params = []gin.Param{{Key: "ledgerId", Value: tc.ledgerId}}
+			}
+			c.Request = req
+
+			handler := ledger.VerifyLedger(store)
+			handler(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.JSONEq(t, tc.expectedBody, w.Body.String())
+		})
+	}
+}
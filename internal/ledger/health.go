@@ -0,0 +1,126 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// HealthCheckTimeout is how long the readiness probe waits for its checkers before reporting the
+// ones still running as down
+const HealthCheckTimeout = 2 * time.Second
+
+// component health statuses
+const (
+	HealthUp   = "up"
+	HealthDown = "down"
+)
+
+// HealthChecker checks that a component the service depends on is usable
+type HealthChecker interface {
+	Check(ctx context.Context) error
+}
+
+// HealthCheckFunc adapts a function to a HealthChecker
+type HealthCheckFunc func(ctx context.Context) error
+
+// Check calls f with the context
+func (f HealthCheckFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// StoreHealthChecker checks that the store is reachable and can still record changes
+func StoreHealthChecker(store Store) HealthChecker {
+	return HealthCheckFunc(store.Ping)
+}
+
+// HealthReport represents the readiness of the service, ready only when every component is up
+type HealthReport struct {
+	Ready      bool                       `json:"ready"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+// ComponentHealth represents the result of the check of one component
+type ComponentHealth struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Liveness handles the liveness probe, which only fails when the service cannot serve requests at
+// all, so that a restart is never triggered by a dependency being down
+func Liveness() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		SuccessHandler(ctx, http.StatusOK, gin.H{"alive": true})
+	}
+}
+
+// Readiness handles the readiness probe. It runs the checkers concurrently and reports each
+// component by name, with status 503 when any of them is down.
+func Readiness(checkers map[string]HealthChecker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		report := checkHealth(ctx.Request.Context(), checkers)
+		if report.Ready {
+			SuccessHandler(ctx, http.StatusOK, report)
+			return
+		}
+
+		names := make([]string, 0, len(report.Components))
+		for name, component := range report.Components {
+			if component.Status == HealthDown {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		loggerFrom(ctx.Request.Context()).Warn("service not ready", zap.Strings("components", names))
+		SuccessHandler(ctx, http.StatusServiceUnavailable, report)
+	}
+}
+
+// checkHealth runs the checkers concurrently, reporting those not done within HealthCheckTimeout
+// as down
+func checkHealth(ctx context.Context, checkers map[string]HealthChecker) HealthReport {
+	ctx, cancel := context.WithTimeout(ctx, HealthCheckTimeout)
+	defer cancel()
+
+	type result struct {
+		name string
+		err  error
+	}
+	results := make(chan result, len(checkers))
+	for name, checker := range checkers {
+		go func() {
+			results <- result{name: name, err: checker.Check(ctx)}
+		}()
+	}
+
+	report := HealthReport{Ready: true, Components: make(map[string]ComponentHealth, len(checkers))}
+	for name := range checkers {
+		report.Components[name] = ComponentHealth{
+			Status: HealthDown,
+			Error:  fmt.Sprintf("failed get health within %s", HealthCheckTimeout),
+		}
+	}
+	for range checkers {
+		select {
+		case r := <-results:
+			if r.err != nil {
+				report.Components[r.name] = ComponentHealth{Status: HealthDown, Error: r.err.Error()}
+				continue
+			}
+			report.Components[r.name] = ComponentHealth{Status: HealthUp}
+		case <-ctx.Done():
+		}
+	}
+	for _, component := range report.Components {
+		if component.Status == HealthDown {
+			report.Ready = false
+		}
+	}
+
+	return report
+}
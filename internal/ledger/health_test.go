@@ -0,0 +1,119 @@
+package ledger_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+	internalMock "github.com/dineshd30/ledger-service/internal/mock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadiness(t *testing.T) {
+	up := ledger.HealthCheckFunc(func(context.Context) error { return nil })
+	down := ledger.HealthCheckFunc(func(context.Context) error { return errors.New("failed get serving service, got draining service") })
+
+	tests := []struct {
+		name         string
+		mockStore    func() *internalMock.Store
+		checkers     map[string]ledger.HealthChecker
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "should report ready when every component is up",
+			mockStore: func() *internalMock.Store {
+				ms := new(internalMock.Store)
+				ms.On("Ping", mock.Anything).Return(nil)
+				return ms
+			},
+			checkers:     map[string]ledger.HealthChecker{"draining": up},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"data":{"ready":true,"components":{"draining":{"status":"up"},"store":{"status":"up"}}}}`,
+		},
+		{
+			name: "should report unready when the store is down",
+			mockStore: func() *internalMock.Store {
+				ms := new(internalMock.Store)
+				ms.On("Ping", mock.Anything).Return(errors.New("failed to ping store, got error : file already closed"))
+				return ms
+			},
+			checkers:     map[string]ledger.HealthChecker{"draining": up},
+			expectedCode: http.StatusServiceUnavailable,
+			expectedBody: `{"data":{"ready":false,"components":{"draining":{"status":"up"},"store":{"status":"down","error":"failed to ping store, got error : file already closed"}}}}`,
+		},
+		{
+			name: "should report unready when draining",
+			mockStore: func() *internalMock.Store {
+				ms := new(internalMock.Store)
+				ms.On("Ping", mock.Anything).Return(nil)
+				return ms
+			},
+			checkers:     map[string]ledger.HealthChecker{"draining": down},
+			expectedCode: http.StatusServiceUnavailable,
+			expectedBody: `{"data":{"ready":false,"components":{"draining":{"status":"down","error":"failed get serving service, got draining service"},"store":{"status":"up"}}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			ms := tt.mockStore()
+			tt.checkers["store"] = ledger.StoreHealthChecker(ms)
+
+			router := gin.New()
+			router.GET("/readyz", ledger.Readiness(tt.checkers))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			ms.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHoldSweeperCheck(t *testing.T) {
+	sweeper := ledger.NewHoldSweeper(newFundedStore(t), 5*time.Millisecond)
+	assert.Error(t, sweeper.Check(context.Background()), "not started")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		sweeper.Run(ctx)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool {
+		return sweeper.Check(context.Background()) == nil
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	<-done
+	assert.ErrorContains(t, sweeper.Check(context.Background()), "stopped sweeper")
+}
+
+func TestStorePing(t *testing.T) {
+	t.Run("should ping file store until closed", func(t *testing.T) {
+		storeInstance := openFileStore(t, filepath.Join(t.TempDir(), "ledger.wal"))
+		require.NoError(t, storeInstance.Ping(context.Background()))
+
+		require.NoError(t, storeInstance.Close())
+		assert.Error(t, storeInstance.Ping(context.Background()))
+	})
+
+	t.Run("should ping sql store until closed", func(t *testing.T) {
+		storeInstance := openSQLStore(t, sqliteDSN(t))
+		require.NoError(t, storeInstance.Ping(context.Background()))
+
+		require.NoError(t, storeInstance.Close())
+		assert.Error(t, storeInstance.Ping(context.Background()))
+	})
+}
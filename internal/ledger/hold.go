@@ -0,0 +1,414 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultHoldSweepInterval is how often expired holds are released when no interval is configured
+const DefaultHoldSweepInterval = time.Minute
+
+// HoldStatus represents the lifecycle state of a hold
+type HoldStatus string
+
+const (
+	HoldActive   HoldStatus = "active"
+	HoldCaptured HoldStatus = "captured"
+	HoldReleased HoldStatus = "released"
+	HoldExpired  HoldStatus = "expired"
+)
+
+// Hold represents funds reserved on a ledger until they are captured, released or the hold expires.
+// An active hold reduces the available balance of the ledger but not its posted balance.
+type Hold struct {
+	ID             string     `json:"id"`
+	LedgerID       string     `json:"ledgerId"`
+	Description    string     `json:"description"`
+	Currency       Currency   `json:"currency"`
+	Amount         Money      `json:"amount"`
+	Status         HoldStatus `json:"status"`
+	CreatedAt      int64      `json:"createdAt"`
+	ExpiresAt      int64      `json:"expiresAt"`
+	ClosedAt       int64      `json:"closedAt,omitempty"`
+	CapturedAmount Money      `json:"capturedAmount"`
+	TransactionID  string     `json:"transactionId,omitempty"`
+}
+
+// HoldRequestDTO represents the request payload for placing a hold; ExpiresAt is in unix milliseconds
+type HoldRequestDTO struct {
+	Description string   `json:"description"`
+	Currency    Currency `json:"currency"`
+	Amount      Money    `json:"amount"`
+	ExpiresAt   int64    `json:"expiresAt"`
+}
+
+// CaptureRequestDTO represents the request payload for capturing a hold. A zero amount captures
+// the whole hold and an empty description reuses the description of the hold.
+type CaptureRequestDTO struct {
+	Description string `json:"description"`
+	Amount      Money  `json:"amount"`
+}
+
+// HoldCapture represents a captured hold and the debit transaction it was captured into
+type HoldCapture struct {
+	Hold        Hold        `json:"hold"`
+	Transaction Transaction `json:"transaction"`
+}
+
+// Validate checks the hold request has a positive amount in a supported currency and expires in the future
+func (hrd HoldRequestDTO) Validate(now time.Time) error {
+	if !hrd.Amount.IsPositive() {
+		return errors.New("failed get amount greater than zero")
+	}
+
+	if err := hrd.Currency.Validate(); err != nil {
+		return err
+	}
+
+	if !hrd.Currency.Allows(hrd.Amount) {
+		return fmt.Errorf("failed get amount with at most %d decimal places for %s", hrd.Currency.MinorUnits(), hrd.Currency)
+	}
+
+	if hrd.ExpiresAt <= now.UTC().UnixMilli() {
+		return errors.New("failed get expiresAt in the future")
+	}
+
+	return nil
+}
+
+// PlaceHold reserves the amount on the ledger if the balance policy of the ledger allows the
+// available balance it leaves
+func (s *store) PlaceHold(ctx context.Context, ledgerId string, hrd HoldRequestDTO) (Hold, error) {
+	now := time.Now()
+	if err := hrd.Validate(now); err != nil {
+		return Hold{}, fmt.Errorf("failed to place hold, got error : %w", withKind(ErrValidation, err))
+	}
+
+	return retryStale(func() (Hold, error) {
+		return s.placeHold(ctx, ledgerId, hrd, now)
+	})
+}
+
+// placeHold places the validated hold
+func (s *store) placeHold(ctx context.Context, ledgerId string, hrd HoldRequestDTO, now time.Time) (Hold, error) {
+	unlock := s.lockLedgers(ledgerId)
+	defer unlock()
+
+	ledger, balance, err := s.getLedgerWithBalance(ledgerId)
+	if err != nil {
+		return Hold{}, fmt.Errorf("failed to place hold, got error : %w", err)
+	}
+
+	if ledger.Status == LedgerClosed {
+		return Hold{}, withKind(ErrLedgerClosed, fmt.Errorf("failed to place hold on closed ledger: %s", ledgerId))
+	}
+
+	if err := validateAmount(ledger, hrd.Currency, hrd.Amount); err != nil {
+		return Hold{}, fmt.Errorf("failed to place hold, got error : %w", err)
+	}
+
+	held, err := ledger.held.CheckedAdd(hrd.Amount)
+	if err != nil {
+		return Hold{}, withKind(ErrValidation, fmt.Errorf("failed to place hold, got error : %w", err))
+	}
+	available, err := balance.CheckedSub(held)
+	if err != nil {
+		return Hold{}, withKind(ErrValidation, fmt.Errorf("failed to place hold, got error : %w", err))
+	}
+
+	if err := checkPolicy(ledger, available); err != nil {
+		return Hold{}, fmt.Errorf("failed to place hold, got error : %w", err)
+	}
+
+	hold := Hold{
+		ID:          s.uuid.Generate(),
+		LedgerID:    ledgerId,
+		Description: hrd.Description,
+		Currency:    hrd.Currency,
+		Amount:      hrd.Amount,
+		Status:      HoldActive,
+		CreatedAt:   now.UTC().UnixMilli(),
+		ExpiresAt:   hrd.ExpiresAt,
+	}
+	if err := s.commit(change{Kind: changeHoldPlaced, Hold: &hold}); err != nil {
+		return Hold{}, fmt.Errorf("failed to place hold, got error : %w", err)
+	}
+
+	loggerFrom(ctx).Info("placed hold", zap.String("ledgerId", ledgerId), zap.String("holdId", hold.ID), zap.Stringer("amount", hold.Amount))
+	return hold, nil
+}
+
+// GetHold returns the hold
+func (s *store) GetHold(ctx context.Context, holdId string) (Hold, error) {
+	hold, unlock, err := s.lockHold(holdId)
+	if err != nil {
+		return Hold{}, fmt.Errorf("failed to get hold, got error : %w", err)
+	}
+	defer unlock()
+
+	return *hold, nil
+}
+
+// CaptureHold posts a debit of the captured amount, at most the amount of the hold, against the
+// counter account and releases the rest of the hold
+func (s *store) CaptureHold(ctx context.Context, holdId string, crd CaptureRequestDTO) (HoldCapture, error) {
+	return retryStale(func() (HoldCapture, error) {
+		return s.captureHold(ctx, holdId, crd)
+	})
+}
+
+// captureHold captures the hold
+func (s *store) captureHold(ctx context.Context, holdId string, crd CaptureRequestDTO) (HoldCapture, error) {
+	hold, unlock, err := s.lockHold(holdId)
+	if err != nil {
+		return HoldCapture{}, fmt.Errorf("failed to capture hold, got error : %w", err)
+	}
+	defer unlock()
+
+	now := time.Now()
+	if err := hold.checkActive(now); err != nil {
+		return HoldCapture{}, fmt.Errorf("failed to capture hold, got error : %w", err)
+	}
+
+	amount := crd.Amount
+	if amount.IsZero() {
+		amount = hold.Amount
+	}
+	if !amount.IsPositive() || amount.Cmp(hold.Amount) > 0 {
+		return HoldCapture{}, withKind(ErrValidation, fmt.Errorf("failed to capture hold, got amount %s outside of 0 and %s", amount, hold.Amount))
+	}
+
+	description := crd.Description
+	if description == "" {
+		description = hold.Description
+	}
+
+	counterAccountId, err := s.counterAccount(hold.Currency)
+	if err != nil {
+		return HoldCapture{}, fmt.Errorf("failed to capture hold, got error : %w", err)
+	}
+
+	entry, err := s.prepareEntry(JournalEntryRequestDTO{
+		Description: description,
+		Currency:    hold.Currency,
+		Legs: []LegDTO{
+			{LedgerID: hold.LedgerID, Type: Debit, Amount: amount},
+			{LedgerID: counterAccountId, Type: Credit, Amount: amount},
+		},
+		capturing: hold,
+	}, nil)
+	if err != nil {
+		return HoldCapture{}, fmt.Errorf("failed to capture hold, got error : %w", err)
+	}
+
+	captured := *hold
+	captured.Status = HoldCaptured
+	captured.ClosedAt = entry.Date
+	captured.CapturedAmount = amount
+	captured.TransactionID = entry.Transactions[0].ID
+	if err := s.commit(change{Kind: changeHoldCaptured, Hold: &captured, Entry: &entry}); err != nil {
+		return HoldCapture{}, fmt.Errorf("failed to capture hold, got error : %w", err)
+	}
+
+	loggerFrom(ctx).Info("captured hold", zap.String("holdId", holdId), zap.Stringer("amount", amount))
+	return HoldCapture{Hold: captured, Transaction: entry.Transactions[0]}, nil
+}
+
+// ReleaseHold releases the active hold without posting anything
+func (s *store) ReleaseHold(ctx context.Context, holdId string) (Hold, error) {
+	return retryStale(func() (Hold, error) {
+		return s.releaseHold(ctx, holdId)
+	})
+}
+
+// releaseHold releases the hold
+func (s *store) releaseHold(ctx context.Context, holdId string) (Hold, error) {
+	hold, unlock, err := s.lockHold(holdId)
+	if err != nil {
+		return Hold{}, fmt.Errorf("failed to release hold, got error : %w", err)
+	}
+	defer unlock()
+
+	now := time.Now()
+	if err := hold.checkActive(now); err != nil {
+		return Hold{}, fmt.Errorf("failed to release hold, got error : %w", err)
+	}
+
+	released := *hold
+	released.Status = HoldReleased
+	released.ClosedAt = now.UTC().UnixMilli()
+	if err := s.commit(change{Kind: changeHoldReleased, Hold: &released}); err != nil {
+		return Hold{}, fmt.Errorf("failed to release hold, got error : %w", err)
+	}
+
+	loggerFrom(ctx).Info("released hold", zap.String("holdId", holdId))
+	return released, nil
+}
+
+// ExpireHolds releases every active hold whose expiry has passed and returns how many it released
+func (s *store) ExpireHolds(ctx context.Context) (int, error) {
+	now := time.Now()
+	nowMillis := now.UTC().UnixMilli()
+
+	s.mu.RLock()
+	ids := make([]string, 0)
+	for id, expiresAt := range s.activeHolds {
+		if expiresAt <= nowMillis {
+			ids = append(ids, id)
+		}
+	}
+	s.mu.RUnlock()
+
+	expired := 0
+	for _, id := range ids {
+		ok, err := retryStale(func() (bool, error) {
+			return s.expireHold(ctx, id, nowMillis)
+		})
+		if err != nil {
+			return expired, fmt.Errorf("failed to expire holds, got error : %w", err)
+		}
+		if ok {
+			expired++
+		}
+	}
+
+	return expired, nil
+}
+
+// expireHold expires the hold if it is still active and reports whether it did
+func (s *store) expireHold(ctx context.Context, holdId string, now int64) (bool, error) {
+	hold, unlock, err := s.lockHold(holdId)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	if hold.Status != HoldActive || hold.ExpiresAt > now {
+		return false, nil
+	}
+
+	expired := *hold
+	expired.Status = HoldExpired
+	expired.ClosedAt = now
+	if err := s.commit(change{Kind: changeHoldExpired, Hold: &expired}); err != nil {
+		return false, err
+	}
+
+	loggerFrom(ctx).Info("expired hold", zap.String("holdId", holdId), zap.String("ledgerId", hold.LedgerID))
+	return true, nil
+}
+
+// RunHoldSweeper expires the holds of the store every interval until the context is done
+func RunHoldSweeper(ctx context.Context, store Store, interval time.Duration) {
+	NewHoldSweeper(store, interval).Run(ctx)
+}
+
+// HoldSweeper expires the holds of a store in the background. As a HealthChecker it reports
+// whether it is running and sweeping on time.
+type HoldSweeper struct {
+	store    Store
+	interval time.Duration
+	running  atomic.Bool
+	// lastSweep is when the sweeper last completed a sweep or started, in unix milliseconds
+	lastSweep atomic.Int64
+}
+
+// NewHoldSweeper creates a sweeper expiring the holds of the store every interval
+func NewHoldSweeper(store Store, interval time.Duration) *HoldSweeper {
+	if interval <= 0 {
+		interval = DefaultHoldSweepInterval
+	}
+
+	return &HoldSweeper{store: store, interval: interval}
+}
+
+// Run expires the holds of the store every interval until the context is done
+func (h *HoldSweeper) Run(ctx context.Context) {
+	h.lastSweep.Store(time.Now().UnixMilli())
+	h.running.Store(true)
+	defer h.running.Store(false)
+
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := h.store.ExpireHolds(ctx)
+			h.lastSweep.Store(time.Now().UnixMilli())
+			if err != nil {
+				loggerFrom(ctx).Error("failed to sweep expired holds", zap.Error(err), zap.Int("expired", expired))
+				continue
+			}
+			if expired > 0 {
+				loggerFrom(ctx).Info("swept expired holds", zap.Int("expired", expired))
+			}
+		}
+	}
+}
+
+// Check checks that the sweeper is running and has completed a sweep within the last two
+// intervals, so that a sweep stuck on the store is reported
+func (h *HoldSweeper) Check(context.Context) error {
+	if !h.running.Load() {
+		return errors.New("failed get running hold sweeper, got stopped sweeper")
+	}
+
+	since := time.Since(time.UnixMilli(h.lastSweep.Load()))
+	if since > 2*h.interval {
+		return fmt.Errorf("failed get hold sweep within %s, got last sweep %s ago", 2*h.interval, since.Round(time.Second))
+	}
+
+	return nil
+}
+
+// checkActive checks the hold can still be captured or released
+func (h *Hold) checkActive(now time.Time) error {
+	if h.Status != HoldActive {
+		return withKind(ErrConflict, fmt.Errorf("failed get active hold, got %s hold: %s", h.Status, h.ID))
+	}
+
+	if h.ExpiresAt <= now.UTC().UnixMilli() {
+		return withKind(ErrConflict, fmt.Errorf("failed get active hold, got expired hold: %s", h.ID))
+	}
+
+	return nil
+}
+
+// lockHold locks the ledger and, when it has one, the counter account of the hold and returns the
+// hold with a function releasing the locks
+func (s *store) lockHold(holdId string) (*Hold, func(), error) {
+	s.mu.RLock()
+	hold, ok := s.holds[holdId]
+	s.mu.RUnlock()
+	if !ok {
+		// a hold another writer placed is loaded along with its ledger
+		ledgerId, err := s.changes.holdLedger(context.Background(), holdId)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to find hold %s, got error : %w", holdId, err)
+		}
+		if ledgerId != "" {
+			s.lockLedgers(ledgerId)()
+			s.mu.RLock()
+			hold, ok = s.holds[holdId]
+			s.mu.RUnlock()
+		}
+	}
+	if !ok {
+		return nil, nil, withKind(ErrNotFound, fmt.Errorf("failed get hold: %s", holdId))
+	}
+
+	// the ledger and currency of a hold never change, so they can be read before locking
+	ids := []string{hold.LedgerID}
+	if counterAccountId, ok := s.counterAccounts[hold.Currency]; ok {
+		ids = append(ids, counterAccountId)
+	}
+
+	return hold, s.lockLedgers(ids...), nil
+}
@@ -0,0 +1,261 @@
+package ledger_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFundedStore returns a store whose EUR "wallet" ledger holds 100
+func newFundedStore(t *testing.T) ledger.Store {
+	storeInstance := newConcurrentStore("wallet")
+	_, err := storeInstance.Credit(context.Background(), "wallet", ledger.TransactionRequestDTO{
+		Type: ledger.Credit, Description: "opening", Currency: "EUR", Amount: ledger.MustParseMoney("100"),
+	})
+	require.NoError(t, err)
+	return storeInstance
+}
+
+// holdFor returns a request holding the amount in EUR for an hour
+func holdFor(amount string) ledger.HoldRequestDTO {
+	return ledger.HoldRequestDTO{
+		Description: "card authorisation",
+		Currency:    "EUR",
+		Amount:      ledger.MustParseMoney(amount),
+		ExpiresAt:   time.Now().Add(time.Hour).UnixMilli(),
+	}
+}
+
+// assertBalances checks the posted and available balance of the wallet
+func assertBalances(t *testing.T, storeInstance ledger.Store, posted, available string) {
+	balance, err := storeInstance.GetLastBalance(context.Background(), "wallet")
+	require.NoError(t, err)
+	assert.Equal(t, posted, balance.Balance.String(), "posted balance")
+	require.NotNil(t, balance.Available)
+	assert.Equal(t, available, balance.Available.String(), "available balance")
+}
+
+func TestStorePlaceHold(t *testing.T) {
+	tests := []struct {
+		name              string
+		ledgerId          string
+		request           ledger.HoldRequestDTO
+		expectedAvailable string
+		expectError       bool
+	}{
+		{
+			name:              "Hold within available balance",
+			ledgerId:          "wallet",
+			request:           holdFor("30"),
+			expectedAvailable: "70",
+		},
+		{
+			name:              "Hold exceeding available balance returns error",
+			ledgerId:          "wallet",
+			request:           holdFor("150"),
+			expectedAvailable: "100",
+			expectError:       true,
+		},
+		{
+			name:     "Hold in other currency returns error",
+			ledgerId: "wallet",
+			request: ledger.HoldRequestDTO{
+				Currency: "GBP", Amount: ledger.MustParseMoney("10"), ExpiresAt: time.Now().Add(time.Hour).UnixMilli(),
+			},
+			expectedAvailable: "100",
+			expectError:       true,
+		},
+		{
+			name:     "Hold expiring in the past returns error",
+			ledgerId: "wallet",
+			request: ledger.HoldRequestDTO{
+				Currency: "EUR", Amount: ledger.MustParseMoney("10"), ExpiresAt: time.Now().Add(-time.Hour).UnixMilli(),
+			},
+			expectedAvailable: "100",
+			expectError:       true,
+		},
+		{
+			name:              "Hold on unknown ledger returns error",
+			ledgerId:          "unknown",
+			request:           holdFor("10"),
+			expectedAvailable: "100",
+			expectError:       true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			storeInstance := newFundedStore(t)
+
+			hold, err := storeInstance.PlaceHold(context.Background(), tc.ledgerId, tc.request)
+
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, ledger.HoldActive, hold.Status)
+				fetched, err := storeInstance.GetHold(context.Background(), hold.ID)
+				assert.NoError(t, err)
+				assert.Equal(t, hold, fetched)
+			}
+			assertBalances(t, storeInstance, "100", tc.expectedAvailable)
+		})
+	}
+}
+
+func TestStoreDebitChecksAvailableBalance(t *testing.T) {
+	storeInstance := newFundedStore(t)
+	_, err := storeInstance.PlaceHold(context.Background(), "wallet", holdFor("60"))
+	require.NoError(t, err)
+
+	withdrawal := ledger.TransactionRequestDTO{
+		Type: ledger.Debit, Description: "withdrawal", Currency: "EUR", Amount: ledger.MustParseMoney("50"),
+	}
+	_, err = storeInstance.Debit(context.Background(), "wallet", withdrawal)
+	assert.ErrorContains(t, err, "available balance")
+
+	withdrawal.Amount = ledger.MustParseMoney("30")
+	_, err = storeInstance.Debit(context.Background(), "wallet", withdrawal)
+	assert.NoError(t, err)
+	assertBalances(t, storeInstance, "70", "10")
+}
+
+func TestStoreCaptureHold(t *testing.T) {
+	tests := []struct {
+		name              string
+		request           ledger.CaptureRequestDTO
+		expectedCaptured  string
+		expectedPosted    string
+		expectedAvailable string
+		expectError       bool
+	}{
+		{
+			name:              "Capture whole hold",
+			request:           ledger.CaptureRequestDTO{},
+			expectedCaptured:  "99",
+			expectedPosted:    "1",
+			expectedAvailable: "1",
+		},
+		{
+			name:              "Capture part of hold releases the rest",
+			request:           ledger.CaptureRequestDTO{Amount: ledger.MustParseMoney("40"), Description: "final amount"},
+			expectedCaptured:  "40",
+			expectedPosted:    "60",
+			expectedAvailable: "60",
+		},
+		{
+			name:              "Capture more than hold returns error",
+			request:           ledger.CaptureRequestDTO{Amount: ledger.MustParseMoney("100")},
+			expectedPosted:    "100",
+			expectedAvailable: "1",
+			expectError:       true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			storeInstance := newFundedStore(t)
+			hold, err := storeInstance.PlaceHold(context.Background(), "wallet", holdFor("99"))
+			require.NoError(t, err)
+
+			capture, err := storeInstance.CaptureHold(context.Background(), hold.ID, tc.request)
+
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, ledger.HoldCaptured, capture.Hold.Status)
+				assert.Equal(t, tc.expectedCaptured, capture.Hold.CapturedAmount.String())
+				assert.Equal(t, capture.Transaction.ID, capture.Hold.TransactionID)
+				assert.Equal(t, ledger.Debit, capture.Transaction.Type)
+				assert.Equal(t, tc.expectedCaptured, capture.Transaction.Amount.String())
+
+				_, err = storeInstance.CaptureHold(context.Background(), hold.ID, tc.request)
+				assert.ErrorContains(t, err, "captured hold")
+			}
+			assertBalances(t, storeInstance, tc.expectedPosted, tc.expectedAvailable)
+			assertConsistentHistory(t, storeInstance, "wallet")
+		})
+	}
+}
+
+func TestStoreReleaseHold(t *testing.T) {
+	storeInstance := newFundedStore(t)
+	hold, err := storeInstance.PlaceHold(context.Background(), "wallet", holdFor("30"))
+	require.NoError(t, err)
+
+	released, err := storeInstance.ReleaseHold(context.Background(), hold.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, ledger.HoldReleased, released.Status)
+	assert.NotZero(t, released.ClosedAt)
+	assertBalances(t, storeInstance, "100", "100")
+
+	_, err = storeInstance.ReleaseHold(context.Background(), hold.ID)
+	assert.ErrorContains(t, err, "released hold")
+
+	_, err = storeInstance.CaptureHold(context.Background(), hold.ID, ledger.CaptureRequestDTO{})
+	assert.ErrorContains(t, err, "released hold")
+
+	_, err = storeInstance.ReleaseHold(context.Background(), "unknown")
+	assert.Error(t, err)
+}
+
+func TestStoreExpireHolds(t *testing.T) {
+	storeInstance := newFundedStore(t)
+	expiring := holdFor("30")
+	expiring.ExpiresAt = time.Now().Add(20 * time.Millisecond).UnixMilli()
+	short, err := storeInstance.PlaceHold(context.Background(), "wallet", expiring)
+	require.NoError(t, err)
+	long, err := storeInstance.PlaceHold(context.Background(), "wallet", holdFor("10"))
+	require.NoError(t, err)
+	assertBalances(t, storeInstance, "100", "60")
+
+	time.Sleep(30 * time.Millisecond)
+
+	_, err = storeInstance.CaptureHold(context.Background(), short.ID, ledger.CaptureRequestDTO{})
+	assert.ErrorContains(t, err, "expired hold")
+
+	expired, err := storeInstance.ExpireHolds(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	assertBalances(t, storeInstance, "100", "90")
+
+	fetched, err := storeInstance.GetHold(context.Background(), short.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, ledger.HoldExpired, fetched.Status)
+	fetched, err = storeInstance.GetHold(context.Background(), long.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, ledger.HoldActive, fetched.Status)
+
+	expired, err = storeInstance.ExpireHolds(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, expired)
+}
+
+func TestRunHoldSweeper(t *testing.T) {
+	storeInstance := newFundedStore(t)
+	expiring := holdFor("30")
+	expiring.ExpiresAt = time.Now().Add(10 * time.Millisecond).UnixMilli()
+	hold, err := storeInstance.PlaceHold(context.Background(), "wallet", expiring)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		ledger.RunHoldSweeper(ctx, storeInstance, 5*time.Millisecond)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		fetched, err := storeInstance.GetHold(context.Background(), hold.ID)
+		return err == nil && fetched.Status == ledger.HoldExpired
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	<-done
+	assertBalances(t, storeInstance, "100", "100")
+}
@@ -0,0 +1,98 @@
+package ledger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader is the request header carrying the client supplied idempotency key
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// MaxIdempotencyKeyLength is the longest idempotency key accepted
+const MaxIdempotencyKeyLength = 255
+
+// DefaultIdempotencyRetention is how long idempotency keys are remembered when no retention is configured
+const DefaultIdempotencyRetention = 24 * time.Hour
+
+// ErrIdempotencyConflict is returned when an idempotency key is reused with a different request
+var ErrIdempotencyConflict = errors.New("failed to reuse idempotency key with a different request")
+
+// idempotencyRecord holds the result of a request made with an idempotency key
+type idempotencyRecord struct {
+	fingerprint string
+	transaction Transaction
+	expiresAt   time.Time
+}
+
+// idempotencyKeys remembers the results of requests per ledger and idempotency key until they expire
+type idempotencyKeys struct {
+	mu        sync.Mutex
+	retention time.Duration
+	records   map[string]idempotencyRecord
+	// order holds the record keys in insertion order; with a fixed retention this is also expiry order
+	order []string
+}
+
+// newIdempotencyKeys creates an empty set of idempotency keys with the given retention
+func newIdempotencyKeys(retention time.Duration) *idempotencyKeys {
+	if retention <= 0 {
+		retention = DefaultIdempotencyRetention
+	}
+
+	return &idempotencyKeys{
+		retention: retention,
+		records:   make(map[string]idempotencyRecord),
+	}
+}
+
+// lookup returns the unexpired record for the idempotency key on the ledger
+func (k *idempotencyKeys) lookup(ledgerId, key string, now time.Time) (idempotencyRecord, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.expire(now)
+	record, ok := k.records[idempotencyRecordKey(ledgerId, key)]
+	return record, ok
+}
+
+// remember stores the transaction produced by the request with the idempotency key on the ledger
+func (k *idempotencyKeys) remember(ledgerId, key, fingerprint string, tx Transaction, now time.Time) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.expire(now)
+	recordKey := idempotencyRecordKey(ledgerId, key)
+	k.records[recordKey] = idempotencyRecord{
+		fingerprint: fingerprint,
+		transaction: tx,
+		expiresAt:   now.Add(k.retention),
+	}
+	k.order = append(k.order, recordKey)
+}
+
+// expire drops the records whose retention has elapsed; the caller must hold mu
+func (k *idempotencyKeys) expire(now time.Time) {
+	for len(k.order) > 0 {
+		record, ok := k.records[k.order[0]]
+		if ok && record.expiresAt.After(now) {
+			return
+		}
+		delete(k.records, k.order[0])
+		k.order = k.order[1:]
+	}
+}
+
+// idempotencyRecordKey scopes the idempotency key to the ledger
+func idempotencyRecordKey(ledgerId, key string) string {
+	return ledgerId + "\x00" + key
+}
+
+// fingerprint returns a digest of the request used to detect an idempotency key reused for a different request
+func (trd TransactionRequestDTO) fingerprint(txType TransactionType) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%s\x00%s", txType, trd.Currency, trd.Amount, trd.Description)))
+	return hex.EncodeToString(sum[:])
+}
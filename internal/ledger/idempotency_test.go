@@ -0,0 +1,163 @@
+package ledger_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreCreditWithIdempotencyKey(t *testing.T) {
+	deposit := ledger.TransactionRequestDTO{
+		Type:           ledger.Credit,
+		Description:    "deposit",
+		Currency:       "EUR",
+		Amount:         ledger.MustParseMoney("25"),
+		IdempotencyKey: "payment-1",
+	}
+
+	tests := []struct {
+		name            string
+		retry           ledger.TransactionRequestDTO
+		retryLedgerId   string
+		expectReplay    bool
+		expectConflict  bool
+		expectedBalance string
+	}{
+		{
+			name:            "Retry with same key and payload replays the original transaction",
+			retry:           deposit,
+			retryLedgerId:   "wallet",
+			expectReplay:    true,
+			expectedBalance: "25",
+		},
+		{
+			name: "Retry with same key and different payload is rejected",
+			retry: ledger.TransactionRequestDTO{
+				Type:           ledger.Credit,
+				Description:    "deposit",
+				Currency:       "EUR",
+				Amount:         ledger.MustParseMoney("30"),
+				IdempotencyKey: "payment-1",
+			},
+			retryLedgerId:   "wallet",
+			expectConflict:  true,
+			expectedBalance: "25",
+		},
+		{
+			name: "Retry with different key records a new transaction",
+			retry: ledger.TransactionRequestDTO{
+				Type:           ledger.Credit,
+				Description:    "deposit",
+				Currency:       "EUR",
+				Amount:         ledger.MustParseMoney("25"),
+				IdempotencyKey: "payment-2",
+			},
+			retryLedgerId:   "wallet",
+			expectedBalance: "50",
+		},
+		{
+			name: "Retry without key records a new transaction",
+			retry: ledger.TransactionRequestDTO{
+				Type:        ledger.Credit,
+				Description: "deposit",
+				Currency:    "EUR",
+				Amount:      ledger.MustParseMoney("25"),
+			},
+			retryLedgerId:   "wallet",
+			expectedBalance: "50",
+		},
+		{
+			name:            "Same key on another ledger records a new transaction",
+			retry:           deposit,
+			retryLedgerId:   "savings",
+			expectedBalance: "25",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			storeInstance := newConcurrentStore("wallet", "savings")
+
+			original, err := storeInstance.Credit(context.Background(), "wallet", deposit)
+			require.NoError(t, err)
+			assert.Equal(t, "payment-1", original.IdempotencyKey)
+
+			retried, err := storeInstance.Credit(context.Background(), tc.retryLedgerId, tc.retry)
+
+			if tc.expectConflict {
+				assert.ErrorIs(t, err, ledger.ErrIdempotencyConflict)
+			} else {
+				assert.NoError(t, err)
+			}
+			if tc.expectReplay {
+				assert.Equal(t, original, retried)
+			} else if !tc.expectConflict {
+				assert.NotEqual(t, original.ID, retried.ID)
+			}
+
+			balance, err := storeInstance.GetLastBalance(context.Background(), tc.retryLedgerId)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedBalance, balance.Balance.String())
+		})
+	}
+}
+
+func TestStoreIdempotencyKeyExpires(t *testing.T) {
+	ledgers := newLedgersWithCounterAccount()
+	ledgers["wallet"] = &ledger.Ledger{ID: "wallet", Type: "cash", Currency: "EUR"}
+	storeInstance := ledger.NewStore(ledger.NewUUIDGenerator(), ledgers, ledger.StoreOptions{
+		CounterAccounts:      counterAccountOptions.CounterAccounts,
+		IdempotencyRetention: 10 * time.Millisecond,
+	})
+	deposit := ledger.TransactionRequestDTO{
+		Type:           ledger.Credit,
+		Description:    "deposit",
+		Currency:       "EUR",
+		Amount:         ledger.MustParseMoney("25"),
+		IdempotencyKey: "payment-1",
+	}
+
+	first, err := storeInstance.Credit(context.Background(), "wallet", deposit)
+	require.NoError(t, err)
+
+	time.Sleep(20 * time.Millisecond)
+
+	second, err := storeInstance.Credit(context.Background(), "wallet", deposit)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, second.ID)
+	assert.Equal(t, "50", second.RunningBalance.String())
+}
+
+func TestStoreConcurrentRetriesWithSameIdempotencyKey(t *testing.T) {
+	storeInstance := newConcurrentStore("wallet")
+	deposit := ledger.TransactionRequestDTO{
+		Type:           ledger.Credit,
+		Description:    "deposit",
+		Currency:       "EUR",
+		Amount:         ledger.MustParseMoney("10"),
+		IdempotencyKey: "payment-1",
+	}
+
+	results := make([]ledger.Transaction, 50)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tx, err := storeInstance.Credit(context.Background(), "wallet", deposit)
+			assert.NoError(t, err)
+			results[i] = tx
+		}(i)
+	}
+	wg.Wait()
+
+	for _, tx := range results {
+		assert.Equal(t, results[0], tx)
+	}
+	assert.Equal(t, ledger.MustParseMoney("10"), assertConsistentHistory(t, storeInstance, "wallet"))
+}
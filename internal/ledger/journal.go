@@ -0,0 +1,267 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// LegDTO represents one debit or credit leg of a journal entry
+type LegDTO struct {
+	LedgerID string          `json:"ledgerId"`
+	Type     TransactionType `json:"type"`
+	Amount   Money           `json:"amount"`
+}
+
+// JournalEntryRequestDTO represents the request payload for a double-entry journal posting
+type JournalEntryRequestDTO struct {
+	Description string   `json:"description"`
+	Currency    Currency `json:"currency"`
+	Legs        []LegDTO `json:"legs"`
+	// capturing is the hold the entry captures, whose funds are available to its debit
+	capturing *Hold
+}
+
+// JournalEntry represents a balanced set of transactions recorded across ledgers
+type JournalEntry struct {
+	ID           string        `json:"id"`
+	Date         int64         `json:"date"`
+	Description  string        `json:"description"`
+	Currency     Currency      `json:"currency"`
+	Transactions []Transaction `json:"transactions"`
+}
+
+// Validate checks the journal entry has at least two well-formed legs whose credits equal its debits
+func (jrd JournalEntryRequestDTO) Validate() error {
+	if len(jrd.Legs) < 2 {
+		return errors.New("failed get at least two legs")
+	}
+
+	if err := jrd.Currency.Validate(); err != nil {
+		return err
+	}
+
+	var credits, debits Money
+	for _, leg := range jrd.Legs {
+		if leg.LedgerID == "" {
+			return errors.New("failed get valid ledgerId for every leg")
+		}
+
+		if !leg.Amount.IsPositive() {
+			return errors.New("failed get amount greater than zero for every leg")
+		}
+
+		if !jrd.Currency.Allows(leg.Amount) {
+			return fmt.Errorf("failed get amount with at most %d decimal places for %s", jrd.Currency.MinorUnits(), jrd.Currency)
+		}
+
+		var err error
+		switch leg.Type {
+		case Credit:
+			credits, err = credits.CheckedAdd(leg.Amount)
+		case Debit:
+			debits, err = debits.CheckedAdd(leg.Amount)
+		default:
+			return errors.New("failed get transaction type either credit or debit for every leg")
+		}
+		if err != nil {
+			return fmt.Errorf("failed get legs summing within range, got error : %w", err)
+		}
+	}
+
+	if credits.Cmp(debits) != 0 {
+		return fmt.Errorf("failed get balanced legs, got credits %s and debits %s", credits, debits)
+	}
+
+	return nil
+}
+
+// ledgerIds returns the id of the ledger of every leg
+func (jrd JournalEntryRequestDTO) ledgerIds() []string {
+	ids := make([]string, 0, len(jrd.Legs))
+	for _, leg := range jrd.Legs {
+		ids = append(ids, leg.LedgerID)
+	}
+	return ids
+}
+
+// Post records a journal entry across ledgers; either every leg is recorded or none is
+func (s *store) Post(ctx context.Context, jrd JournalEntryRequestDTO) (JournalEntry, error) {
+	entry, err := s.post(jrd, nil)
+	if err != nil {
+		return JournalEntry{}, fmt.Errorf("failed to post journal entry, got error : %w", err)
+	}
+
+	loggerFrom(ctx).Info("posted journal entry", zap.String("journalEntryId", entry.ID), zap.Int("legs", len(entry.Transactions)))
+	return entry, nil
+}
+
+// GetJournalEntry returns the journal entry of the transaction of the ledger with every leg
+func (s *store) GetJournalEntry(ctx context.Context, ledgerId string, transactionId string) (JournalEntry, error) {
+	entryId, err := s.journalEntryOf(ledgerId, transactionId)
+	if err != nil {
+		return JournalEntry{}, fmt.Errorf("failed to get journal entry, got error : %w", err)
+	}
+
+	// the ledgers of a journal entry never change, so they can be read before locking
+	s.mu.RLock()
+	ledgerIds := s.entries[entryId]
+	s.mu.RUnlock()
+
+	unlock := s.lockLedgers(ledgerIds...)
+	defer unlock()
+
+	transactions, err := s.entryTransactions(entryId, ledgerIds)
+	if err != nil {
+		return JournalEntry{}, fmt.Errorf("failed to get journal entry, got error : %w", err)
+	}
+	ledger, err := s.getLedger(ledgerId)
+	if err != nil {
+		return JournalEntry{}, fmt.Errorf("failed to get journal entry, got error : %w", err)
+	}
+
+	// every leg carries the date and description of its entry
+	return JournalEntry{
+		ID:           entryId,
+		Date:         transactions[0].Date,
+		Description:  transactions[0].Description,
+		Currency:     ledger.Currency,
+		Transactions: transactions,
+	}, nil
+}
+
+// post locks every ledger the journal entry touches and records it with postLocked, again when
+// another writer changed one of the ledgers meanwhile
+func (s *store) post(jrd JournalEntryRequestDTO, annotate func(*Transaction)) (JournalEntry, error) {
+	return retryStale(func() (JournalEntry, error) {
+		unlock := s.lockLedgers(jrd.ledgerIds()...)
+		defer unlock()
+
+		return s.postLocked(jrd, annotate)
+	})
+}
+
+// postLocked builds the journal entry with prepareEntry and commits it.
+// The caller must hold the locks of the ledgers it touches.
+func (s *store) postLocked(jrd JournalEntryRequestDTO, annotate func(*Transaction)) (JournalEntry, error) {
+	entry, err := s.prepareEntry(jrd, annotate)
+	if err != nil {
+		return JournalEntry{}, err
+	}
+
+	if err := s.commit(change{Kind: changeJournalEntry, Entry: &entry}); err != nil {
+		return JournalEntry{}, err
+	}
+
+	return entry, nil
+}
+
+// prepareEntry validates the journal entry against every ledger it touches and builds its
+// transactions without recording them, so a rejected leg leaves all ledgers unchanged. annotate,
+// when set, is applied to each transaction before it is chained to its ledger. The caller must hold the locks of those ledgers.
+func (s *store) prepareEntry(jrd JournalEntryRequestDTO, annotate func(*Transaction)) (JournalEntry, error) {
+	if err := jrd.Validate(); err != nil {
+		return JournalEntry{}, withKind(ErrValidation, err)
+	}
+
+	// the entry is dated no earlier than the last transaction of any ledger it touches, so dates
+	// never decrease along a ledger's history even if the clock steps back
+	date := time.Now().UTC().UnixMilli()
+	ledgers := make(map[string]*Ledger, len(jrd.Legs))
+	balances := make(map[string]Money, len(jrd.Legs))
+	held := make(map[string]Money, len(jrd.Legs))
+	heads := make(map[string]string, len(jrd.Legs))
+	for _, leg := range jrd.Legs {
+		if _, seen := balances[leg.LedgerID]; seen {
+			continue
+		}
+
+		ledger, lastBalance, err := s.getLedgerWithBalance(leg.LedgerID)
+		if err != nil {
+			return JournalEntry{}, err
+		}
+
+		if ledger.Status == LedgerClosed {
+			err := withKind(ErrLedgerClosed, fmt.Errorf("failed to post to closed ledger: %s", ledger.ID))
+			if debits(jrd.Legs, ledger.ID) {
+				s.metrics.recordRejectedDebit(err)
+			}
+			return JournalEntry{}, err
+		}
+
+		if err := validateAmount(ledger, jrd.Currency, leg.Amount); err != nil {
+			return JournalEntry{}, err
+		}
+
+		ledgers[leg.LedgerID] = ledger
+		balances[leg.LedgerID] = lastBalance
+		held[leg.LedgerID] = ledger.held
+		heads[leg.LedgerID] = ledger.head()
+		if jrd.capturing != nil && jrd.capturing.LedgerID == leg.LedgerID {
+			held[leg.LedgerID] = ledger.held.Sub(jrd.capturing.Amount)
+		}
+		if n := len(ledger.Transactions); n > 0 {
+			date = max(date, ledger.Transactions[n-1].Date)
+		}
+	}
+
+	entry := JournalEntry{
+		ID:           s.uuid.Generate(),
+		Date:         date,
+		Description:  jrd.Description,
+		Currency:     jrd.Currency,
+		Transactions: make([]Transaction, 0, len(jrd.Legs)),
+	}
+	for _, leg := range jrd.Legs {
+		newBalance, err := balances[leg.LedgerID].CheckedAdd(leg.Amount)
+		if leg.Type == Debit {
+			newBalance, err = balances[leg.LedgerID].CheckedSub(leg.Amount)
+		}
+		if err != nil {
+			return JournalEntry{}, withKind(ErrValidation, fmt.Errorf("failed get new balance of ledger %s within range, got error : %w", leg.LedgerID, err))
+		}
+		if leg.Type == Debit {
+			available, err := newBalance.CheckedSub(held[leg.LedgerID])
+			if err != nil {
+				return JournalEntry{}, withKind(ErrValidation, fmt.Errorf("failed get new available balance of ledger %s within range, got error : %w", leg.LedgerID, err))
+			}
+			if err := checkPolicy(ledgers[leg.LedgerID], available); err != nil {
+				s.metrics.recordRejectedDebit(err)
+				return JournalEntry{}, err
+			}
+		}
+		balances[leg.LedgerID] = newBalance
+
+		tx := Transaction{
+			ID:             s.uuid.Generate(),
+			LedgerID:       leg.LedgerID,
+			Date:           entry.Date,
+			Type:           leg.Type,
+			Description:    jrd.Description,
+			Amount:         leg.Amount,
+			RunningBalance: newBalance,
+			JournalEntryID: entry.ID,
+		}
+		if annotate != nil {
+			annotate(&tx)
+		}
+		tx.Hash = chainHash(heads[leg.LedgerID], tx)
+		heads[leg.LedgerID] = tx.Hash
+		entry.Transactions = append(entry.Transactions, tx)
+	}
+
+	return entry, nil
+}
+
+// debits reports whether any of the legs debits the ledger
+func debits(legs []LegDTO, ledgerId string) bool {
+	for _, leg := range legs {
+		if leg.LedgerID == ledgerId && leg.Type == Debit {
+			return true
+		}
+	}
+	return false
+}
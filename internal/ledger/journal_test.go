@@ -0,0 +1,237 @@
+package ledger_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+	internalMock "github.com/dineshd30/ledger-service/internal/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorePost(t *testing.T) {
+	newLedgers := func() map[string]*ledger.Ledger {
+		return map[string]*ledger.Ledger{
+			"wallet": {
+				ID:       "wallet",
+				Type:     "cash",
+				Currency: "EUR",
+				Transactions: []ledger.Transaction{
+					{
+						ID:             "tx-old",
+						LedgerID:       "wallet",
+						Date:           1234567890,
+						Type:           ledger.Credit,
+						Description:    "previous deposit",
+						Amount:         ledger.MustParseMoney("100"),
+						RunningBalance: ledger.MustParseMoney("100"),
+					},
+				},
+			},
+			"fees":     {ID: "fees", Type: "income", Currency: "EUR"},
+			"tax":      {ID: "tax", Type: "liability", Currency: "EUR"},
+			"sterling": {ID: "sterling", Type: "cash", Currency: "GBP"},
+		}
+	}
+
+	tests := []struct {
+		name             string
+		request          ledger.JournalEntryRequestDTO
+		expectedBalances map[string]string
+		expectError      bool
+	}{
+		{
+			name: "Three-leg entry across ledgers",
+			request: ledger.JournalEntryRequestDTO{
+				Description: "fee with tax",
+				Currency:    "EUR",
+				Legs: []ledger.LegDTO{
+					{LedgerID: "wallet", Type: ledger.Debit, Amount: ledger.MustParseMoney("12")},
+					{LedgerID: "fees", Type: ledger.Credit, Amount: ledger.MustParseMoney("10")},
+					{LedgerID: "tax", Type: ledger.Credit, Amount: ledger.MustParseMoney("2")},
+				},
+			},
+			expectedBalances: map[string]string{"wallet": "88", "fees": "10", "tax": "2"},
+		},
+		{
+			name: "Several legs on the same ledger",
+			request: ledger.JournalEntryRequestDTO{
+				Description: "split fee",
+				Currency:    "EUR",
+				Legs: []ledger.LegDTO{
+					{LedgerID: "wallet", Type: ledger.Debit, Amount: ledger.MustParseMoney("1")},
+					{LedgerID: "wallet", Type: ledger.Debit, Amount: ledger.MustParseMoney("2")},
+					{LedgerID: "fees", Type: ledger.Credit, Amount: ledger.MustParseMoney("3")},
+				},
+			},
+			expectedBalances: map[string]string{"wallet": "97", "fees": "3"},
+		},
+		{
+			name: "Unbalanced entry is rejected",
+			request: ledger.JournalEntryRequestDTO{
+				Description: "unbalanced",
+				Currency:    "EUR",
+				Legs: []ledger.LegDTO{
+					{LedgerID: "wallet", Type: ledger.Debit, Amount: ledger.MustParseMoney("12")},
+					{LedgerID: "fees", Type: ledger.Credit, Amount: ledger.MustParseMoney("10")},
+				},
+			},
+			expectedBalances: map[string]string{"wallet": "100", "fees": "0"},
+			expectError:      true,
+		},
+		{
+			name: "Entry overdrawing one ledger is rolled back",
+			request: ledger.JournalEntryRequestDTO{
+				Description: "too much",
+				Currency:    "EUR",
+				Legs: []ledger.LegDTO{
+					{LedgerID: "fees", Type: ledger.Credit, Amount: ledger.MustParseMoney("150")},
+					{LedgerID: "wallet", Type: ledger.Debit, Amount: ledger.MustParseMoney("150")},
+				},
+			},
+			expectedBalances: map[string]string{"wallet": "100", "fees": "0"},
+			expectError:      true,
+		},
+		{
+			name: "Entry touching ledger in different currency is rolled back",
+			request: ledger.JournalEntryRequestDTO{
+				Description: "cross currency",
+				Currency:    "EUR",
+				Legs: []ledger.LegDTO{
+					{LedgerID: "wallet", Type: ledger.Debit, Amount: ledger.MustParseMoney("10")},
+					{LedgerID: "sterling", Type: ledger.Credit, Amount: ledger.MustParseMoney("10")},
+				},
+			},
+			expectedBalances: map[string]string{"wallet": "100", "sterling": "0"},
+			expectError:      true,
+		},
+		{
+			name: "Entry touching unknown ledger is rolled back",
+			request: ledger.JournalEntryRequestDTO{
+				Description: "unknown",
+				Currency:    "EUR",
+				Legs: []ledger.LegDTO{
+					{LedgerID: "wallet", Type: ledger.Debit, Amount: ledger.MustParseMoney("10")},
+					{LedgerID: "unknown", Type: ledger.Credit, Amount: ledger.MustParseMoney("10")},
+				},
+			},
+			expectedBalances: map[string]string{"wallet": "100"},
+			expectError:      true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ledgers := newLedgers()
+			uuid := internalMock.UUIDGenerator{}
+			uuid.On("Generate").Return("123")
+			storeInstance := ledger.NewStore(&uuid, ledgers, ledger.StoreOptions{})
+
+			entry, err := storeInstance.Post(context.Background(), tc.request)
+
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, entry.Transactions, len(tc.request.Legs))
+				for i, tx := range entry.Transactions {
+					assert.Equal(t, tc.request.Legs[i].LedgerID, tx.LedgerID)
+					assert.Equal(t, tc.request.Legs[i].Type, tx.Type)
+					assert.Equal(t, entry.ID, tx.JournalEntryID)
+				}
+			}
+
+			for ledgerId, expected := range tc.expectedBalances {
+				balance, err := storeInstance.GetLastBalance(context.Background(), ledgerId)
+				assert.NoError(t, err)
+				assert.Equal(t, expected, balance.Balance.String(), ledgerId)
+			}
+		})
+	}
+}
+
+func TestStoreCreditPostsAgainstCounterAccount(t *testing.T) {
+	ledgers := map[string]*ledger.Ledger{
+		"wallet":      {ID: "wallet", Type: "cash", Currency: "EUR"},
+		"counter-eur": {ID: "counter-eur", Type: "counter-account", Currency: "EUR"},
+		"yen-wallet":  {ID: "yen-wallet", Type: "cash", Currency: "JPY"},
+	}
+	uuid := internalMock.UUIDGenerator{}
+	uuid.On("Generate").Return("123")
+	storeInstance := ledger.NewStore(&uuid, ledgers, ledger.StoreOptions{
+		CounterAccounts: map[ledger.Currency]string{"EUR": "counter-eur"},
+	})
+
+	credit, err := storeInstance.Credit(context.Background(), "wallet", ledger.TransactionRequestDTO{
+		Type:        ledger.Credit,
+		Description: "deposit",
+		Currency:    "EUR",
+		Amount:      ledger.MustParseMoney("25"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "25", credit.RunningBalance.String())
+
+	debit, err := storeInstance.Debit(context.Background(), "wallet", ledger.TransactionRequestDTO{
+		Type:        ledger.Debit,
+		Description: "withdrawal",
+		Currency:    "EUR",
+		Amount:      ledger.MustParseMoney("5"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "20", debit.RunningBalance.String())
+
+	counter := ledgers["counter-eur"].Transactions
+	assert.Len(t, counter, 2)
+	assert.Equal(t, ledger.Debit, counter[0].Type)
+	assert.Equal(t, credit.JournalEntryID, counter[0].JournalEntryID)
+	assert.Equal(t, ledger.Credit, counter[1].Type)
+	assert.Equal(t, debit.JournalEntryID, counter[1].JournalEntryID)
+	assert.Equal(t, "-20", counter[1].RunningBalance.String())
+
+	_, err = storeInstance.Credit(context.Background(), "yen-wallet", ledger.TransactionRequestDTO{
+		Type:        ledger.Credit,
+		Description: "deposit without counter account",
+		Currency:    "JPY",
+		Amount:      ledger.MustParseMoney("100"),
+	})
+	assert.Error(t, err)
+	assert.Empty(t, ledgers["yen-wallet"].Transactions)
+}
+
+func TestStoreRejectsBalanceOutOfRange(t *testing.T) {
+	largest := ledger.MustParseMoney("999999999999.99")
+	nearlyFull := ledger.Money{}
+	for i := 0; i < 922; i++ {
+		nearlyFull = nearlyFull.Add(largest)
+	}
+
+	ledgers := newLedgersWithCounterAccount()
+	ledgers["wallet"] = &ledger.Ledger{
+		ID:       "wallet",
+		Type:     "cash",
+		Currency: "EUR",
+		Transactions: []ledger.Transaction{
+			{ID: "tx-old", LedgerID: "wallet", Type: ledger.Credit, Amount: largest, RunningBalance: nearlyFull},
+		},
+	}
+	storeInstance := ledger.NewStore(ledger.NewUUIDGenerator(), ledgers, counterAccountOptions)
+
+	_, err := storeInstance.Credit(context.Background(), "wallet", ledger.TransactionRequestDTO{
+		Type: ledger.Credit, Description: "deposit", Currency: "EUR", Amount: largest,
+	})
+	assert.ErrorIs(t, err, ledger.ErrValidation)
+	assert.ErrorContains(t, err, "failed get new balance of ledger wallet within range")
+
+	balance, err := storeInstance.GetLastBalance(context.Background(), "wallet")
+	assert.NoError(t, err)
+	assert.Equal(t, nearlyFull, balance.Balance)
+
+	legs := []ledger.LegDTO{{LedgerID: "wallet", Type: ledger.Debit, Amount: largest}}
+	for i := 0; i < 923; i++ {
+		legs = append(legs, ledger.LegDTO{LedgerID: "counter-eur", Type: ledger.Credit, Amount: largest})
+	}
+	jrd := ledger.JournalEntryRequestDTO{Description: "too much", Currency: "EUR", Legs: legs}
+	assert.ErrorContains(t, jrd.Validate(), "failed get legs summing within range")
+	_, err = storeInstance.Post(context.Background(), jrd)
+	assert.ErrorIs(t, err, ledger.ErrValidation)
+}
@@ -0,0 +1,275 @@
+package ledger
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// jwk represents a JSON Web Key as published in a JWKS, RFC 7517
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	// RSA keys
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC and OKP keys
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey decodes the public key of the JWK
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("failed get rsa exponent of at most 32 bits")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("failed get supported ec curve, got %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("failed get ec point on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("failed get supported okp curve, got %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("failed get ed25519 public key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("failed get supported key type, got %q", k.Kty)
+	}
+}
+
+// decodeBigInt decodes the unpadded base64url big-endian integer of a JWK
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("failed get base64url encoded integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// jwksLoadTimeout bounds each load of the JWKS
+const jwksLoadTimeout = 10 * time.Second
+
+// jwks is the set of keys verifying bearer tokens, loaded from a file or an http(s) URL. The keys
+// are reloaded once older than the refresh interval, or sooner when a token is signed with a key
+// they do not hold, so that the keys the identity provider rotates in are picked up without a
+// restart.
+type jwks struct {
+	source             string
+	client             *http.Client
+	refreshInterval    time.Duration
+	minRefreshInterval time.Duration
+
+	mu       sync.Mutex
+	keys     map[string]crypto.PublicKey
+	loadedAt time.Time
+	// attemptedAt is when the last load started, whether it succeeded or not
+	attemptedAt time.Time
+	// failures is the number of reloads failed in a row
+	failures int
+	// reloading is closed when the running reload ends, nil when none runs
+	reloading chan struct{}
+}
+
+// newJWKS loads the keys of the JWKS at source, a file path or an http(s) URL
+func newJWKS(source string, refreshInterval time.Duration, minRefreshInterval time.Duration) (*jwks, error) {
+	j := &jwks{
+		source:             source,
+		client:             &http.Client{Timeout: jwksLoadTimeout},
+		refreshInterval:    refreshInterval,
+		minRefreshInterval: minRefreshInterval,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jwksLoadTimeout)
+	defer cancel()
+	keys, err := j.load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load jwks from %s, got error : %w", source, err)
+	}
+	j.keys, j.loadedAt = keys, time.Now()
+	j.attemptedAt = j.loadedAt
+
+	return j, nil
+}
+
+// key returns the key of the kid. Keys older than the refresh interval are reloaded in the
+// background while the keys loaded last keep verifying tokens, whereas a kid the keys do not hold
+// waits for a reload. A failed reload keeps the keys loaded last.
+func (j *jwks) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	j.mu.Lock()
+	key, ok := j.keys[kid]
+	var reloaded <-chan struct{}
+	if !ok || time.Since(j.loadedAt) > j.refreshInterval {
+		reloaded = j.reload()
+	}
+	j.mu.Unlock()
+
+	if !ok && reloaded != nil {
+		select {
+		case <-reloaded:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed get key of token, got error : %w", ctx.Err())
+		}
+		j.mu.Lock()
+		key, ok = j.keys[kid]
+		j.mu.Unlock()
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("failed get key of token, got unknown kid %q", kid)
+	}
+	return key, nil
+}
+
+// reload starts reloading the keys unless a reload is running already or the last one started
+// less than the backoff ago, and returns a channel closed when the running reload ends, nil when
+// none runs. The keys are loaded outside mu, with a context of their own so that a request giving
+// up does not fail the reload for the requests waiting on it. It is called with mu held.
+func (j *jwks) reload() <-chan struct{} {
+	if j.reloading != nil {
+		return j.reloading
+	}
+	if time.Since(j.attemptedAt) <= j.backoff() {
+		return nil
+	}
+
+	done := make(chan struct{})
+	j.reloading, j.attemptedAt = done, time.Now()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), jwksLoadTimeout)
+		defer cancel()
+		keys, err := j.load(ctx)
+
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		if err != nil {
+			j.failures++
+			zap.L().Warn("failed to reload jwks, keeping keys loaded last",
+				zap.String("source", j.source), zap.Int("failures", j.failures), zap.Error(err))
+		} else {
+			j.keys, j.loadedAt, j.failures = keys, time.Now(), 0
+		}
+		j.reloading = nil
+		close(done)
+	}()
+	return done
+}
+
+// backoff returns how long after the last load started the keys may be reloaded: the minimum
+// refresh interval, doubled for each reload failed in a row up to the refresh interval
+func (j *jwks) backoff() time.Duration {
+	backoff := min(j.minRefreshInterval, j.refreshInterval)
+	for range j.failures {
+		if backoff *= 2; backoff >= j.refreshInterval {
+			return j.refreshInterval
+		}
+	}
+	return backoff
+}
+
+// load reads and decodes the signing keys of the JWKS, skipping the keys of other uses and types
+func (j *jwks) load(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	body, err := j.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(body, &set); err != nil {
+		return nil, fmt.Errorf("failed to decode jwks, got error : %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			loggerFrom(ctx).Warn("skipping unusable jwk", zap.String("kid", k.Kid), zap.Error(err))
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("failed get signing keys in jwks")
+	}
+
+	return keys, nil
+}
+
+// read returns the content of the JWKS file or the body of the JWKS URL
+func (j *jwks) read(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(j.source, "http://") && !strings.HasPrefix(j.source, "https://") {
+		return os.ReadFile(j.source)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := j.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed get jwks, got status %d", res.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(res.Body, 1<<20))
+}
@@ -0,0 +1,200 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultLedgerPageSize is the number of ledgers listed when no limit is requested
+	DefaultLedgerPageSize = 50
+	// MaxLedgerPageSize is the largest number of ledgers listed in a single page
+	MaxLedgerPageSize = 200
+)
+
+// LedgerRequestDTO represents the request payload for opening a ledger; a ledger requested without a
+// balance policy may be drained to exactly zero
+type LedgerRequestDTO struct {
+	Type     string        `json:"type"`
+	Currency Currency      `json:"currency"`
+	Policy   BalancePolicy `json:"policy"`
+}
+
+// LedgerQuery represents the filter and page of a ledger listing
+type LedgerQuery struct {
+	Type   string
+	Cursor string
+	Limit  int
+}
+
+// LedgerPage represents one page of ledgers ordered by id
+type LedgerPage struct {
+	Ledgers    []Ledger `json:"ledgers"`
+	NextCursor string   `json:"nextCursor,omitempty"`
+}
+
+// Validate checks the ledger request has a type, a supported currency and, when set, a valid balance
+// policy
+func (lrd LedgerRequestDTO) Validate() error {
+	if lrd.Type == "" {
+		return errors.New("failed get valid ledger type")
+	}
+
+	if err := lrd.Currency.Validate(); err != nil {
+		return err
+	}
+
+	if lrd.Policy.Type == "" {
+		return nil
+	}
+
+	if err := lrd.Policy.Validate(); err != nil {
+		return err
+	}
+
+	if !lrd.Currency.Allows(lrd.Policy.OverdraftLimit) {
+		return fmt.Errorf("failed get overdraft limit with at most %d decimal places for %s", lrd.Currency.MinorUnits(), lrd.Currency)
+	}
+
+	return nil
+}
+
+// CreateLedger opens a new empty ledger in a currency the store has a counter account for
+func (s *store) CreateLedger(ctx context.Context, lrd LedgerRequestDTO) (Ledger, error) {
+	if err := lrd.Validate(); err != nil {
+		return Ledger{}, fmt.Errorf("failed to create ledger, got error : %w", withKind(ErrValidation, err))
+	}
+	if _, err := s.counterAccount(lrd.Currency); err != nil {
+		return Ledger{}, fmt.Errorf("failed to create ledger, got error : %w", err)
+	}
+
+	ledger := &Ledger{
+		ID:        s.uuid.Generate(),
+		Type:      lrd.Type,
+		Currency:  lrd.Currency,
+		Status:    LedgerOpen,
+		CreatedAt: time.Now().UTC().UnixMilli(),
+		Policy:    lrd.Policy,
+	}
+	if ledger.Policy.Type == "" {
+		ledger.Policy = s.defaultPolicy(ledger.ID)
+	}
+
+	// the generated id is unused, so no lock is needed until the ledger is added to the store
+	if err := s.commit(change{Kind: changeLedgerCreated, Ledger: ledger}); err != nil {
+		return Ledger{}, fmt.Errorf("failed to create ledger, got error : %w", err)
+	}
+
+	loggerFrom(ctx).Info("created ledger", zap.String("ledgerId", ledger.ID), zap.String("type", ledger.Type))
+	return s.GetLedger(ctx, ledger.ID)
+}
+
+// GetLedger returns the ledger metadata
+func (s *store) GetLedger(ctx context.Context, ledgerId string) (Ledger, error) {
+	unlock := s.lockLedgers(ledgerId)
+	defer unlock()
+
+	ledger, err := s.getLedger(ledgerId)
+	if err != nil {
+		return Ledger{}, fmt.Errorf("failed to get ledger, got error : %w", err)
+	}
+
+	return ledger.metadata(), nil
+}
+
+// ListLedgers returns a page of ledgers ordered by id, optionally filtered by type
+func (s *store) ListLedgers(ctx context.Context, query LedgerQuery) (LedgerPage, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultLedgerPageSize
+	}
+	if limit > MaxLedgerPageSize {
+		limit = MaxLedgerPageSize
+	}
+
+	s.mu.RLock()
+	ids := make([]string, 0, len(s.ledgers))
+	for id, ledger := range s.ledgers {
+		if id <= query.Cursor {
+			continue
+		}
+		if query.Type != "" && ledger.Type != query.Type {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	s.mu.RUnlock()
+	sort.Strings(ids)
+
+	page := LedgerPage{Ledgers: make([]Ledger, 0, min(limit, len(ids)))}
+	for _, id := range ids {
+		if len(page.Ledgers) == limit {
+			page.NextCursor = page.Ledgers[limit-1].ID
+			break
+		}
+
+		ledger, err := s.getLedger(id)
+		if err != nil {
+			return LedgerPage{}, fmt.Errorf("failed to list ledgers, got error : %w", err)
+		}
+		unlock := s.lockLedgers(id)
+		page.Ledgers = append(page.Ledgers, ledger.metadata())
+		unlock()
+	}
+
+	return page, nil
+}
+
+// CloseLedger closes the ledger so that it rejects further transactions
+func (s *store) CloseLedger(ctx context.Context, ledgerId string) (Ledger, error) {
+	return retryStale(func() (Ledger, error) {
+		return s.closeLedger(ctx, ledgerId)
+	})
+}
+
+// closeLedger closes the ledger
+func (s *store) closeLedger(ctx context.Context, ledgerId string) (Ledger, error) {
+	unlock := s.lockLedgers(ledgerId)
+	defer unlock()
+
+	ledger, err := s.getLedger(ledgerId)
+	if err != nil {
+		return Ledger{}, fmt.Errorf("failed to close ledger, got error : %w", err)
+	}
+
+	if ledger.Status == LedgerClosed {
+		return Ledger{}, withKind(ErrLedgerClosed, fmt.Errorf("failed to close ledger, got already closed ledger: %s", ledgerId))
+	}
+
+	if s.isCounterAccount(ledgerId) {
+		return Ledger{}, withKind(ErrConflict, fmt.Errorf("failed to close ledger, got counter account: %s", ledgerId))
+	}
+
+	closed := ledger.metadata()
+	closed.Status = LedgerClosed
+	closed.ClosedAt = time.Now().UTC().UnixMilli()
+	if err := s.commit(change{Kind: changeLedgerClosed, Ledger: &closed}); err != nil {
+		return Ledger{}, fmt.Errorf("failed to close ledger, got error : %w", err)
+	}
+
+	loggerFrom(ctx).Info("closed ledger", zap.String("ledgerId", ledgerId))
+	return ledger.metadata(), nil
+}
+
+// metadata returns a copy of the ledger without its balance, transaction history, holds and events
+func (l *Ledger) metadata() Ledger {
+	copied := *l
+	copied.Transactions = nil
+	copied.balance = Money{}
+	copied.held = Money{}
+	copied.reversedBy = nil
+	copied.events = nil
+	copied.snapshots = nil
+	copied.unhashed = 0
+	return copied
+}
@@ -0,0 +1,166 @@
+package ledger_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+	internalMock "github.com/dineshd30/ledger-service/internal/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreCreateLedger(t *testing.T) {
+	tests := []struct {
+		name        string
+		request     ledger.LedgerRequestDTO
+		expectError bool
+	}{
+		{
+			name:    "Create cash ledger",
+			request: ledger.LedgerRequestDTO{Type: "cash", Currency: "GBP"},
+		},
+		{
+			name:        "Create ledger without type returns error",
+			request:     ledger.LedgerRequestDTO{Currency: "GBP"},
+			expectError: true,
+		},
+		{
+			name:        "Create ledger with unsupported currency returns error",
+			request:     ledger.LedgerRequestDTO{Type: "cash", Currency: "XYZ"},
+			expectError: true,
+		},
+		{
+			name:        "Create ledger in currency without counter account returns error",
+			request:     ledger.LedgerRequestDTO{Type: "cash", Currency: "USD"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ledgers := make(map[string]*ledger.Ledger)
+			uuid := internalMock.UUIDGenerator{}
+			uuid.On("Generate").Return("new-ledger")
+			storeInstance := ledger.NewStore(&uuid, ledgers, ledger.StoreOptions{
+				CounterAccounts: map[ledger.Currency]string{"GBP": "counter-gbp"},
+			})
+
+			created, err := storeInstance.CreateLedger(context.Background(), tc.request)
+
+			if tc.expectError {
+				assert.ErrorIs(t, err, ledger.ErrValidation)
+				assert.Empty(t, ledgers)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "new-ledger", created.ID)
+				assert.Equal(t, tc.request.Type, created.Type)
+				assert.Equal(t, tc.request.Currency, created.Currency)
+				assert.Equal(t, ledger.LedgerOpen, created.Status)
+				assert.NotZero(t, created.CreatedAt)
+
+				fetched, err := storeInstance.GetLedger(context.Background(), "new-ledger")
+				assert.NoError(t, err)
+				assert.Equal(t, created, fetched)
+			}
+		})
+	}
+}
+
+func TestStoreListLedgers(t *testing.T) {
+	ledgers := make(map[string]*ledger.Ledger)
+	for i := 0; i < 5; i++ {
+		id := fmt.Sprintf("ledger-%d", i)
+		ledgerType := "cash"
+		if i%2 == 1 {
+			ledgerType = "savings"
+		}
+		ledgers[id] = &ledger.Ledger{ID: id, Type: ledgerType, Currency: "EUR"}
+	}
+	storeInstance := ledger.NewStore(ledger.NewUUIDGenerator(), ledgers, ledger.StoreOptions{})
+
+	tests := []struct {
+		name               string
+		query              ledger.LedgerQuery
+		expectedIds        []string
+		expectedNextCursor string
+	}{
+		{
+			name:        "List all ledgers",
+			query:       ledger.LedgerQuery{},
+			expectedIds: []string{"ledger-0", "ledger-1", "ledger-2", "ledger-3", "ledger-4"},
+		},
+		{
+			name:               "List first page",
+			query:              ledger.LedgerQuery{Limit: 2},
+			expectedIds:        []string{"ledger-0", "ledger-1"},
+			expectedNextCursor: "ledger-1",
+		},
+		{
+			name:        "List last page",
+			query:       ledger.LedgerQuery{Limit: 2, Cursor: "ledger-3"},
+			expectedIds: []string{"ledger-4"},
+		},
+		{
+			name:               "List ledgers filtered by type",
+			query:              ledger.LedgerQuery{Type: "cash", Limit: 2},
+			expectedIds:        []string{"ledger-0", "ledger-2"},
+			expectedNextCursor: "ledger-2",
+		},
+		{
+			name:        "List ledgers filtered by unknown type",
+			query:       ledger.LedgerQuery{Type: "unknown"},
+			expectedIds: []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := storeInstance.ListLedgers(context.Background(), tc.query)
+
+			assert.NoError(t, err)
+			ids := make([]string, 0, len(page.Ledgers))
+			for _, l := range page.Ledgers {
+				ids = append(ids, l.ID)
+				assert.Equal(t, ledger.LedgerOpen, l.Status)
+			}
+			assert.Equal(t, tc.expectedIds, ids)
+			assert.Equal(t, tc.expectedNextCursor, page.NextCursor)
+		})
+	}
+}
+
+func TestStoreCloseLedger(t *testing.T) {
+	ledgers := newLedgersWithCounterAccount()
+	ledgers["wallet"] = &ledger.Ledger{ID: "wallet", Type: "cash", Currency: "EUR"}
+	uuid := internalMock.UUIDGenerator{}
+	uuid.On("Generate").Return("123")
+	storeInstance := ledger.NewStore(&uuid, ledgers, counterAccountOptions)
+	deposit := ledger.TransactionRequestDTO{
+		Type:        ledger.Credit,
+		Description: "deposit",
+		Currency:    "EUR",
+		Amount:      ledger.MustParseMoney("10"),
+	}
+
+	_, err := storeInstance.Credit(context.Background(), "wallet", deposit)
+	assert.NoError(t, err)
+
+	closed, err := storeInstance.CloseLedger(context.Background(), "wallet")
+	assert.NoError(t, err)
+	assert.Equal(t, ledger.LedgerClosed, closed.Status)
+	assert.NotZero(t, closed.ClosedAt)
+
+	_, err = storeInstance.Credit(context.Background(), "wallet", deposit)
+	assert.ErrorContains(t, err, "failed to post to closed ledger: wallet")
+	assert.Len(t, ledgers["wallet"].Transactions, 1)
+
+	_, err = storeInstance.CloseLedger(context.Background(), "wallet")
+	assert.ErrorContains(t, err, "already closed")
+
+	_, err = storeInstance.CloseLedger(context.Background(), "counter-eur")
+	assert.ErrorContains(t, err, "counter account")
+
+	_, err = storeInstance.CloseLedger(context.Background(), "unknown")
+	assert.Error(t, err)
+}
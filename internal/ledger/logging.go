@@ -0,0 +1,85 @@
+package ledger
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader is the request and response header carrying the id of the request
+const RequestIDHeader = "X-Request-ID"
+
+// MaxRequestIDLength is the longest request id accepted from a client; a longer one is replaced
+const MaxRequestIDLength = 128
+
+// loggerKey is the context key of the request-scoped logger
+type loggerKey struct{}
+
+// withLogger returns a copy of ctx carrying the logger
+func withLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// loggerFrom returns the logger carried by ctx, the global logger if it carries none, so that the
+// lines logged while serving a request carry its id
+func loggerFrom(ctx context.Context) *zap.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok {
+		return logger
+	}
+	return zap.L()
+}
+
+// RequestLogger assigns every request an id, the one of its X-Request-ID header when valid or one
+// generated by uuid otherwise, and returns it in the X-Request-ID response header. The request
+// context carries a logger derived from logger with a "request" object holding the id, the ledger
+// id of the path, the client address and the trace id, which the handlers and the store log with.
+// Once served, the request is logged with its status and latency.
+func RequestLogger(uuid UUIDGenerator, logger *zap.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+
+		requestId := ctx.GetHeader(RequestIDHeader)
+		if !validRequestID(requestId) {
+			requestId = uuid.Generate()
+		}
+		ctx.Header(RequestIDHeader, requestId)
+
+		fields := []zap.Field{zap.String("id", requestId), zap.String("clientIp", ctx.ClientIP())}
+		if ledgerId := ctx.Param("ledgerId"); ledgerId != "" {
+			fields = append(fields, zap.String("ledgerId", ledgerId))
+		}
+		if spanContext := trace.SpanContextFromContext(ctx.Request.Context()); spanContext.HasTraceID() {
+			fields = append(fields, zap.String("traceId", spanContext.TraceID().String()))
+		}
+		requestLogger := logger.With(zap.Dict("request", fields...))
+		ctx.Request = ctx.Request.WithContext(withLogger(ctx.Request.Context(), requestLogger))
+
+		ctx.Next()
+
+		// the request logger of the context, to which authentication adds the client
+		loggerFrom(ctx.Request.Context()).Info("served request",
+			zap.String("method", ctx.Request.Method),
+			zap.String("route", ctx.FullPath()),
+			zap.Int("status", ctx.Writer.Status()),
+			zap.Duration("latency", time.Since(start)))
+	}
+}
+
+// validRequestID reports whether the client supplied request id is short enough and only made of
+// letters, digits and the separators - _ . :, so that it is safe to log and echo back
+func validRequestID(requestId string) bool {
+	if requestId == "" || len(requestId) > MaxRequestIDLength {
+		return false
+	}
+	for _, r := range requestId {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+package ledger_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+	internalMock "github.com/dineshd30/ledger-service/internal/mock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRequestLogger(t *testing.T) {
+	tests := []struct {
+		name       string
+		requestId  string
+		expectedId string
+	}{
+		{
+			name:       "should generate request id when none is sent",
+			requestId:  "",
+			expectedId: "generated-id",
+		},
+		{
+			name:       "should keep request id sent by client",
+			requestId:  "client-id:42",
+			expectedId: "client-id:42",
+		},
+		{
+			name:       "should replace request id with unsafe characters",
+			requestId:  "client id\nforged",
+			expectedId: "generated-id",
+		},
+		{
+			name:       "should replace request id longer than the limit",
+			requestId:  strings.Repeat("a", ledger.MaxRequestIDLength+1),
+			expectedId: "generated-id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			core, logs := observer.New(zapcore.InfoLevel)
+			uuid := new(internalMock.UUIDGenerator)
+			uuid.On("Generate").Return("generated-id").Maybe()
+
+			router := gin.New()
+			router.Use(ledger.RequestLogger(uuid, zap.New(core)))
+			router.POST("/ledger/:ledgerId/transaction", ledger.DoTransaction(newFundedStore(t)))
+
+			req := httptest.NewRequest(http.MethodPost, "/ledger/wallet/transaction",
+				strings.NewReader(`{"type": "credit", "description": "deposit", "currency": "EUR", "amount": 5}`))
+			req.RemoteAddr = "192.0.2.1:1234"
+			if tt.requestId != "" {
+				req.Header.Set(ledger.RequestIDHeader, tt.requestId)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			assert.Equal(t, tt.expectedId, w.Header().Get(ledger.RequestIDHeader))
+
+			messages := make([]string, 0, logs.Len())
+			for _, entry := range logs.All() {
+				messages = append(messages, entry.Message)
+				assert.Equal(t, map[string]interface{}{
+					"id":       tt.expectedId,
+					"clientIp": "192.0.2.1",
+					"ledgerId": "wallet",
+				}, entry.ContextMap()["request"], entry.Message)
+			}
+			assert.Equal(t, []string{"called deposit handler", "credited the ledger", "served request"}, messages)
+
+			served := logs.FilterMessage("served request").All()[0].ContextMap()
+			assert.Equal(t, "POST", served["method"])
+			assert.Equal(t, "/ledger/:ledgerId/transaction", served["route"])
+			assert.Equal(t, int64(http.StatusOK), served["status"])
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package ledger
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics holds the Prometheus collectors of the service: the requests served per route and
+// status, and the domain metrics recorded by the store. A nil *Metrics records nothing.
+type Metrics struct {
+	requests          *prometheus.CounterVec
+	requestDuration   *prometheus.HistogramVec
+	transactions      *prometheus.CounterVec
+	transactionVolume *prometheus.CounterVec
+	rejectedDebits    *prometheus.CounterVec
+	ledgers           *prometheus.GaugeVec
+}
+
+// NewMetrics creates the collectors of the service and registers them with registerer
+func NewMetrics(registerer prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Number of HTTP requests served, by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time taken to serve HTTP requests, by method, route and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		transactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ledger_transactions_total",
+			Help: "Number of credits and debits recorded, by transaction type and ledger type.",
+		}, []string{"type", "ledger_type"}),
+		transactionVolume: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ledger_transaction_volume_total",
+			Help: "Sum of the amounts of the credits and debits recorded, by transaction type, ledger type and currency.",
+		}, []string{"type", "ledger_type", "currency"}),
+		rejectedDebits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ledger_rejected_debits_total",
+			Help: "Number of debit legs rejected by the balance policy or a closed ledger, by the code of the error returned.",
+		}, []string{"reason"}),
+		ledgers: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "ledger_ledgers",
+			Help: "Number of ledgers in the store, by ledger type and status.",
+		}, []string{"type", "status"}),
+	}
+
+	registerer.MustRegister(m.requests, m.requestDuration, m.transactions, m.transactionVolume, m.rejectedDebits, m.ledgers)
+	return m
+}
+
+// Middleware records the count and latency of every request under the route it matched, so that
+// ids in the path do not each become a series of their own
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		if m == nil {
+			return
+		}
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(ctx.Writer.Status())
+		m.requests.WithLabelValues(ctx.Request.Method, route, status).Inc()
+		m.requestDuration.WithLabelValues(ctx.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// recordTransaction counts the committed credit or debit and adds its amount to the volume of its
+// ledger type
+func (m *Metrics) recordTransaction(ledger *Ledger, tx Transaction) {
+	if m == nil {
+		return
+	}
+	m.transactions.WithLabelValues(string(tx.Type), ledger.Type).Inc()
+	m.transactionVolume.WithLabelValues(string(tx.Type), ledger.Type, string(ledger.Currency)).
+		Add(float64(tx.Amount.units) / float64(moneyFactor))
+}
+
+// recordRejectedDebit counts the debit leg of a journal entry rejected with err by the balance policy
+// or a closed ledger, under the error code the API responds with
+func (m *Metrics) recordRejectedDebit(err error) {
+	if m == nil {
+		return
+	}
+	m.rejectedDebits.WithLabelValues(string(errorCode(errorStatus(err), err))).Inc()
+}
+
+// recordLedgerEvent keeps the ledger count in step with the ledgers opened and closed, both as
+// they are committed and as the store is loaded
+func (m *Metrics) recordLedgerEvent(ledger *Ledger, e Event) {
+	if m == nil {
+		return
+	}
+	switch e.Type {
+	case EventLedgerOpened:
+		m.ledgers.WithLabelValues(ledger.Type, string(LedgerOpen)).Inc()
+	case EventLedgerClosed:
+		m.ledgers.WithLabelValues(ledger.Type, string(LedgerOpen)).Dec()
+		m.ledgers.WithLabelValues(ledger.Type, string(LedgerClosed)).Inc()
+	}
+}
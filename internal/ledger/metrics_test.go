@@ -0,0 +1,120 @@
+package ledger_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreRecordsDomainMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	opts := counterAccountOptions
+	opts.Metrics = ledger.NewMetrics(registry)
+	ledgers := newLedgersWithCounterAccount()
+	ledgers["wallet"] = &ledger.Ledger{ID: "wallet", Type: "cash", Currency: "EUR"}
+	storeInstance := ledger.NewStore(ledger.NewUUIDGenerator(), ledgers, opts)
+
+	savings, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "savings", Currency: "EUR"})
+	require.NoError(t, err)
+	_, err = storeInstance.Credit(context.Background(), "wallet", ledger.TransactionRequestDTO{
+		Type: ledger.Credit, Description: "deposit", Currency: "EUR", Amount: ledger.MustParseMoney("100.5"),
+	})
+	require.NoError(t, err)
+	_, err = storeInstance.Debit(context.Background(), "wallet", ledger.TransactionRequestDTO{
+		Type: ledger.Debit, Description: "payment", Currency: "EUR", Amount: ledger.MustParseMoney("20"),
+	})
+	require.NoError(t, err)
+	_, err = storeInstance.Debit(context.Background(), "wallet", ledger.TransactionRequestDTO{
+		Type: ledger.Debit, Description: "payment", Currency: "EUR", Amount: ledger.MustParseMoney("1000"),
+	})
+	require.ErrorIs(t, err, ledger.ErrInsufficientFunds)
+	_, err = storeInstance.Post(context.Background(), ledger.JournalEntryRequestDTO{
+		Description: "sweep", Currency: "EUR", Legs: []ledger.LegDTO{
+			{LedgerID: "wallet", Type: ledger.Debit, Amount: ledger.MustParseMoney("500")},
+			{LedgerID: savings.ID, Type: ledger.Credit, Amount: ledger.MustParseMoney("500")},
+		},
+	})
+	require.ErrorIs(t, err, ledger.ErrInsufficientFunds)
+	// rejections other than of a debit leg by the balance policy or a closed ledger are not counted
+	_, err = storeInstance.Transfer(context.Background(), ledger.TransferRequestDTO{
+		SourceLedgerID: "wallet", DestinationLedgerID: "wallet", Currency: "EUR", Amount: ledger.MustParseMoney("1"),
+	})
+	require.ErrorIs(t, err, ledger.ErrValidation)
+	_, err = storeInstance.CloseLedger(context.Background(), savings.ID)
+	require.NoError(t, err)
+	_, err = storeInstance.Transfer(context.Background(), ledger.TransferRequestDTO{
+		SourceLedgerID: "wallet", DestinationLedgerID: savings.ID, Currency: "EUR", Amount: ledger.MustParseMoney("1"),
+	})
+	require.ErrorIs(t, err, ledger.ErrLedgerClosed)
+	_, err = storeInstance.Transfer(context.Background(), ledger.TransferRequestDTO{
+		SourceLedgerID: savings.ID, DestinationLedgerID: "wallet", Currency: "EUR", Amount: ledger.MustParseMoney("1"),
+	})
+	require.ErrorIs(t, err, ledger.ErrLedgerClosed)
+
+	expected := `
+# HELP ledger_ledgers Number of ledgers in the store, by ledger type and status.
+# TYPE ledger_ledgers gauge
+ledger_ledgers{status="closed",type="savings"} 1
+ledger_ledgers{status="open",type="cash"} 1
+ledger_ledgers{status="open",type="counter-account"} 1
+ledger_ledgers{status="open",type="savings"} 0
+# HELP ledger_rejected_debits_total Number of debit legs rejected by the balance policy or a closed ledger, by the code of the error returned.
+# TYPE ledger_rejected_debits_total counter
+ledger_rejected_debits_total{reason="insufficient_funds"} 2
+ledger_rejected_debits_total{reason="ledger_closed"} 1
+# HELP ledger_transaction_volume_total Sum of the amounts of the credits and debits recorded, by transaction type, ledger type and currency.
+# TYPE ledger_transaction_volume_total counter
+ledger_transaction_volume_total{currency="EUR",ledger_type="cash",type="credit"} 100.5
+ledger_transaction_volume_total{currency="EUR",ledger_type="cash",type="debit"} 20
+ledger_transaction_volume_total{currency="EUR",ledger_type="counter-account",type="credit"} 20
+ledger_transaction_volume_total{currency="EUR",ledger_type="counter-account",type="debit"} 100.5
+# HELP ledger_transactions_total Number of credits and debits recorded, by transaction type and ledger type.
+# TYPE ledger_transactions_total counter
+ledger_transactions_total{ledger_type="cash",type="credit"} 1
+ledger_transactions_total{ledger_type="cash",type="debit"} 1
+ledger_transactions_total{ledger_type="counter-account",type="credit"} 1
+ledger_transactions_total{ledger_type="counter-account",type="debit"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"ledger_ledgers", "ledger_rejected_debits_total", "ledger_transaction_volume_total", "ledger_transactions_total"))
+}
+
+func TestMetricsMiddlewareRecordsRequestsPerRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := prometheus.NewRegistry()
+	metrics := ledger.NewMetrics(registry)
+
+	router := gin.New()
+	router.Use(metrics.Middleware(), gin.Recovery())
+	router.GET("/ledgers/:ledgerId", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+	router.GET("/panic", func(ctx *gin.Context) {
+		panic("handler failed")
+	})
+
+	for _, path := range []string{"/ledgers/a", "/ledgers/b", "/panic", "/unknown"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	expected := `
+# HELP http_requests_total Number of HTTP requests served, by method, route and status.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",route="/ledgers/:ledgerId",status="200"} 2
+http_requests_total{method="GET",route="/panic",status="500"} 1
+http_requests_total{method="GET",route="unmatched",status="404"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "http_requests_total"))
+	count, err := testutil.GatherAndCount(registry, "http_request_duration_seconds")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
@@ -0,0 +1,138 @@
+package ledger
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// migrations holds the versioned schema migrations of the SQL store. Each file is named
+// <version>_<name>.sql and its statements are separated by semicolons.
+//
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrate applies, in version order, the schema migrations newer than the version recorded in
+// schema_migrations. Each migration and the record of its version commit in one database transaction.
+func migrate(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    BIGINT PRIMARY KEY,
+    applied_at BIGINT NOT NULL
+)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations, got error : %w", err)
+	}
+
+	var current int64
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to get schema version, got error : %w", err)
+	}
+
+	files, err := fs.ReadDir(migrations, "migrations")
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		version, err := migrationVersion(file.Name())
+		if err != nil {
+			return err
+		}
+		if version <= current {
+			continue
+		}
+
+		script, err := migrations.ReadFile("migrations/" + file.Name())
+		if err != nil {
+			return err
+		}
+
+		if err := applyMigration(ctx, db, version, string(script)); err != nil {
+			return fmt.Errorf("failed to apply migration %s, got error : %w", file.Name(), err)
+		}
+
+		zap.L().Info("applied schema migration", zap.String("migration", file.Name()))
+	}
+
+	return nil
+}
+
+// checkMigrated checks, without applying any, that every schema migration was applied to the database
+func checkMigrated(ctx context.Context, db *sql.DB) error {
+	var current int64
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to get schema version, got error : %w", err)
+	}
+
+	files, err := fs.ReadDir(migrations, "migrations")
+	if err != nil {
+		return err
+	}
+	latest, err := migrationVersion(files[len(files)-1].Name())
+	if err != nil {
+		return err
+	}
+
+	if current < latest {
+		return fmt.Errorf("failed get database migrated to schema version %d, got version %d", latest, current)
+	}
+	return nil
+}
+
+// applyMigration runs the statements of the migration script and records its version
+func applyMigration(ctx context.Context, db *sql.DB, version int64, script string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, statement := range strings.Split(script, ";") {
+		if strings.TrimSpace(stripSQLComments(statement)) == "" {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES ($1, $2)`, version, time.Now().UTC().UnixMilli())
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// migrationVersion returns the version prefix of the migration file name
+func migrationVersion(name string) (int64, error) {
+	prefix, _, ok := strings.Cut(name, "_")
+	if !ok {
+		return 0, fmt.Errorf("failed get migration version from %s", name)
+	}
+
+	version, err := strconv.ParseInt(prefix, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("failed get migration version from %s", name)
+	}
+
+	return version, nil
+}
+
+// stripSQLComments removes the -- line comments of the statement
+func stripSQLComments(statement string) string {
+	lines := strings.Split(statement, "\n")
+	for i, line := range lines {
+		if before, _, found := strings.Cut(line, "--"); found {
+			lines[i] = before
+		}
+	}
+	return strings.Join(lines, "\n")
+}
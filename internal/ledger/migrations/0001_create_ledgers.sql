@@ -0,0 +1,39 @@
+-- Ledgers and the journal entries posted to them. Balances and amounts are stored as integers in
+-- ten-thousandths of the currency unit, e.g. 12.5 EUR is stored as 125000.
+
+CREATE TABLE ledgers (
+    id         VARCHAR(64) PRIMARY KEY,
+    type       VARCHAR(64) NOT NULL,
+    currency   CHAR(3)     NOT NULL,
+    status     VARCHAR(16) NOT NULL,
+    created_at BIGINT      NOT NULL,
+    closed_at  BIGINT,
+    balance    BIGINT      NOT NULL DEFAULT 0,
+    version    BIGINT      NOT NULL DEFAULT 0
+);
+
+CREATE TABLE journal_entries (
+    id          VARCHAR(64) PRIMARY KEY,
+    posted_at   BIGINT      NOT NULL,
+    description TEXT        NOT NULL,
+    currency    CHAR(3)     NOT NULL
+);
+
+CREATE TABLE transactions (
+    id               VARCHAR(64)  PRIMARY KEY,
+    ledger_id        VARCHAR(64)  NOT NULL REFERENCES ledgers (id),
+    seq              BIGINT       NOT NULL,
+    journal_entry_id VARCHAR(64)  NOT NULL REFERENCES journal_entries (id),
+    posted_at        BIGINT       NOT NULL,
+    type             VARCHAR(16)  NOT NULL,
+    description      TEXT         NOT NULL,
+    amount           BIGINT       NOT NULL,
+    running_balance  BIGINT       NOT NULL,
+    transfer_id      VARCHAR(64),
+    idempotency_key  VARCHAR(255),
+    UNIQUE (ledger_id, seq)
+);
+
+CREATE INDEX transactions_ledger_posted_at ON transactions (ledger_id, posted_at);
+
+CREATE INDEX transactions_journal_entry ON transactions (journal_entry_id);
@@ -0,0 +1,12 @@
+-- Idempotency keys of credit and debit requests and the transaction each one produced.
+
+CREATE TABLE idempotency_keys (
+    ledger_id       VARCHAR(64)  NOT NULL REFERENCES ledgers (id),
+    idempotency_key VARCHAR(255) NOT NULL,
+    fingerprint     CHAR(64)     NOT NULL,
+    transaction_id  VARCHAR(64)  NOT NULL REFERENCES transactions (id),
+    created_at      BIGINT       NOT NULL,
+    PRIMARY KEY (ledger_id, idempotency_key)
+);
+
+CREATE INDEX idempotency_keys_created_at ON idempotency_keys (created_at);
@@ -0,0 +1,19 @@
+-- Holds reserving funds on ledgers until they are captured, released or expire.
+
+CREATE TABLE holds (
+    id              VARCHAR(64) PRIMARY KEY,
+    ledger_id       VARCHAR(64) NOT NULL REFERENCES ledgers (id),
+    description     TEXT        NOT NULL,
+    currency        CHAR(3)     NOT NULL,
+    amount          BIGINT      NOT NULL,
+    status          VARCHAR(16) NOT NULL,
+    created_at      BIGINT      NOT NULL,
+    expires_at      BIGINT      NOT NULL,
+    closed_at       BIGINT,
+    captured_amount BIGINT      NOT NULL DEFAULT 0,
+    transaction_id  VARCHAR(64) REFERENCES transactions (id)
+);
+
+CREATE INDEX holds_ledger ON holds (ledger_id);
+
+CREATE INDEX holds_status_expires_at ON holds (status, expires_at);
@@ -0,0 +1,5 @@
+-- Links a reversing transaction to the transaction it reverses, which is reversed at most once.
+
+ALTER TABLE transactions ADD COLUMN reversal_of VARCHAR(64) REFERENCES transactions (id);
+
+CREATE UNIQUE INDEX transactions_reversal_of ON transactions (reversal_of);
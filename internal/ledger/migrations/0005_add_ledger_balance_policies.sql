@@ -0,0 +1,6 @@
+-- Balance policy of each ledger. An empty policy type is replaced by the default policy of the
+-- ledger when it is loaded.
+
+ALTER TABLE ledgers ADD COLUMN policy_type VARCHAR(16) NOT NULL DEFAULT '';
+
+ALTER TABLE ledgers ADD COLUMN overdraft_limit BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,11 @@
+-- The append-only event stream of every ledger, from which the ledgers are rebuilt on startup. The
+-- other tables are kept up to date alongside for querying with standard SQL tooling.
+
+CREATE TABLE ledger_events (
+    ledger_id   VARCHAR(64) NOT NULL REFERENCES ledgers (id),
+    version     BIGINT      NOT NULL,
+    type        VARCHAR(32) NOT NULL,
+    occurred_at BIGINT      NOT NULL,
+    payload     TEXT        NOT NULL,
+    PRIMARY KEY (ledger_id, version)
+);
@@ -0,0 +1,8 @@
+-- Hash of each transaction chained over the hash of the previous transaction of its ledger. The
+-- transactions recorded before keep no hash and their events are marked unhashed, so as the
+-- leading transactions of their ledger they are chained each time the store loads them. Any other
+-- transaction without a hash is a broken link of its chain.
+
+ALTER TABLE transactions ADD COLUMN hash CHAR(64);
+ALTER TABLE ledger_events ADD COLUMN unhashed BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE ledger_events SET unhashed = TRUE WHERE type IN ('credited', 'debited');
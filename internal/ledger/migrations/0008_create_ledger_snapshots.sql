@@ -0,0 +1,9 @@
+-- Snapshot of the state of a ledger every snapshot interval, from which the ledger is restored on
+-- startup before the events after it are folded.
+
+CREATE TABLE ledger_snapshots (
+    ledger_id VARCHAR(64) NOT NULL REFERENCES ledgers (id),
+    version   BIGINT      NOT NULL,
+    payload   TEXT        NOT NULL,
+    PRIMARY KEY (ledger_id, version)
+);
@@ -0,0 +1,224 @@
+package ledger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// moneyScale is the number of decimal places held internally by Money
+const moneyScale = 4
+
+// moneyFactor is 10^moneyScale, the number of units in one major currency unit
+const moneyFactor int64 = 10000
+
+// maxMoneyDigits bounds the integer part of a parsed amount, so that a single amount is below 10^16
+// units and a balance takes hundreds of the largest amounts before leaving the range of int64.
+// Balances are still summed with CheckedAdd and CheckedSub, which reject a sum out of range.
+const maxMoneyDigits = 12
+
+// Money represents an exact monetary amount as a fixed-point decimal.
+// The zero value is an amount of zero.
+type Money struct {
+	units int64
+}
+
+// ParseMoney parses a plain decimal string such as "66.33" or "-20" into Money.
+// Exponents, leading plus signs, and more than 4 decimal places are rejected.
+func ParseMoney(s string) (Money, error) {
+	if s == "" {
+		return Money{}, errors.New("failed to parse empty amount")
+	}
+
+	negative := false
+	digits := s
+	if strings.HasPrefix(digits, "-") {
+		negative = true
+		digits = digits[1:]
+	}
+
+	whole, fraction, hasPoint := strings.Cut(digits, ".")
+	if whole == "" || (hasPoint && fraction == "") {
+		return Money{}, fmt.Errorf("failed to parse amount: %q", s)
+	}
+	if !isDigits(whole) || !isDigits(fraction) {
+		return Money{}, fmt.Errorf("failed to parse amount: %q", s)
+	}
+	if len(whole) > 1 && whole[0] == '0' {
+		return Money{}, fmt.Errorf("failed to parse amount with leading zeros: %q", s)
+	}
+	if len(whole) > maxMoneyDigits {
+		return Money{}, fmt.Errorf("failed to parse amount within range: %q", s)
+	}
+	if len(fraction) > moneyScale {
+		return Money{}, fmt.Errorf("failed to parse amount with at most %d decimal places: %q", moneyScale, s)
+	}
+
+	major, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("failed to parse amount: %q, got error : %w", s, err)
+	}
+	minor := int64(0)
+	if fraction != "" {
+		minor, err = strconv.ParseInt(fraction+strings.Repeat("0", moneyScale-len(fraction)), 10, 64)
+		if err != nil {
+			return Money{}, fmt.Errorf("failed to parse amount: %q, got error : %w", s, err)
+		}
+	}
+
+	units := major*moneyFactor + minor
+	if negative {
+		units = -units
+	}
+	return Money{units: units}, nil
+}
+
+// MustParseMoney is like ParseMoney but panics if the amount cannot be parsed
+func MustParseMoney(s string) Money {
+	m, err := ParseMoney(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Add returns the sum of m and o; it wraps around when the sum is out of range, so sums of
+// amounts that are not bounded, such as balances, use CheckedAdd
+func (m Money) Add(o Money) Money {
+	return Money{units: m.units + o.units}
+}
+
+// Sub returns the difference of m and o; it wraps around when the difference is out of range, so
+// differences of amounts that are not bounded, such as balances, use CheckedSub
+func (m Money) Sub(o Money) Money {
+	return Money{units: m.units - o.units}
+}
+
+// CheckedAdd returns the sum of m and o, or an error when the sum is out of the range of Money
+func (m Money) CheckedAdd(o Money) (Money, error) {
+	sum := m.units + o.units
+	if (sum > m.units) != (o.units > 0) {
+		return Money{}, fmt.Errorf("failed to add %s to %s within range", o, m)
+	}
+	return Money{units: sum}, nil
+}
+
+// CheckedSub returns the difference of m and o, or an error when the difference is out of the
+// range of Money
+func (m Money) CheckedSub(o Money) (Money, error) {
+	difference := m.units - o.units
+	if (difference < m.units) != (o.units > 0) {
+		return Money{}, fmt.Errorf("failed to subtract %s from %s within range", o, m)
+	}
+	return Money{units: difference}, nil
+}
+
+// Neg returns the negated amount
+func (m Money) Neg() Money {
+	return Money{units: -m.units}
+}
+
+// Cmp compares m and o and returns -1, 0 or +1
+func (m Money) Cmp(o Money) int {
+	switch {
+	case m.units < o.units:
+		return -1
+	case m.units > o.units:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of m
+func (m Money) Sign() int {
+	return m.Cmp(Money{})
+}
+
+// IsZero reports whether m is zero
+func (m Money) IsZero() bool {
+	return m.units == 0
+}
+
+// IsPositive reports whether m is greater than zero
+func (m Money) IsPositive() bool {
+	return m.units > 0
+}
+
+// IsNegative reports whether m is less than zero
+func (m Money) IsNegative() bool {
+	return m.units < 0
+}
+
+// Precision returns the number of significant decimal places in m
+func (m Money) Precision() int {
+	units := m.units
+	if units < 0 {
+		units = -units
+	}
+	precision := moneyScale
+	for precision > 0 && units%10 == 0 {
+		units /= 10
+		precision--
+	}
+	return precision
+}
+
+// String formats m as a plain decimal without trailing zeros, e.g. "146.32"
+func (m Money) String() string {
+	units := m.units
+	sign := ""
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+
+	whole := strconv.FormatInt(units/moneyFactor, 10)
+	precision := m.Precision()
+	if precision == 0 {
+		return sign + whole
+	}
+
+	fraction := fmt.Sprintf("%0*d", moneyScale, units%moneyFactor)
+	return sign + whole + "." + fraction[:precision]
+}
+
+// MarshalJSON encodes m as a JSON number
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON decodes m from a JSON number or a JSON string holding a decimal
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	raw := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("failed to decode amount, got error : %w", err)
+		}
+	}
+
+	parsed, err := ParseMoney(raw)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// isDigits reports whether s consists of ASCII digits only
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
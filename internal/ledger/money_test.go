@@ -0,0 +1,134 @@
+package ledger_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		expected     string
+		expectedPrec int
+		expectError  bool
+	}{
+		{name: "Whole amount", input: "100", expected: "100", expectedPrec: 0},
+		{name: "Two decimal places", input: "66.33", expected: "66.33", expectedPrec: 2},
+		{name: "Trailing zeros are dropped", input: "20.10", expected: "20.1", expectedPrec: 1},
+		{name: "Four decimal places", input: "0.0001", expected: "0.0001", expectedPrec: 4},
+		{name: "Negative amount", input: "-20.01", expected: "-20.01", expectedPrec: 2},
+		{name: "Largest integer part", input: "999999999999.9999", expected: "999999999999.9999", expectedPrec: 4},
+		{name: "Empty string", input: "", expectError: true},
+		{name: "More than four decimal places", input: "1.00001", expectError: true},
+		{name: "Exponent notation", input: "1e2", expectError: true},
+		{name: "Leading plus sign", input: "+1", expectError: true},
+		{name: "Leading decimal point", input: ".5", expectError: true},
+		{name: "Trailing decimal point", input: "5.", expectError: true},
+		{name: "Leading zeros", input: "007", expectError: true},
+		{name: "Integer part out of range", input: "1000000000000", expectError: true},
+		{name: "Not a number", input: "abc", expectError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := ledger.ParseMoney(tc.input)
+
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expected, m.String())
+				assert.Equal(t, tc.expectedPrec, m.Precision())
+			}
+		})
+	}
+}
+
+func TestMoneyArithmeticIsExact(t *testing.T) {
+	balance := ledger.Money{}
+	tenCents := ledger.MustParseMoney("0.1")
+	threeCents := ledger.MustParseMoney("0.03")
+
+	for i := 0; i < 100000; i++ {
+		balance = balance.Add(tenCents)
+		balance = balance.Sub(threeCents)
+	}
+
+	assert.Equal(t, "7000", balance.String())
+	assert.Equal(t, 1, balance.Sign())
+	assert.Equal(t, 0, balance.Cmp(ledger.MustParseMoney("7000.00")))
+	assert.True(t, balance.Neg().IsNegative())
+}
+
+func TestMoneyCheckedArithmetic(t *testing.T) {
+	largest := ledger.MustParseMoney("999999999999.9999")
+
+	balance, credits := ledger.Money{}, 0
+	for {
+		next, err := balance.CheckedAdd(largest)
+		if err != nil {
+			assert.ErrorContains(t, err, "failed to add 999999999999.9999")
+			break
+		}
+		balance, credits = next, credits+1
+	}
+	assert.Equal(t, 922, credits)
+
+	balance, debits := ledger.Money{}, 0
+	for {
+		next, err := balance.CheckedSub(largest)
+		if err != nil {
+			assert.ErrorContains(t, err, "failed to subtract 999999999999.9999")
+			break
+		}
+		balance, debits = next, debits+1
+	}
+	assert.Equal(t, 922, debits)
+
+	sum, err := largest.CheckedAdd(largest.Neg())
+	assert.NoError(t, err)
+	assert.True(t, sum.IsZero())
+	difference, err := largest.Neg().CheckedSub(largest.Neg())
+	assert.NoError(t, err)
+	assert.True(t, difference.IsZero())
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    string
+		expectError bool
+	}{
+		{name: "JSON number", input: `{"amount": 66.33}`, expected: "66.33"},
+		{name: "JSON string", input: `{"amount": "66.33"}`, expected: "66.33"},
+		{name: "JSON null", input: `{"amount": null}`, expected: "0"},
+		{name: "JSON number with exponent", input: `{"amount": 6.633e1}`, expectError: true},
+		{name: "JSON number with too many decimal places", input: `{"amount": 0.00001}`, expectError: true},
+		{name: "JSON boolean", input: `{"amount": true}`, expectError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var payload struct {
+				Amount ledger.Money `json:"amount"`
+			}
+			err := json.Unmarshal([]byte(tc.input), &payload)
+
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expected, payload.Amount.String())
+
+				encoded, err := json.Marshal(payload)
+				assert.NoError(t, err)
+				assert.JSONEq(t, `{"amount": `+tc.expected+`}`, string(encoded))
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// BalancePolicyType represents how far debits may take the available balance of a ledger
+type BalancePolicyType string
+
+const (
+	// PolicyPositive keeps the available balance above zero
+	PolicyPositive BalancePolicyType = "positive"
+	// PolicyNonNegative lets the available balance be drained to exactly zero but not below
+	PolicyNonNegative BalancePolicyType = "non-negative"
+	// PolicyOverdraft lets the available balance go negative down to the overdraft limit
+	PolicyOverdraft BalancePolicyType = "overdraft"
+	// PolicyUnlimited lets the available balance go negative without limit, for internal system ledgers
+	PolicyUnlimited BalancePolicyType = "unlimited"
+)
+
+// BalancePolicy represents the lowest available balance debits and holds may leave on a ledger.
+// OverdraftLimit is only set for the overdraft policy and is the most the balance may go below zero.
+type BalancePolicy struct {
+	Type           BalancePolicyType `json:"type"`
+	OverdraftLimit Money             `json:"overdraftLimit,omitzero"`
+}
+
+// Validate checks the policy is known and only an overdraft policy has a positive overdraft limit
+func (bp BalancePolicy) Validate() error {
+	switch bp.Type {
+	case PolicyPositive, PolicyNonNegative, PolicyUnlimited:
+		if !bp.OverdraftLimit.IsZero() {
+			return fmt.Errorf("failed get overdraft limit only for %s policy", PolicyOverdraft)
+		}
+	case PolicyOverdraft:
+		if !bp.OverdraftLimit.IsPositive() {
+			return errors.New("failed get overdraft limit greater than zero")
+		}
+	default:
+		return fmt.Errorf("failed get policy type either %s, %s, %s or %s", PolicyPositive, PolicyNonNegative, PolicyOverdraft, PolicyUnlimited)
+	}
+
+	return nil
+}
+
+// allows reports whether the policy accepts the available balance
+func (bp BalancePolicy) allows(available Money) bool {
+	switch bp.Type {
+	case PolicyPositive:
+		return available.IsPositive()
+	case PolicyNonNegative:
+		return !available.IsNegative()
+	case PolicyOverdraft:
+		return available.Cmp(bp.OverdraftLimit.Neg()) >= 0
+	case PolicyUnlimited:
+		return true
+	}
+	return false
+}
+
+// String describes the policy for error messages
+func (bp BalancePolicy) String() string {
+	if bp.Type == PolicyOverdraft {
+		return fmt.Sprintf("%s policy with limit %s", bp.Type, bp.OverdraftLimit)
+	}
+	return fmt.Sprintf("%s policy", bp.Type)
+}
+
+// defaultPolicy returns the policy of a ledger created without one: counter accounts are unlimited
+// and every other ledger may be drained to exactly zero
+func (s *store) defaultPolicy(ledgerId string) BalancePolicy {
+	if s.isCounterAccount(ledgerId) {
+		return BalancePolicy{Type: PolicyUnlimited}
+	}
+	return BalancePolicy{Type: PolicyNonNegative}
+}
+
+// checkPolicy checks the policy of the ledger accepts the available balance left by a debit or hold
+func checkPolicy(ledger *Ledger, available Money) error {
+	if !ledger.Policy.allows(available) {
+		return withKind(ErrInsufficientFunds, fmt.Errorf("failed to get new available balance %s allowed by %s of ledger: %s", available, ledger.Policy, ledger.ID))
+	}
+	return nil
+}
+
+// SetBalancePolicy replaces the balance policy of the ledger. It only applies to later debits and
+// holds, so a ledger whose balance is already below the new policy keeps its balance.
+func (s *store) SetBalancePolicy(ctx context.Context, ledgerId string, policy BalancePolicy) (Ledger, error) {
+	if err := policy.Validate(); err != nil {
+		return Ledger{}, fmt.Errorf("failed to set balance policy, got error : %w", withKind(ErrValidation, err))
+	}
+
+	return retryStale(func() (Ledger, error) {
+		return s.setBalancePolicy(ctx, ledgerId, policy)
+	})
+}
+
+// setBalancePolicy sets the validated policy
+func (s *store) setBalancePolicy(ctx context.Context, ledgerId string, policy BalancePolicy) (Ledger, error) {
+	unlock := s.lockLedgers(ledgerId)
+	defer unlock()
+
+	ledger, err := s.getLedger(ledgerId)
+	if err != nil {
+		return Ledger{}, fmt.Errorf("failed to set balance policy, got error : %w", err)
+	}
+
+	if !ledger.Currency.Allows(policy.OverdraftLimit) {
+		return Ledger{}, withKind(ErrValidation, fmt.Errorf("failed to set balance policy, got error : failed get overdraft limit with at most %d decimal places for %s", ledger.Currency.MinorUnits(), ledger.Currency))
+	}
+
+	changed := ledger.metadata()
+	changed.Policy = policy
+	if err := s.commit(change{Kind: changeLedgerPolicySet, Ledger: &changed, Date: time.Now().UTC().UnixMilli()}); err != nil {
+		return Ledger{}, fmt.Errorf("failed to set balance policy, got error : %w", err)
+	}
+
+	loggerFrom(ctx).Info("set ledger balance policy", zap.String("ledgerId", ledgerId), zap.Stringer("policy", policy))
+	return ledger.metadata(), nil
+}
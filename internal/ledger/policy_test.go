@@ -0,0 +1,193 @@
+package ledger_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreDebitFollowsBalancePolicy(t *testing.T) {
+	tests := []struct {
+		name            string
+		policy          ledger.BalancePolicy
+		amount          string
+		expectedBalance string
+		expectError     bool
+	}{
+		{
+			name:            "Positive policy rejects draining to zero",
+			policy:          ledger.BalancePolicy{Type: ledger.PolicyPositive},
+			amount:          "100",
+			expectedBalance: "100",
+			expectError:     true,
+		},
+		{
+			name:            "Positive policy allows debit leaving a balance",
+			policy:          ledger.BalancePolicy{Type: ledger.PolicyPositive},
+			amount:          "99.99",
+			expectedBalance: "0.01",
+		},
+		{
+			name:            "Non-negative policy allows draining to zero",
+			policy:          ledger.BalancePolicy{Type: ledger.PolicyNonNegative},
+			amount:          "100",
+			expectedBalance: "0",
+		},
+		{
+			name:            "Non-negative policy rejects negative balance",
+			policy:          ledger.BalancePolicy{Type: ledger.PolicyNonNegative},
+			amount:          "100.01",
+			expectedBalance: "100",
+			expectError:     true,
+		},
+		{
+			name:            "Overdraft policy allows balance down to the limit",
+			policy:          ledger.BalancePolicy{Type: ledger.PolicyOverdraft, OverdraftLimit: ledger.MustParseMoney("50")},
+			amount:          "150",
+			expectedBalance: "-50",
+		},
+		{
+			name:            "Overdraft policy rejects balance below the limit",
+			policy:          ledger.BalancePolicy{Type: ledger.PolicyOverdraft, OverdraftLimit: ledger.MustParseMoney("50")},
+			amount:          "150.01",
+			expectedBalance: "100",
+			expectError:     true,
+		},
+		{
+			name:            "Unlimited policy allows any negative balance",
+			policy:          ledger.BalancePolicy{Type: ledger.PolicyUnlimited},
+			amount:          "1000000",
+			expectedBalance: "-999900",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storeInstance := newFundedStore(t)
+			_, err := storeInstance.SetBalancePolicy(context.Background(), "wallet", tt.policy)
+			require.NoError(t, err)
+
+			_, err = storeInstance.Debit(context.Background(), "wallet", ledger.TransactionRequestDTO{
+				Type: ledger.Debit, Description: "payment", Currency: "EUR", Amount: ledger.MustParseMoney(tt.amount),
+			})
+			if tt.expectError {
+				assert.ErrorContains(t, err, string(tt.policy.Type))
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.expectedBalance, assertConsistentHistory(t, storeInstance, "wallet").String())
+		})
+	}
+}
+
+func TestStoreBalancePolicyCoversHoldsAndTransfers(t *testing.T) {
+	storeInstance := newFundedStore(t)
+	_, err := storeInstance.SetBalancePolicy(context.Background(), "wallet", ledger.BalancePolicy{
+		Type: ledger.PolicyOverdraft, OverdraftLimit: ledger.MustParseMoney("20"),
+	})
+	require.NoError(t, err)
+
+	_, err = storeInstance.PlaceHold(context.Background(), "wallet", holdFor("120.01"))
+	assert.Error(t, err)
+	_, err = storeInstance.PlaceHold(context.Background(), "wallet", holdFor("110"))
+	require.NoError(t, err)
+	assertBalances(t, storeInstance, "100", "-10")
+
+	savings, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "savings", Currency: "EUR"})
+	require.NoError(t, err)
+	assert.Equal(t, ledger.BalancePolicy{Type: ledger.PolicyNonNegative}, savings.Policy)
+
+	_, err = storeInstance.Transfer(context.Background(), ledger.TransferRequestDTO{
+		SourceLedgerID: "wallet", DestinationLedgerID: savings.ID, Currency: "EUR", Amount: ledger.MustParseMoney("10.01"),
+	})
+	assert.Error(t, err)
+	_, err = storeInstance.Transfer(context.Background(), ledger.TransferRequestDTO{
+		SourceLedgerID: "wallet", DestinationLedgerID: savings.ID, Currency: "EUR", Amount: ledger.MustParseMoney("10"),
+	})
+	assert.NoError(t, err)
+	assertBalances(t, storeInstance, "90", "-20")
+}
+
+func TestStoreDefaultBalancePolicies(t *testing.T) {
+	storeInstance := newConcurrentStore("wallet")
+
+	wallet, err := storeInstance.GetLedger(context.Background(), "wallet")
+	require.NoError(t, err)
+	assert.Equal(t, ledger.BalancePolicy{Type: ledger.PolicyNonNegative}, wallet.Policy)
+
+	counter, err := storeInstance.GetLedger(context.Background(), "counter-eur")
+	require.NoError(t, err)
+	assert.Equal(t, ledger.BalancePolicy{Type: ledger.PolicyUnlimited}, counter.Policy)
+
+	settlement, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{
+		Type:     "settlement",
+		Currency: "EUR",
+		Policy:   ledger.BalancePolicy{Type: ledger.PolicyUnlimited},
+	})
+	require.NoError(t, err)
+	_, err = storeInstance.Debit(context.Background(), settlement.ID, ledger.TransactionRequestDTO{
+		Type: ledger.Debit, Description: "settlement", Currency: "EUR", Amount: ledger.MustParseMoney("500"),
+	})
+	assert.NoError(t, err)
+}
+
+func TestStoreSetBalancePolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		ledgerId    string
+		policy      ledger.BalancePolicy
+		expectError bool
+	}{
+		{
+			name:     "Overdraft policy",
+			ledgerId: "wallet",
+			policy:   ledger.BalancePolicy{Type: ledger.PolicyOverdraft, OverdraftLimit: ledger.MustParseMoney("25.5")},
+		},
+		{
+			name:        "Overdraft policy without limit returns error",
+			ledgerId:    "wallet",
+			policy:      ledger.BalancePolicy{Type: ledger.PolicyOverdraft},
+			expectError: true,
+		},
+		{
+			name:        "Overdraft limit with too many decimal places returns error",
+			ledgerId:    "wallet",
+			policy:      ledger.BalancePolicy{Type: ledger.PolicyOverdraft, OverdraftLimit: ledger.MustParseMoney("0.001")},
+			expectError: true,
+		},
+		{
+			name:        "Unknown policy returns error",
+			ledgerId:    "wallet",
+			policy:      ledger.BalancePolicy{Type: "generous"},
+			expectError: true,
+		},
+		{
+			name:        "Unknown ledger returns error",
+			ledgerId:    "unknown",
+			policy:      ledger.BalancePolicy{Type: ledger.PolicyUnlimited},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storeInstance := newFundedStore(t)
+
+			updated, err := storeInstance.SetBalancePolicy(context.Background(), tt.ledgerId, tt.policy)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.policy, updated.Policy)
+			stored, err := storeInstance.GetLedger(context.Background(), tt.ledgerId)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.policy, stored.Policy)
+		})
+	}
+}
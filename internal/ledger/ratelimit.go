@@ -0,0 +1,199 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// rateLimitSweepInterval is how often the buckets refilled to their burst are dropped, so that
+// the buckets of clients and ledgers no longer sending requests do not pile up
+const rateLimitSweepInterval = time.Minute
+
+// RateLimit configures a token bucket refilled with Rate tokens per second up to Burst tokens,
+// each request taking one
+type RateLimit struct {
+	Rate  float64 `mapstructure:"rate"`
+	Burst int     `mapstructure:"burst"`
+}
+
+// bucket holds the tokens left to a client or ledger
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// RateLimiter limits the requests of every client or ledger to its own token bucket. A nil
+// RateLimiter lets every request through, for when rate limiting is disabled.
+type RateLimiter struct {
+	limit RateLimit
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	sweptAt time.Time
+}
+
+// NewRateLimiter creates a limiter of the buckets configured by limit, or returns nil when its
+// rate is not positive
+func NewRateLimiter(limit RateLimit) *RateLimiter {
+	if limit.Rate <= 0 {
+		return nil
+	}
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+
+	return &RateLimiter{limit: limit, buckets: make(map[string]*bucket), sweptAt: time.Now()}
+}
+
+// take takes a token from the bucket of every key, or none of them when any is empty, in which case
+// it returns how long until all of them hold a token again
+func (l *RateLimiter) take(keys ...string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.sweptAt) > rateLimitSweepInterval {
+		for key, b := range l.buckets {
+			if l.refill(b, now); b.tokens >= float64(l.limit.Burst) {
+				delete(l.buckets, key)
+			}
+		}
+		l.sweptAt = now
+	}
+
+	var wait time.Duration
+	buckets := make([]*bucket, 0, len(keys))
+	for _, key := range keys {
+		b, ok := l.buckets[key]
+		if !ok {
+			b = &bucket{tokens: float64(l.limit.Burst), updatedAt: now}
+			l.buckets[key] = b
+		}
+		l.refill(b, now)
+		if b.tokens < 1 {
+			wait = max(wait, time.Duration((1-b.tokens)/l.limit.Rate*float64(time.Second)))
+		}
+		buckets = append(buckets, b)
+	}
+	if wait > 0 {
+		return wait, false
+	}
+
+	for _, b := range buckets {
+		b.tokens--
+	}
+	return 0, true
+}
+
+// refill adds the tokens accrued by the bucket since it was last updated
+func (l *RateLimiter) refill(b *bucket, now time.Time) {
+	b.tokens = math.Min(float64(l.limit.Burst), b.tokens+now.Sub(b.updatedAt).Seconds()*l.limit.Rate)
+	b.updatedAt = now
+}
+
+// LimitClients rejects with 429 the request of a client that ran out of tokens, the client being
+// the authenticated API key or token, or the client address when authentication is disabled
+func (l *RateLimiter) LimitClients() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if l == nil {
+			ctx.Next()
+			return
+		}
+
+		bucketKey, client := "ip:"+ctx.ClientIP(), ctx.ClientIP()
+		if key, ok := clientFrom(ctx.Request.Context()); ok {
+			bucketKey, client = "client:"+key.ID, key.ID
+		}
+
+		if wait, ok := l.take(bucketKey); !ok {
+			rejectRateLimited(ctx, wait, fmt.Errorf("failed get request within rate limit of client: %s", client))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// LimitLedgers rejects with 429 the request touching a ledger that ran out of tokens, the ledgers
+// being the one of the ledgerId path parameter or, on a route without one, those of a transfer or
+// of the legs of a journal entry. A payload too large to be read is rejected with 413.
+func (l *RateLimiter) LimitLedgers() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if l == nil {
+			ctx.Next()
+			return
+		}
+
+		ledgerIds, err := ledgerIdsOf(ctx)
+		if err != nil {
+			ErrorHandler(ctx, errorStatus(err), fmt.Errorf("failed to rate limit request, got error: %w", err))
+			ctx.Abort()
+			return
+		}
+		if len(ledgerIds) == 0 {
+			ctx.Next()
+			return
+		}
+
+		if wait, ok := l.take(ledgerIds...); !ok {
+			rejectRateLimited(ctx, wait, fmt.Errorf("failed get request within rate limit of ledgers: %s", strings.Join(ledgerIds, ", ")))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// ledgerIdsOf returns the distinct ledgers the request touches, from its path or, for a transfer or
+// journal entry, its payload. A payload that cannot be decoded touches no ledger, for the handler
+// to reject it, while one too large to be read is an error.
+func ledgerIdsOf(ctx *gin.Context) ([]string, error) {
+	if ledgerId := ctx.Param("ledgerId"); ledgerId != "" {
+		return []string{ledgerId}, nil
+	}
+
+	var req struct {
+		SourceLedgerID      string `json:"sourceLedgerId"`
+		DestinationLedgerID string `json:"destinationLedgerId"`
+		Legs                []struct {
+			LedgerID string `json:"ledgerId"`
+		} `json:"legs"`
+	}
+	if err := peekJSON(ctx, &req); err != nil {
+		if errors.Is(err, ErrPayloadTooLarge) {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	ledgerIds := make([]string, 0, 2+len(req.Legs))
+	add := func(ledgerId string) {
+		if ledgerId != "" && !slices.Contains(ledgerIds, ledgerId) {
+			ledgerIds = append(ledgerIds, ledgerId)
+		}
+	}
+	add(req.SourceLedgerID)
+	add(req.DestinationLedgerID)
+	for _, leg := range req.Legs {
+		add(leg.LedgerID)
+	}
+	return ledgerIds, nil
+}
+
+// rejectRateLimited responds 429 with a Retry-After header of the whole seconds until the request
+// can be retried
+func rejectRateLimited(ctx *gin.Context, wait time.Duration, err error) {
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	loggerFrom(ctx.Request.Context()).Warn("rejected rate limited request", zap.Error(err), zap.Int("retryAfter", retryAfter))
+	ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+	ErrorHandler(ctx, http.StatusTooManyRequests, withKind(ErrRateLimited, err))
+	ctx.Abort()
+}
@@ -0,0 +1,106 @@
+package ledger_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// limitedRequest is a request to a rate limited router and the response expected of it
+type limitedRequest struct {
+	method       string
+	path         string
+	key          string
+	body         string
+	expectedCode int
+}
+
+// serveLimited serves the requests in order and checks their responses, with a Retry-After header
+// on each rate limited one
+func serveLimited(t *testing.T, router *gin.Engine, requests []limitedRequest) {
+	for i, r := range requests {
+		req := httptest.NewRequest(r.method, r.path, strings.NewReader(r.body))
+		req.Header.Set(ledger.APIKeyHeader, r.key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, r.expectedCode, w.Code, "request %d: %s", i, w.Body.String())
+		if r.expectedCode == http.StatusTooManyRequests {
+			assert.Equal(t, "10", w.Header().Get("Retry-After"), "request %d", i)
+			assert.Contains(t, w.Body.String(), `"code":"rate_limited"`, "request %d", i)
+		}
+	}
+}
+
+func TestRateLimitClients(t *testing.T) {
+	auth, err := ledger.NewAuth(apiKeys, nil, newConcurrentStore())
+	require.NoError(t, err)
+	limiter := ledger.NewRateLimiter(ledger.RateLimit{Rate: 0.1, Burst: 2})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api := router.Group("", auth.Authenticate(), limiter.LimitClients())
+	api.GET("/ledger/:ledgerId/balance", echoBody)
+
+	serveLimited(t, router, []limitedRequest{
+		{method: http.MethodGet, path: "/ledger/wallet/balance", key: "wallet-key", expectedCode: http.StatusOK},
+		{method: http.MethodGet, path: "/ledger/savings/balance", key: "wallet-key", expectedCode: http.StatusOK},
+		{method: http.MethodGet, path: "/ledger/wallet/balance", key: "wallet-key", expectedCode: http.StatusTooManyRequests},
+		{method: http.MethodGet, path: "/ledger/wallet/balance", key: "admin-key", expectedCode: http.StatusOK},
+	})
+}
+
+func TestRateLimitLedgers(t *testing.T) {
+	limiter := ledger.NewRateLimiter(ledger.RateLimit{Rate: 0.1, Burst: 1})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/ledger/:ledgerId/transaction", limiter.LimitLedgers(), echoBody)
+	router.POST("/transfers", limiter.LimitLedgers(), echoBody)
+	router.POST("/journal-entries", limiter.LimitLedgers(), echoBody)
+
+	serveLimited(t, router, []limitedRequest{
+		{method: http.MethodPost, path: "/ledger/wallet/transaction", body: `{"type": "debit"}`, expectedCode: http.StatusOK},
+		{method: http.MethodPost, path: "/ledger/wallet/transaction", body: `{"type": "debit"}`, expectedCode: http.StatusTooManyRequests},
+		{method: http.MethodPost, path: "/transfers", body: `{"sourceLedgerId": "savings", "destinationLedgerId": "wallet"}`, expectedCode: http.StatusTooManyRequests},
+		// the rejected transfer took no token of savings
+		{method: http.MethodPost, path: "/journal-entries", body: `{"legs": [{"ledgerId": "savings"}, {"ledgerId": "cash"}]}`, expectedCode: http.StatusOK},
+		{method: http.MethodPost, path: "/ledger/cash/transaction", body: `{"type": "credit"}`, expectedCode: http.StatusTooManyRequests},
+	})
+}
+
+func TestRateLimitLedgersRejectsPayloadOverSizeLimit(t *testing.T) {
+	limiter := ledger.NewRateLimiter(ledger.RateLimit{Rate: 0.1, Burst: 1})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/transfers", limiter.LimitLedgers(), echoBody)
+
+	body := `{"sourceLedgerId": "savings", "destinationLedgerId": "wallet", "description": "` + strings.Repeat("x", 1<<20) + `"}`
+	serveLimited(t, router, []limitedRequest{
+		{method: http.MethodPost, path: "/transfers", body: body, expectedCode: http.StatusRequestEntityTooLarge},
+		// the rejected transfer took no token of savings
+		{method: http.MethodPost, path: "/transfers", body: `{"sourceLedgerId": "savings", "destinationLedgerId": "wallet"}`, expectedCode: http.StatusOK},
+	})
+}
+
+func TestNilRateLimiterLetsEveryRequestThrough(t *testing.T) {
+	limiter := ledger.NewRateLimiter(ledger.RateLimit{})
+	assert.Nil(t, limiter)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/ledger/:ledgerId/transaction", limiter.LimitClients(), limiter.LimitLedgers(), echoBody)
+
+	requests := make([]limitedRequest, 0, 5)
+	for range 5 {
+		requests = append(requests, limitedRequest{method: http.MethodPost, path: "/ledger/wallet/transaction", body: `{}`, expectedCode: http.StatusOK})
+	}
+	serveLimited(t, router, requests)
+}
@@ -0,0 +1,196 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// ReversalRequestDTO represents the request payload for reversing a transaction; a zero amount
+// reverses the whole transaction
+type ReversalRequestDTO struct {
+	Description string `json:"description"`
+	Amount      Money  `json:"amount"`
+}
+
+// Reversal represents the compensating journal entry of a reversed transaction
+type Reversal struct {
+	ID          string      `json:"id"`
+	Original    Transaction `json:"original"`
+	Transaction Transaction `json:"transaction"`
+}
+
+// ReverseTransaction posts a compensating journal entry that undoes every leg of the journal entry
+// of the transaction, each leg linked to the transaction it reverses. A partial amount may only be
+// reversed for a two-leg entry. A transaction is reversed at most once, even partially, and a
+// reversal cannot itself be reversed.
+func (s *store) ReverseTransaction(ctx context.Context, ledgerId string, transactionId string, rrd ReversalRequestDTO) (Reversal, error) {
+	return retryStale(func() (Reversal, error) {
+		return s.reverseTransaction(ctx, ledgerId, transactionId, rrd)
+	})
+}
+
+// reverseTransaction reverses the transaction
+func (s *store) reverseTransaction(ctx context.Context, ledgerId string, transactionId string, rrd ReversalRequestDTO) (Reversal, error) {
+	entryId, err := s.journalEntryOf(ledgerId, transactionId)
+	if err != nil {
+		return Reversal{}, fmt.Errorf("failed to reverse transaction, got error : %w", err)
+	}
+
+	// the ledgers of a journal entry never change, so they can be read before locking
+	s.mu.RLock()
+	ledgerIds := s.entries[entryId]
+	s.mu.RUnlock()
+
+	unlock := s.lockLedgers(ledgerIds...)
+	defer unlock()
+
+	reversal, err := s.reverseLocked(ledgerId, transactionId, entryId, ledgerIds, rrd)
+	if err != nil {
+		return Reversal{}, fmt.Errorf("failed to reverse transaction, got error : %w", err)
+	}
+
+	loggerFrom(ctx).Info("reversed transaction",
+		zap.String("ledgerId", ledgerId),
+		zap.String("transactionId", transactionId),
+		zap.String("reversalId", reversal.Transaction.ID),
+		zap.Stringer("amount", reversal.Transaction.Amount))
+	return reversal, nil
+}
+
+// reverseLocked builds and commits the reversal of the transaction. The caller must hold the locks
+// of the ledgers of its journal entry.
+func (s *store) reverseLocked(ledgerId, transactionId, entryId string, ledgerIds []string, rrd ReversalRequestDTO) (Reversal, error) {
+	originals, err := s.entryTransactions(entryId, ledgerIds)
+	if err != nil {
+		return Reversal{}, err
+	}
+
+	var original Transaction
+	for _, tx := range originals {
+		if tx.ReversalOf != "" {
+			return Reversal{}, withKind(ErrConflict, fmt.Errorf("failed to reverse a reversal, got transaction %s reversing %s", tx.ID, tx.ReversalOf))
+		}
+		if tx.ReversedBy != "" {
+			return Reversal{}, withKind(ErrConflict, fmt.Errorf("failed to reverse transaction %s again, got reversal %s", tx.ID, tx.ReversedBy))
+		}
+		if tx.ID == transactionId {
+			original = tx
+		}
+	}
+
+	amount := rrd.Amount
+	if amount.IsZero() {
+		amount = original.Amount
+	}
+	if !amount.IsPositive() || amount.Cmp(original.Amount) > 0 {
+		return Reversal{}, withKind(ErrValidation, fmt.Errorf("failed get reversal amount, got %s outside of 0 and %s", amount, original.Amount))
+	}
+	partial := amount.Cmp(original.Amount) != 0
+	if partial && len(originals) != 2 {
+		return Reversal{}, withKind(ErrValidation, fmt.Errorf("failed to partially reverse journal entry with %d legs", len(originals)))
+	}
+
+	description := rrd.Description
+	if description == "" {
+		description = fmt.Sprintf("reversal of %s", original.ID)
+	}
+
+	ledger, err := s.getLedger(ledgerId)
+	if err != nil {
+		return Reversal{}, err
+	}
+
+	legs := make([]LegDTO, 0, len(originals))
+	for _, tx := range originals {
+		leg := LegDTO{LedgerID: tx.LedgerID, Type: Credit, Amount: tx.Amount}
+		if tx.Type == Credit {
+			leg.Type = Debit
+		}
+		if partial {
+			leg.Amount = amount
+		}
+		legs = append(legs, leg)
+	}
+
+	// prepareEntry builds the transactions in the order of the legs, so the i-th reverses the i-th original
+	next := 0
+	entry, err := s.prepareEntry(JournalEntryRequestDTO{
+		Description: description,
+		Currency:    ledger.Currency,
+		Legs:        legs,
+	}, func(tx *Transaction) {
+		tx.ReversalOf = originals[next].ID
+		next++
+	})
+	if err != nil {
+		return Reversal{}, err
+	}
+
+	if err := s.commit(change{Kind: changeJournalEntry, Entry: &entry}); err != nil {
+		return Reversal{}, err
+	}
+
+	reversal := Reversal{ID: entry.ID, Original: original}
+	for _, tx := range entry.Transactions {
+		if tx.ReversalOf == transactionId {
+			reversal.Transaction = tx
+		}
+	}
+	reversal.Original.ReversedBy = reversal.Transaction.ID
+	return reversal, nil
+}
+
+// journalEntryOf returns the id of the journal entry of the transaction on the ledger
+func (s *store) journalEntryOf(ledgerId, transactionId string) (string, error) {
+	unlock := s.lockLedgers(ledgerId)
+	defer unlock()
+
+	ledger, err := s.getLedger(ledgerId)
+	if err != nil {
+		return "", err
+	}
+
+	i := indexOfTransaction(ledger.Transactions, transactionId)
+	if i < 0 {
+		return "", withKind(ErrNotFound, fmt.Errorf("failed get transaction %s on ledger %s", transactionId, ledgerId))
+	}
+
+	entryId := ledger.Transactions[i].JournalEntryID
+	if entryId == "" {
+		return "", withKind(ErrValidation, fmt.Errorf("failed get journal entry of transaction %s", transactionId))
+	}
+
+	return entryId, nil
+}
+
+// entryTransactions returns the transactions of the journal entry on the given ledgers, each with
+// the reversal linked to it. The caller must hold the locks of those ledgers.
+func (s *store) entryTransactions(entryId string, ledgerIds []string) ([]Transaction, error) {
+	var transactions []Transaction
+	for _, id := range ledgerIds {
+		ledger, err := s.getLedger(id)
+		if err != nil {
+			return nil, err
+		}
+
+		// the legs of an entry are appended together, so they are contiguous in the history
+		found := false
+		for i := len(ledger.Transactions) - 1; i >= 0; i-- {
+			tx := ledger.Transactions[i]
+			if tx.JournalEntryID != entryId {
+				if found {
+					break
+				}
+				continue
+			}
+
+			found = true
+			tx.ReversedBy = ledger.reversedBy[tx.ID]
+			transactions = append(transactions, tx)
+		}
+	}
+
+	return transactions, nil
+}
@@ -0,0 +1,210 @@
+package ledger_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreReverseTransaction(t *testing.T) {
+	tests := []struct {
+		name            string
+		txType          ledger.TransactionType
+		amount          string
+		reversal        ledger.ReversalRequestDTO
+		expectedBalance string
+		expectError     bool
+	}{
+		{
+			name:            "Full reversal of credit",
+			txType:          ledger.Credit,
+			amount:          "30",
+			expectedBalance: "100",
+		},
+		{
+			name:            "Partial reversal of credit",
+			txType:          ledger.Credit,
+			amount:          "30",
+			reversal:        ledger.ReversalRequestDTO{Amount: ledger.MustParseMoney("12.5")},
+			expectedBalance: "117.5",
+		},
+		{
+			name:            "Full reversal of debit",
+			txType:          ledger.Debit,
+			amount:          "40",
+			expectedBalance: "100",
+		},
+		{
+			name:            "Partial reversal of debit",
+			txType:          ledger.Debit,
+			amount:          "40",
+			reversal:        ledger.ReversalRequestDTO{Description: "refund", Amount: ledger.MustParseMoney("15")},
+			expectedBalance: "75",
+		},
+		{
+			name:            "Reversal above the amount of the transaction returns error",
+			txType:          ledger.Debit,
+			amount:          "40",
+			reversal:        ledger.ReversalRequestDTO{Amount: ledger.MustParseMoney("40.01")},
+			expectedBalance: "60",
+			expectError:     true,
+		},
+		{
+			name:            "Reversal with too many decimal places returns error",
+			txType:          ledger.Debit,
+			amount:          "40",
+			reversal:        ledger.ReversalRequestDTO{Amount: ledger.MustParseMoney("0.001")},
+			expectedBalance: "60",
+			expectError:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storeInstance := newFundedStore(t)
+			trd := ledger.TransactionRequestDTO{
+				Type: tt.txType, Description: "payment", Currency: "EUR", Amount: ledger.MustParseMoney(tt.amount),
+			}
+			var original ledger.Transaction
+			var err error
+			if tt.txType == ledger.Credit {
+				original, err = storeInstance.Credit(context.Background(), "wallet", trd)
+			} else {
+				original, err = storeInstance.Debit(context.Background(), "wallet", trd)
+			}
+			require.NoError(t, err)
+
+			reversal, err := storeInstance.ReverseTransaction(context.Background(), "wallet", original.ID, tt.reversal)
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, original.ID, reversal.Original.ID)
+				assert.Equal(t, reversal.Transaction.ID, reversal.Original.ReversedBy)
+				assert.Equal(t, original.ID, reversal.Transaction.ReversalOf)
+				assert.NotEqual(t, original.Type, reversal.Transaction.Type)
+				assert.Equal(t, reversal.ID, reversal.Transaction.JournalEntryID)
+			}
+
+			assert.Equal(t, tt.expectedBalance, assertConsistentHistory(t, storeInstance, "wallet").String())
+			assertConsistentHistory(t, storeInstance, "counter-eur")
+		})
+	}
+}
+
+func TestStoreReverseTransactionLinksStatements(t *testing.T) {
+	storeInstance := newFundedStore(t)
+	debit, err := storeInstance.Debit(context.Background(), "wallet", ledger.TransactionRequestDTO{
+		Type: ledger.Debit, Description: "payment", Currency: "EUR", Amount: ledger.MustParseMoney("40"),
+	})
+	require.NoError(t, err)
+
+	reversal, err := storeInstance.ReverseTransaction(context.Background(), "wallet", debit.ID, ledger.ReversalRequestDTO{})
+	require.NoError(t, err)
+	assert.Equal(t, "reversal of "+debit.ID, reversal.Transaction.Description)
+
+	wallet := allTransactions(t, storeInstance, "wallet")
+	require.Len(t, wallet, 3)
+	assert.Equal(t, reversal.Transaction.ID, wallet[1].ReversedBy)
+	assert.Equal(t, debit.ID, wallet[2].ReversalOf)
+	assert.Empty(t, wallet[0].ReversedBy)
+
+	// the counter account leg of the original entry is reversed by the other leg of the reversal
+	counter := allTransactions(t, storeInstance, "counter-eur")
+	require.Len(t, counter, 3)
+	assert.Equal(t, counter[2].ID, counter[1].ReversedBy)
+	assert.Equal(t, counter[1].ID, counter[2].ReversalOf)
+	assert.Equal(t, reversal.ID, counter[2].JournalEntryID)
+}
+
+func TestStoreReverseTransactionOnlyOnce(t *testing.T) {
+	storeInstance := newFundedStore(t)
+	debit, err := storeInstance.Debit(context.Background(), "wallet", ledger.TransactionRequestDTO{
+		Type: ledger.Debit, Description: "payment", Currency: "EUR", Amount: ledger.MustParseMoney("40"),
+	})
+	require.NoError(t, err)
+
+	reversal, err := storeInstance.ReverseTransaction(context.Background(), "wallet", debit.ID, ledger.ReversalRequestDTO{
+		Amount: ledger.MustParseMoney("10"),
+	})
+	require.NoError(t, err)
+
+	_, err = storeInstance.ReverseTransaction(context.Background(), "wallet", debit.ID, ledger.ReversalRequestDTO{
+		Amount: ledger.MustParseMoney("10"),
+	})
+	assert.Error(t, err, "partially reversed transaction is reversed again")
+
+	counter := allTransactions(t, storeInstance, "counter-eur")
+	_, err = storeInstance.ReverseTransaction(context.Background(), "counter-eur", counter[1].ID, ledger.ReversalRequestDTO{})
+	assert.Error(t, err, "other leg of reversed entry is reversed")
+
+	_, err = storeInstance.ReverseTransaction(context.Background(), "wallet", reversal.Transaction.ID, ledger.ReversalRequestDTO{})
+	assert.Error(t, err, "reversal is reversed")
+
+	_, err = storeInstance.ReverseTransaction(context.Background(), "wallet", "unknown", ledger.ReversalRequestDTO{})
+	assert.Error(t, err, "unknown transaction is reversed")
+
+	_, err = storeInstance.ReverseTransaction(context.Background(), "counter-eur", debit.ID, ledger.ReversalRequestDTO{})
+	assert.Error(t, err, "transaction is reversed on another ledger")
+
+	assert.Equal(t, "70", assertConsistentHistory(t, storeInstance, "wallet").String())
+}
+
+func TestStoreReverseTransfer(t *testing.T) {
+	storeInstance := newConcurrentStore("alice", "bob")
+	_, err := storeInstance.Credit(context.Background(), "alice", ledger.TransactionRequestDTO{
+		Type: ledger.Credit, Description: "opening", Currency: "EUR", Amount: ledger.MustParseMoney("100"),
+	})
+	require.NoError(t, err)
+	transfer, err := storeInstance.Transfer(context.Background(), ledger.TransferRequestDTO{
+		SourceLedgerID: "alice", DestinationLedgerID: "bob", Description: "rent", Currency: "EUR", Amount: ledger.MustParseMoney("30"),
+	})
+	require.NoError(t, err)
+
+	// the transfer is reversed from the credited side
+	reversal, err := storeInstance.ReverseTransaction(context.Background(), "bob", transfer.Credit.ID, ledger.ReversalRequestDTO{
+		Amount: ledger.MustParseMoney("20"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ledger.Debit, reversal.Transaction.Type)
+
+	assert.Equal(t, "90", assertConsistentHistory(t, storeInstance, "alice").String())
+	assert.Equal(t, "10", assertConsistentHistory(t, storeInstance, "bob").String())
+	alice := allTransactions(t, storeInstance, "alice")
+	assert.Equal(t, transfer.Debit.ID, alice[len(alice)-1].ReversalOf)
+}
+
+func TestStoreReverseJournalEntry(t *testing.T) {
+	storeInstance := newConcurrentStore("wallet", "merchant", "fees")
+	for _, id := range []string{"wallet", "merchant", "fees"} {
+		_, err := storeInstance.Credit(context.Background(), id, ledger.TransactionRequestDTO{
+			Type: ledger.Credit, Description: "opening", Currency: "EUR", Amount: ledger.MustParseMoney("100"),
+		})
+		require.NoError(t, err)
+	}
+	entry, err := storeInstance.Post(context.Background(), ledger.JournalEntryRequestDTO{
+		Description: "purchase",
+		Currency:    "EUR",
+		Legs: []ledger.LegDTO{
+			{LedgerID: "wallet", Type: ledger.Debit, Amount: ledger.MustParseMoney("50")},
+			{LedgerID: "merchant", Type: ledger.Credit, Amount: ledger.MustParseMoney("48")},
+			{LedgerID: "fees", Type: ledger.Credit, Amount: ledger.MustParseMoney("2")},
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = storeInstance.ReverseTransaction(context.Background(), "wallet", entry.Transactions[0].ID, ledger.ReversalRequestDTO{
+		Amount: ledger.MustParseMoney("25"),
+	})
+	assert.Error(t, err, "entry with more than two legs is partially reversed")
+
+	_, err = storeInstance.ReverseTransaction(context.Background(), "fees", entry.Transactions[2].ID, ledger.ReversalRequestDTO{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "100", assertConsistentHistory(t, storeInstance, "wallet").String())
+	assert.Equal(t, "100", assertConsistentHistory(t, storeInstance, "merchant").String())
+	assert.Equal(t, "100", assertConsistentHistory(t, storeInstance, "fees").String())
+}
@@ -0,0 +1,859 @@
+package ledger
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+
+	// registers the pure Go "sqlite" driver
+	_ "modernc.org/sqlite"
+)
+
+// sqlDialect holds what differs between the supported databases
+type sqlDialect struct {
+	// lockRows is appended to a select of rows about to be updated to lock them until commit
+	lockRows string
+	// singleWriter limits the pool to one connection for databases allowing a single writer
+	singleWriter bool
+	// readOnly makes the session it runs in read-only
+	readOnly string
+}
+
+// sqlDialectFor returns the dialect of the database/sql driver
+func sqlDialectFor(driver string) (sqlDialect, error) {
+	switch driver {
+	case "sqlite":
+		// SQLite locks the whole database for the write transaction instead of rows
+		return sqlDialect{singleWriter: true, readOnly: "PRAGMA query_only = ON"}, nil
+	case "pgx", "postgres":
+		return sqlDialect{lockRows: " FOR UPDATE", readOnly: "SET SESSION CHARACTERISTICS AS TRANSACTION READ ONLY"}, nil
+	default:
+		return sqlDialect{}, fmt.Errorf("failed get supported sql driver, got %q", driver)
+	}
+}
+
+// sqlChangeLog is a changeLog writing every change to a relational database in one database
+// transaction, so that ledgers can be queried and backed up with standard SQL tooling
+type sqlChangeLog struct {
+	db      *sql.DB
+	dialect sqlDialect
+	// readOnly is set for a database opened to be read, which records nothing
+	readOnly bool
+}
+
+// NewSQLStore creates a store that writes every change to the database before applying it and
+// loads the ledgers from the database on startup. Schema migrations are applied first and the
+// ledgers passed in, such as counter accounts, are added to the database if missing. The "sqlite"
+// driver is built in; a PostgreSQL driver registered as "pgx" or "postgres" uses the same schema.
+func NewSQLStore(uuid UUIDGenerator, ledgers map[string]*Ledger, opts StoreOptions, driver, dsn string) (Store, error) {
+	return newSQLStore(uuid, ledgers, opts, driver, dsn, false)
+}
+
+// NewReadOnlySQLStore creates a store over the ledgers held in the database through a single
+// read-only session: the schema must be migrated already, the configured ledgers the database does
+// not hold are not added and every change is rejected
+func NewReadOnlySQLStore(uuid UUIDGenerator, ledgers map[string]*Ledger, opts StoreOptions, driver, dsn string) (Store, error) {
+	return newSQLStore(uuid, ledgers, opts, driver, dsn, true)
+}
+
+// newSQLStore creates a store over the database, read-only if readOnly is set
+func newSQLStore(uuid UUIDGenerator, ledgers map[string]*Ledger, opts StoreOptions, driver, dsn string, readOnly bool) (Store, error) {
+	dialect, err := sqlDialectFor(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database, got error : %w", err)
+	}
+	if dialect.singleWriter || readOnly {
+		db.SetMaxOpenConns(1)
+	}
+	if readOnly {
+		if _, err := db.ExecContext(context.Background(), dialect.readOnly); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to make database session read-only, got error : %w", err)
+		}
+	}
+
+	s, err := loadSQLStore(uuid, ledgers, opts, &sqlChangeLog{db: db, dialect: dialect, readOnly: readOnly})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// loadSQLStore migrates the database and creates a store over the ledgers held in it, rebuilding
+// each ledger from its events. A read-only database is only checked to be migrated.
+func loadSQLStore(uuid UUIDGenerator, ledgers map[string]*Ledger, opts StoreOptions, changes *sqlChangeLog) (*store, error) {
+	ctx := context.Background()
+	s := newStore(uuid, make(map[string]*Ledger), opts, changes)
+	if changes.readOnly {
+		if err := checkMigrated(ctx, changes.db); err != nil {
+			return nil, err
+		}
+		ledgers = nil
+	} else {
+		if err := migrate(ctx, changes.db); err != nil {
+			return nil, fmt.Errorf("failed to migrate database, got error : %w", err)
+		}
+		if err := changes.backfillEvents(ctx, s.defaultPolicy); err != nil {
+			return nil, fmt.Errorf("failed to backfill ledger events, got error : %w", err)
+		}
+	}
+
+	for id, ledger := range ledgers {
+		configured := ledger.metadata()
+		if configured.Status == "" {
+			configured.Status = LedgerOpen
+		}
+		if configured.Policy.Type == "" {
+			configured.Policy = s.defaultPolicy(id)
+		}
+		if err := changes.insertConfiguredLedger(ctx, configured); err != nil {
+			return nil, fmt.Errorf("failed to add configured ledger %s, got error : %w", id, err)
+		}
+	}
+
+	events, err := changes.loadEvents(ctx, s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ledger events, got error : %w", err)
+	}
+
+	if err := changes.loadIdempotencyKeys(ctx, s.ledgers, s.idempotency); err != nil {
+		return nil, fmt.Errorf("failed to load idempotency keys, got error : %w", err)
+	}
+
+	zap.L().Info("loaded ledgers from database", zap.Int("ledgers", len(s.ledgers)), zap.Int("events", events))
+	return s, nil
+}
+
+// append writes the change and its events in one database transaction
+func (l *sqlChangeLog) append(c change, events []Event) error {
+	if l.readOnly {
+		return errReadOnly
+	}
+	ctx := context.Background()
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := l.checkVersions(ctx, tx, events); err != nil {
+		return err
+	}
+
+	switch c.Kind {
+	case changeLedgerCreated:
+		err = insertLedger(ctx, tx, c.Ledger)
+	case changeLedgerClosed:
+		err = closeLedgerRow(ctx, tx, c.Ledger)
+	case changeLedgerPolicySet:
+		err = setLedgerPolicyRow(ctx, tx, c.Ledger)
+	case changeJournalEntry:
+		err = l.insertJournalEntry(ctx, tx, c.Entry, c.Idempotency)
+	case changeHoldPlaced:
+		err = insertHold(ctx, tx, c.Hold)
+	case changeHoldCaptured:
+		if err = l.insertJournalEntry(ctx, tx, c.Entry, nil); err == nil {
+			err = closeHoldRow(ctx, tx, c.Hold)
+		}
+	case changeHoldReleased, changeHoldExpired:
+		err = closeHoldRow(ctx, tx, c.Hold)
+	default:
+		err = fmt.Errorf("failed get known change kind, got %q", c.Kind)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := insertEvents(ctx, tx, events); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// checkVersions locks the rows of the ledgers the events follow on, in id order, and checks the
+// database holds no event of those ledgers after the version each was at when the change was built.
+// It fails the change with errStaleLedger when another writer recorded such an event.
+func (l *sqlChangeLog) checkVersions(ctx context.Context, tx *sql.Tx, events []Event) error {
+	built := make(map[string]int64)
+	for _, e := range events {
+		if _, ok := built[e.LedgerID]; !ok && e.Version > 1 {
+			built[e.LedgerID] = e.Version - 1
+		}
+	}
+
+	ids := make([]string, 0, len(built))
+	for id := range built {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	for _, id := range ids {
+		var locked string
+		if err := tx.QueryRowContext(ctx, `SELECT id FROM ledgers WHERE id = $1`+l.dialect.lockRows, id).Scan(&locked); err != nil {
+			return fmt.Errorf("failed to lock ledger %s, got error : %w", id, err)
+		}
+
+		var recorded int64
+		if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM ledger_events WHERE ledger_id = $1`, id).Scan(&recorded); err != nil {
+			return err
+		}
+		if recorded != built[id] {
+			return withKind(errStaleLedger, withKind(ErrConflict,
+				fmt.Errorf("failed to match version %d of ledger %s, got version %d recorded", built[id], id, recorded)))
+		}
+	}
+
+	return nil
+}
+
+// reload applies the events recorded on the ledger after its version in the store, along with the
+// idempotency keys of their transactions, or loads the ledger with the event opening it when the
+// store does not hold it yet. The caller must hold the lock of a ledger the store holds.
+func (l *sqlChangeLog) reload(ctx context.Context, s *store, ledgerId string) error {
+	ledger, err := s.getLedger(ledgerId)
+	if err != nil {
+		return l.loadOpenedLedger(ctx, s, ledgerId)
+	}
+
+	events, err := l.eventsAfter(ctx, ledgerId, ledger.Version)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range events {
+		if err := s.applyEvent(e); err != nil {
+			return fmt.Errorf("failed to apply event %d of ledger %s, got error : %w", e.Version, e.LedgerID, err)
+		}
+
+		if e.Transaction == nil || e.Transaction.IdempotencyKey == "" {
+			continue
+		}
+		var fingerprint string
+		var createdAt int64
+		err := l.db.QueryRowContext(ctx, `SELECT fingerprint, created_at FROM idempotency_keys
+WHERE ledger_id = $1 AND idempotency_key = $2 AND transaction_id = $3`,
+			ledgerId, e.Transaction.IdempotencyKey, e.Transaction.ID).Scan(&fingerprint, &createdAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		s.idempotency.remember(ledgerId, e.Transaction.IdempotencyKey, fingerprint, *e.Transaction, time.UnixMilli(createdAt))
+	}
+
+	if len(events) > 0 {
+		zap.L().Info("reloaded ledger changed by another writer", zap.String("ledgerId", ledgerId), zap.Int("events", len(events)))
+	}
+	return nil
+}
+
+// loadOpenedLedger adds the ledger another writer created to the store with the event opening it,
+// unless no writer created it
+func (l *sqlChangeLog) loadOpenedLedger(ctx context.Context, s *store, ledgerId string) error {
+	events, err := l.eventsAfter(ctx, ledgerId, 0)
+	if err != nil || len(events) == 0 {
+		return err
+	}
+
+	s.opening.Lock()
+	defer s.opening.Unlock()
+	if _, err := s.getLedger(ledgerId); err == nil {
+		return nil
+	}
+	return s.applyEvent(events[0])
+}
+
+// eventsAfter reads the events of the ledger after the version in version order
+func (l *sqlChangeLog) eventsAfter(ctx context.Context, ledgerId string, version int64) ([]Event, error) {
+	rows, err := l.db.QueryContext(ctx, `SELECT payload, unhashed FROM ledger_events WHERE ledger_id = $1 AND version > $2 ORDER BY version`,
+		ledgerId, version)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var payload string
+		var unhashed bool
+		if err := rows.Scan(&payload, &unhashed); err != nil {
+			return nil, err
+		}
+
+		var e Event
+		if err := json.Unmarshal([]byte(payload), &e); err != nil {
+			return nil, fmt.Errorf("failed to decode event, got error : %w", err)
+		}
+		e.unhashed = unhashed
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+// holdLedger returns the ledger of the hold, empty when no writer placed it
+func (l *sqlChangeLog) holdLedger(ctx context.Context, holdId string) (string, error) {
+	var ledgerId string
+	err := l.db.QueryRowContext(ctx, `SELECT ledger_id FROM holds WHERE id = $1`, holdId).Scan(&ledgerId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return ledgerId, err
+}
+
+// transactions returns the transactions of the ledger from the transactions table in posting
+// order, up to count and the ones other writers recorded since the ledger was loaded excluded
+func (l *sqlChangeLog) transactions(ctx context.Context, ledgerId string, count int) ([]Transaction, bool, error) {
+	rows, err := l.db.QueryContext(ctx, `SELECT `+transactionColumns+` FROM transactions
+WHERE ledger_id = $1 AND seq <= $2 ORDER BY seq`, ledgerId, count)
+	if err != nil {
+		return nil, true, err
+	}
+	defer rows.Close()
+
+	var transactions []Transaction
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			return nil, true, err
+		}
+		transactions = append(transactions, t)
+	}
+
+	return transactions, true, rows.Err()
+}
+
+// ping checks that the database is reachable and accepts writes, by updating no row of ledgers in
+// a database transaction rolled back afterwards
+func (l *sqlChangeLog) ping(ctx context.Context) error {
+	if err := l.db.PingContext(ctx); err != nil {
+		return err
+	}
+
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `UPDATE ledgers SET version = version WHERE 1 = 0`)
+	return err
+}
+
+// close closes the database
+func (l *sqlChangeLog) close() error {
+	return l.db.Close()
+}
+
+// insertConfiguredLedger adds the configured ledger and the event opening it unless the database
+// already holds them
+func (l *sqlChangeLog) insertConfiguredLedger(ctx context.Context, ledger Ledger) error {
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO ledgers (id, type, currency, status, created_at, closed_at, policy_type, overdraft_limit)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (id) DO NOTHING`,
+		ledger.ID, ledger.Type, string(ledger.Currency), string(ledger.Status), ledger.CreatedAt, nullInt64(ledger.ClosedAt),
+		string(ledger.Policy.Type), ledger.Policy.OverdraftLimit.units)
+	if err != nil {
+		return err
+	}
+
+	opened := historyEvents(ledger, nil, nil)[0]
+	payload, err := json.Marshal(opened)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO ledger_events (ledger_id, version, type, occurred_at, payload)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (ledger_id, version) DO NOTHING`,
+		opened.LedgerID, opened.Version, string(opened.Type), opened.Date, string(payload))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertLedger adds a newly created ledger
+func insertLedger(ctx context.Context, tx *sql.Tx, ledger *Ledger) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO ledgers (id, type, currency, status, created_at, closed_at, policy_type, overdraft_limit)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		ledger.ID, ledger.Type, string(ledger.Currency), string(ledger.Status), ledger.CreatedAt, nullInt64(ledger.ClosedAt),
+		string(ledger.Policy.Type), ledger.Policy.OverdraftLimit.units)
+	return err
+}
+
+// closeLedgerRow marks the open ledger as closed
+func closeLedgerRow(ctx context.Context, tx *sql.Tx, ledger *Ledger) error {
+	result, err := tx.ExecContext(ctx, `UPDATE ledgers SET status = $1, closed_at = $2 WHERE id = $3 AND status = $4`,
+		string(LedgerClosed), ledger.ClosedAt, ledger.ID, string(LedgerOpen))
+	if err != nil {
+		return err
+	}
+
+	return expectOneRow(result, "ledger "+ledger.ID)
+}
+
+// setLedgerPolicyRow stores the new balance policy of the ledger
+func setLedgerPolicyRow(ctx context.Context, tx *sql.Tx, ledger *Ledger) error {
+	result, err := tx.ExecContext(ctx, `UPDATE ledgers SET policy_type = $1, overdraft_limit = $2 WHERE id = $3`,
+		string(ledger.Policy.Type), ledger.Policy.OverdraftLimit.units, ledger.ID)
+	if err != nil {
+		return err
+	}
+
+	return expectOneRow(result, "ledger "+ledger.ID)
+}
+
+// insertJournalEntry locks the rows of the ledgers the entry touches, in id order, and checks each
+// stored balance is the one the entry was built on before inserting its transactions. The check
+// fails the entry when another writer to the database changed one of the ledgers.
+func (l *sqlChangeLog) insertJournalEntry(ctx context.Context, tx *sql.Tx, entry *JournalEntry, idempotency *idempotentRequest) error {
+	opening := make(map[string]Money)
+	closing := make(map[string]Money)
+	for _, t := range entry.Transactions {
+		if _, ok := opening[t.LedgerID]; !ok {
+			if t.Type == Credit {
+				opening[t.LedgerID] = t.RunningBalance.Sub(t.Amount)
+			} else {
+				opening[t.LedgerID] = t.RunningBalance.Add(t.Amount)
+			}
+		}
+		closing[t.LedgerID] = t.RunningBalance
+	}
+
+	ids := make([]string, 0, len(opening))
+	for id := range opening {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	versions := make(map[string]int64, len(ids))
+	for _, id := range ids {
+		var balance, version int64
+		err := tx.QueryRowContext(ctx, `SELECT balance, version FROM ledgers WHERE id = $1`+l.dialect.lockRows, id).Scan(&balance, &version)
+		if err != nil {
+			return fmt.Errorf("failed to lock ledger %s, got error : %w", id, err)
+		}
+
+		if stored := (Money{units: balance}); stored.Cmp(opening[id]) != 0 {
+			return withKind(ErrConflict, fmt.Errorf("failed to match stored balance %s of ledger %s, got %s", stored, id, opening[id]))
+		}
+		versions[id] = version
+	}
+
+	_, err := tx.ExecContext(ctx, `INSERT INTO journal_entries (id, posted_at, description, currency) VALUES ($1, $2, $3, $4)`,
+		entry.ID, entry.Date, entry.Description, string(entry.Currency))
+	if err != nil {
+		return err
+	}
+
+	for _, t := range entry.Transactions {
+		versions[t.LedgerID]++
+		_, err := tx.ExecContext(ctx, `INSERT INTO transactions
+(id, ledger_id, seq, journal_entry_id, posted_at, type, description, amount, running_balance, transfer_id, idempotency_key, reversal_of, hash)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+			t.ID, t.LedgerID, versions[t.LedgerID], t.JournalEntryID, t.Date, string(t.Type), t.Description,
+			t.Amount.units, t.RunningBalance.units, nullString(t.TransferID), nullString(t.IdempotencyKey), nullString(t.ReversalOf), t.Hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, id := range ids {
+		result, err := tx.ExecContext(ctx, `UPDATE ledgers SET balance = $1, version = $2 WHERE id = $3`,
+			closing[id].units, versions[id], id)
+		if err != nil {
+			return err
+		}
+		if err := expectOneRow(result, "ledger "+id); err != nil {
+			return err
+		}
+	}
+
+	if idempotency == nil {
+		return nil
+	}
+
+	for _, t := range entry.Transactions {
+		if t.LedgerID != idempotency.LedgerID {
+			continue
+		}
+
+		// an expired key may still be stored until the next startup prunes it
+		_, err := tx.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE ledger_id = $1 AND idempotency_key = $2`,
+			idempotency.LedgerID, idempotency.Key)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO idempotency_keys (ledger_id, idempotency_key, fingerprint, transaction_id, created_at)
+VALUES ($1, $2, $3, $4, $5)`,
+			idempotency.LedgerID, idempotency.Key, idempotency.Fingerprint, t.ID, entry.Date)
+		return err
+	}
+
+	return nil
+}
+
+// insertHold adds a newly placed hold
+func insertHold(ctx context.Context, tx *sql.Tx, hold *Hold) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO holds (id, ledger_id, description, currency, amount, status, created_at, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		hold.ID, hold.LedgerID, hold.Description, string(hold.Currency), hold.Amount.units, string(hold.Status), hold.CreatedAt, hold.ExpiresAt)
+	return err
+}
+
+// closeHoldRow records the active hold as captured, released or expired
+func closeHoldRow(ctx context.Context, tx *sql.Tx, hold *Hold) error {
+	result, err := tx.ExecContext(ctx, `UPDATE holds SET status = $1, closed_at = $2, captured_amount = $3, transaction_id = $4
+WHERE id = $5 AND status = $6`,
+		string(hold.Status), hold.ClosedAt, hold.CapturedAmount.units, nullString(hold.TransactionID), hold.ID, string(HoldActive))
+	if err != nil {
+		return err
+	}
+
+	return expectOneRow(result, "hold "+hold.ID)
+}
+
+// insertEvents appends the events to the event streams of their ledgers. The primary key on the
+// ledger and version fails the events when another writer already recorded the same versions.
+func insertEvents(ctx context.Context, tx *sql.Tx, events []Event) error {
+	for _, e := range events {
+		payload, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO ledger_events (ledger_id, version, type, occurred_at, payload, unhashed)
+VALUES ($1, $2, $3, $4, $5, $6)`,
+			e.LedgerID, e.Version, string(e.Type), e.Date, string(payload), e.unhashed)
+		if err != nil {
+			return fmt.Errorf("failed to record event %d of ledger %s, got error : %w", e.Version, e.LedgerID, err)
+		}
+	}
+
+	return nil
+}
+
+// loadEvents adds every ledger to the store from its events and snapshots and returns how many
+// events it read
+func (l *sqlChangeLog) loadEvents(ctx context.Context, s *store) (int, error) {
+	rows, err := l.db.QueryContext(ctx, `SELECT payload, unhashed FROM ledger_events ORDER BY ledger_id, version`)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	count := 0
+	streams := make(map[string][]Event)
+	for rows.Next() {
+		var payload string
+		var unhashed bool
+		if err := rows.Scan(&payload, &unhashed); err != nil {
+			return count, err
+		}
+
+		var e Event
+		if err := json.Unmarshal([]byte(payload), &e); err != nil {
+			return count, fmt.Errorf("failed to decode event, got error : %w", err)
+		}
+		e.unhashed = unhashed
+		streams[e.LedgerID] = append(streams[e.LedgerID], e)
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+
+	snapshots, err := l.loadSnapshots(ctx)
+	if err != nil {
+		return count, fmt.Errorf("failed to load ledger snapshots, got error : %w", err)
+	}
+
+	return count, s.loadLedgers(streams, snapshots)
+}
+
+// loadSnapshots returns the snapshots of every ledger in version order
+func (l *sqlChangeLog) loadSnapshots(ctx context.Context) (map[string][]LedgerState, error) {
+	rows, err := l.db.QueryContext(ctx, `SELECT payload FROM ledger_snapshots ORDER BY ledger_id, version`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := make(map[string][]LedgerState)
+	for rows.Next() {
+		var payload string
+		if err := rows.Scan(&payload); err != nil {
+			return nil, err
+		}
+
+		var snapshot LedgerState
+		if err := json.Unmarshal([]byte(payload), &snapshot); err != nil {
+			return nil, fmt.Errorf("failed to decode snapshot, got error : %w", err)
+		}
+		snapshots[snapshot.Ledger.ID] = append(snapshots[snapshot.Ledger.ID], snapshot)
+	}
+
+	return snapshots, rows.Err()
+}
+
+// recordSnapshot stores the snapshot unless another writer to the database stored it already
+func (l *sqlChangeLog) recordSnapshot(snapshot LedgerState) error {
+	if l.readOnly {
+		return errReadOnly
+	}
+
+	payload, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	_, err = l.db.ExecContext(context.Background(), `INSERT INTO ledger_snapshots (ledger_id, version, payload)
+VALUES ($1, $2, $3)
+ON CONFLICT (ledger_id, version) DO NOTHING`,
+		snapshot.Ledger.ID, snapshot.Ledger.Version, string(payload))
+	return err
+}
+
+// backfillEvents records the history of every ledger of a database created before ledger events
+// were recorded, recreated from the ledgers, transactions and holds tables
+func (l *sqlChangeLog) backfillEvents(ctx context.Context, defaultPolicy func(ledgerId string) BalancePolicy) error {
+	var recorded int64
+	if err := l.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM ledger_events`).Scan(&recorded); err != nil {
+		return err
+	}
+	if recorded > 0 {
+		return nil
+	}
+
+	ledgers, err := l.loadLedgers(ctx)
+	if err != nil {
+		return err
+	}
+	if len(ledgers) == 0 {
+		return nil
+	}
+
+	holds, err := l.loadHolds(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ids := make([]string, 0, len(ledgers))
+	for id := range ledgers {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	backfilled := 0
+	for _, id := range ids {
+		ledger := ledgers[id]
+		if ledger.Policy.Type == "" {
+			ledger.Policy = defaultPolicy(id)
+		}
+
+		events := historyEvents(ledger.metadata(), ledger.Transactions, holds[id])
+		if err := insertEvents(ctx, tx, events); err != nil {
+			return err
+		}
+		backfilled += len(events)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	zap.L().Info("backfilled ledger events", zap.Int("ledgers", len(ledgers)), zap.Int("events", backfilled))
+	return nil
+}
+
+// loadHolds reads every hold, grouped by ledger in the order they were placed
+func (l *sqlChangeLog) loadHolds(ctx context.Context) (map[string][]Hold, error) {
+	rows, err := l.db.QueryContext(ctx, `SELECT id, ledger_id, description, currency, amount, status, created_at, expires_at,
+closed_at, captured_amount, transaction_id FROM holds ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holds := make(map[string][]Hold)
+	for rows.Next() {
+		var hold Hold
+		var amount, capturedAmount int64
+		var closedAt sql.NullInt64
+		var transactionId sql.NullString
+		err := rows.Scan(&hold.ID, &hold.LedgerID, &hold.Description, &hold.Currency, &amount, &hold.Status, &hold.CreatedAt,
+			&hold.ExpiresAt, &closedAt, &capturedAmount, &transactionId)
+		if err != nil {
+			return nil, err
+		}
+		hold.Amount, hold.CapturedAmount = Money{units: amount}, Money{units: capturedAmount}
+		hold.ClosedAt, hold.TransactionID = closedAt.Int64, transactionId.String
+
+		holds[hold.LedgerID] = append(holds[hold.LedgerID], hold)
+	}
+
+	return holds, rows.Err()
+}
+
+// loadLedgers reads every ledger with its transactions in posting order from the tables kept
+// alongside the events
+func (l *sqlChangeLog) loadLedgers(ctx context.Context) (map[string]*Ledger, error) {
+	rows, err := l.db.QueryContext(ctx, `SELECT id, type, currency, status, created_at, closed_at, policy_type, overdraft_limit FROM ledgers`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ledgers := make(map[string]*Ledger)
+	for rows.Next() {
+		var ledger Ledger
+		var closedAt sql.NullInt64
+		var overdraftLimit int64
+		err := rows.Scan(&ledger.ID, &ledger.Type, &ledger.Currency, &ledger.Status, &ledger.CreatedAt, &closedAt,
+			&ledger.Policy.Type, &overdraftLimit)
+		if err != nil {
+			return nil, err
+		}
+		ledger.ClosedAt = closedAt.Int64
+		ledger.Policy.OverdraftLimit = Money{units: overdraftLimit}
+		ledgers[ledger.ID] = &ledger
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	txRows, err := l.db.QueryContext(ctx, `SELECT `+transactionColumns+` FROM transactions ORDER BY ledger_id, seq`)
+	if err != nil {
+		return nil, err
+	}
+	defer txRows.Close()
+
+	for txRows.Next() {
+		t, err := scanTransaction(txRows)
+		if err != nil {
+			return nil, err
+		}
+
+		ledger, ok := ledgers[t.LedgerID]
+		if !ok {
+			return nil, fmt.Errorf("failed get ledger: %s", t.LedgerID)
+		}
+		ledger.Transactions = append(ledger.Transactions, t)
+	}
+
+	return ledgers, txRows.Err()
+}
+
+// loadIdempotencyKeys prunes the expired idempotency keys and remembers the others along with
+// the transaction, already loaded into ledgers, each one produced
+func (l *sqlChangeLog) loadIdempotencyKeys(ctx context.Context, ledgers map[string]*Ledger, keys *idempotencyKeys) error {
+	expired := time.Now().Add(-keys.retention).UTC().UnixMilli()
+	if !l.readOnly {
+		if _, err := l.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE created_at <= $1`, expired); err != nil {
+			return err
+		}
+	}
+
+	transactions := make(map[string]Transaction)
+	for _, ledger := range ledgers {
+		for _, t := range ledger.Transactions {
+			if t.IdempotencyKey != "" {
+				transactions[t.ID] = t
+			}
+		}
+	}
+
+	rows, err := l.db.QueryContext(ctx, `SELECT ledger_id, idempotency_key, fingerprint, transaction_id, created_at
+FROM idempotency_keys ORDER BY created_at`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ledgerId, key, fingerprint, transactionId string
+		var createdAt int64
+		if err := rows.Scan(&ledgerId, &key, &fingerprint, &transactionId, &createdAt); err != nil {
+			return err
+		}
+
+		t, ok := transactions[transactionId]
+		if !ok {
+			return fmt.Errorf("failed get transaction %s of idempotency key %s", transactionId, key)
+		}
+		keys.remember(ledgerId, key, fingerprint, t, time.UnixMilli(createdAt))
+	}
+
+	return rows.Err()
+}
+
+// transactionColumns are the columns scanned by scanTransaction
+const transactionColumns = `id, ledger_id, journal_entry_id, posted_at, type, description, amount, running_balance, transfer_id, idempotency_key, reversal_of, hash`
+
+// scanTransaction scans a row of transactionColumns
+func scanTransaction(rows *sql.Rows) (Transaction, error) {
+	var t Transaction
+	var transferId, idempotencyKey, reversalOf, hash sql.NullString
+	var amount, runningBalance int64
+	err := rows.Scan(&t.ID, &t.LedgerID, &t.JournalEntryID, &t.Date, &t.Type, &t.Description,
+		&amount, &runningBalance, &transferId, &idempotencyKey, &reversalOf, &hash)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	t.Amount, t.RunningBalance = Money{units: amount}, Money{units: runningBalance}
+	t.TransferID, t.IdempotencyKey, t.ReversalOf = transferId.String, idempotencyKey.String, reversalOf.String
+	t.Hash = hash.String
+	return t, nil
+}
+
+// nullString stores an empty string as NULL
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// nullInt64 stores a zero as NULL
+func nullInt64(value int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: value, Valid: value != 0}
+}
+
+// expectOneRow checks the statement changed exactly the one row it targets
+func expectOneRow(result sql.Result, target string) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected != 1 {
+		return fmt.Errorf("failed to update %s, got %d rows", target, affected)
+	}
+	return nil
+}
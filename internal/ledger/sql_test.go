@@ -0,0 +1,554 @@
+package ledger_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openSQLStore opens the SQLite store at dsn with a EUR counter account
+func openSQLStore(t *testing.T, dsn string) ledger.Store {
+	storeInstance, err := ledger.NewSQLStore(ledger.NewUUIDGenerator(), newLedgersWithCounterAccount(), counterAccountOptions, "sqlite", dsn)
+	require.NoError(t, err)
+	return storeInstance
+}
+
+// sqliteDSN returns the DSN of a new SQLite database in a temporary directory
+func sqliteDSN(t *testing.T) string {
+	return "file:" + filepath.Join(t.TempDir(), "ledger.db") + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+}
+
+func TestSQLStoreLoadsChangesAfterRestart(t *testing.T) {
+	dsn := sqliteDSN(t)
+	storeInstance := openSQLStore(t, dsn)
+
+	wallet, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "cash", Currency: "EUR"})
+	require.NoError(t, err)
+	savings, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "savings", Currency: "EUR"})
+	require.NoError(t, err)
+
+	deposit := ledger.TransactionRequestDTO{
+		Type:           ledger.Credit,
+		Description:    "deposit",
+		Currency:       "EUR",
+		Amount:         ledger.MustParseMoney("100"),
+		IdempotencyKey: "payment-1",
+	}
+	credit, err := storeInstance.Credit(context.Background(), wallet.ID, deposit)
+	require.NoError(t, err)
+	_, err = storeInstance.Transfer(context.Background(), ledger.TransferRequestDTO{
+		SourceLedgerID:      wallet.ID,
+		DestinationLedgerID: savings.ID,
+		Description:         "save",
+		Currency:            "EUR",
+		Amount:              ledger.MustParseMoney("40.5"),
+	})
+	require.NoError(t, err)
+	closed, err := storeInstance.CloseLedger(context.Background(), savings.ID)
+	require.NoError(t, err)
+	overdrawn, err := storeInstance.SetBalancePolicy(context.Background(), wallet.ID, ledger.BalancePolicy{
+		Type: ledger.PolicyOverdraft, OverdraftLimit: ledger.MustParseMoney("25"),
+	})
+	require.NoError(t, err)
+	captured, err := storeInstance.PlaceHold(context.Background(), wallet.ID, holdFor("10"))
+	require.NoError(t, err)
+	capture, err := storeInstance.CaptureHold(context.Background(), captured.ID, ledger.CaptureRequestDTO{})
+	require.NoError(t, err)
+	_, err = storeInstance.ReverseTransaction(context.Background(), wallet.ID, capture.Transaction.ID, ledger.ReversalRequestDTO{
+		Amount: ledger.MustParseMoney("2"),
+	})
+	require.NoError(t, err)
+	active, err := storeInstance.PlaceHold(context.Background(), wallet.ID, holdFor("9.5"))
+	require.NoError(t, err)
+
+	statements := make(map[string]ledger.Statement)
+	events := make(map[string]ledger.EventPage)
+	for _, id := range []string{wallet.ID, savings.ID, "counter-eur"} {
+		statements[id], err = storeInstance.GetTransactionHistory(context.Background(), id, ledger.StatementQuery{})
+		require.NoError(t, err)
+		events[id], err = storeInstance.GetLedgerEvents(context.Background(), id, ledger.EventQuery{})
+		require.NoError(t, err)
+	}
+	walletBeforeRestart, err := storeInstance.GetLedger(context.Background(), wallet.ID)
+	require.NoError(t, err)
+	require.NoError(t, storeInstance.Close())
+
+	restarted := openSQLStore(t, dsn)
+	defer restarted.Close()
+
+	for id, expected := range statements {
+		statement, err := restarted.GetTransactionHistory(context.Background(), id, ledger.StatementQuery{})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, statement, id)
+
+		page, err := restarted.GetLedgerEvents(context.Background(), id, ledger.EventQuery{})
+		assert.NoError(t, err)
+		assert.Equal(t, events[id], page, id)
+	}
+
+	reopened, err := restarted.GetLedger(context.Background(), savings.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, closed, reopened)
+	reloaded, err := restarted.GetLedger(context.Background(), wallet.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, walletBeforeRestart, reloaded)
+	assert.Equal(t, overdrawn.Policy, reloaded.Policy)
+	counter, err := restarted.GetLedger(context.Background(), "counter-eur")
+	assert.NoError(t, err)
+	assert.Equal(t, ledger.PolicyUnlimited, counter.Policy.Type)
+
+	replayed, err := restarted.Credit(context.Background(), wallet.ID, deposit)
+	assert.NoError(t, err)
+	assert.Equal(t, credit, replayed)
+
+	balance, err := restarted.GetLastBalance(context.Background(), wallet.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "51.5", balance.Balance.String())
+	assert.Equal(t, "42", balance.Available.String())
+
+	_, err = restarted.ReverseTransaction(context.Background(), wallet.ID, capture.Transaction.ID, ledger.ReversalRequestDTO{})
+	assert.Error(t, err)
+
+	hold, err := restarted.GetHold(context.Background(), captured.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, ledger.HoldCaptured, hold.Status)
+	_, err = restarted.ReleaseHold(context.Background(), active.ID)
+	assert.NoError(t, err)
+}
+
+func TestSQLStoreRestoresLedgersFromRecordedSnapshots(t *testing.T) {
+	dsn := sqliteDSN(t)
+	opts := counterAccountOptions
+	opts.SnapshotInterval = 2
+	open := func() ledger.Store {
+		storeInstance, err := ledger.NewSQLStore(ledger.NewUUIDGenerator(), newLedgersWithCounterAccount(), opts, "sqlite", dsn)
+		require.NoError(t, err)
+		return storeInstance
+	}
+
+	storeInstance := open()
+	wallet, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "cash", Currency: "EUR"})
+	require.NoError(t, err)
+	for range 3 {
+		_, err := storeInstance.Credit(context.Background(), wallet.ID, ledger.TransactionRequestDTO{
+			Type: ledger.Credit, Description: "deposit", Currency: "EUR", Amount: ledger.MustParseMoney("5"),
+		})
+		require.NoError(t, err)
+	}
+	_, err = storeInstance.PlaceHold(context.Background(), wallet.ID, holdFor("2"))
+	require.NoError(t, err)
+	states := ledgerStates(t, storeInstance, wallet.ID)
+	require.NoError(t, storeInstance.Close())
+
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	var versions []int64
+	rows, err := db.Query(`SELECT version FROM ledger_snapshots WHERE ledger_id = $1 ORDER BY version`, wallet.ID)
+	require.NoError(t, err)
+	for rows.Next() {
+		var version int64
+		require.NoError(t, rows.Scan(&version))
+		versions = append(versions, version)
+	}
+	require.NoError(t, rows.Err())
+	require.NoError(t, db.Close())
+	assert.Equal(t, []int64{2, 4}, versions)
+
+	restarted := open()
+	defer restarted.Close()
+	assert.Equal(t, states, ledgerStates(t, restarted, wallet.ID))
+	verification, err := restarted.VerifyLedger(context.Background(), wallet.ID)
+	assert.NoError(t, err)
+	assert.True(t, verification.Verified)
+}
+
+func TestSQLStoreSchemaIsQueryable(t *testing.T) {
+	dsn := sqliteDSN(t)
+	storeInstance := openSQLStore(t, dsn)
+	wallet, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "cash", Currency: "EUR"})
+	require.NoError(t, err)
+	_, err = storeInstance.Credit(context.Background(), wallet.ID, ledger.TransactionRequestDTO{
+		Type: ledger.Credit, Description: "deposit", Currency: "EUR", Amount: ledger.MustParseMoney("12.5"),
+	})
+	require.NoError(t, err)
+	require.NoError(t, storeInstance.Close())
+
+	// reopening must not apply the migrations again
+	require.NoError(t, openSQLStore(t, dsn).Close())
+
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	files, err := os.ReadDir("migrations")
+	require.NoError(t, err)
+	var migrations int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&migrations))
+	assert.Equal(t, len(files), migrations)
+
+	var balance, version int64
+	require.NoError(t, db.QueryRow(`SELECT balance, version FROM ledgers WHERE id = $1`, wallet.ID).Scan(&balance, &version))
+	assert.Equal(t, int64(125000), balance)
+	assert.Equal(t, int64(1), version)
+
+	var transactions int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM transactions t JOIN journal_entries j ON j.id = t.journal_entry_id`).Scan(&transactions))
+	assert.Equal(t, 2, transactions)
+
+	var events int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM ledger_events WHERE ledger_id = $1`, wallet.ID).Scan(&events))
+	assert.Equal(t, 2, events)
+}
+
+func TestSQLStoreBackfillsEventsOfEarlierDatabase(t *testing.T) {
+	dsn := sqliteDSN(t)
+	storeInstance := openSQLStore(t, dsn)
+	wallet, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "cash", Currency: "EUR"})
+	require.NoError(t, err)
+	_, err = storeInstance.Credit(context.Background(), wallet.ID, ledger.TransactionRequestDTO{
+		Type: ledger.Credit, Description: "deposit", Currency: "EUR", Amount: ledger.MustParseMoney("100"),
+	})
+	require.NoError(t, err)
+	captured, err := storeInstance.PlaceHold(context.Background(), wallet.ID, holdFor("10"))
+	require.NoError(t, err)
+	_, err = storeInstance.CaptureHold(context.Background(), captured.ID, ledger.CaptureRequestDTO{})
+	require.NoError(t, err)
+	active, err := storeInstance.PlaceHold(context.Background(), wallet.ID, holdFor("5"))
+	require.NoError(t, err)
+	statement, err := storeInstance.GetTransactionHistory(context.Background(), wallet.ID, ledger.StatementQuery{})
+	require.NoError(t, err)
+	require.NoError(t, storeInstance.Close())
+
+	// a database created before ledger events were recorded holds none
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	_, err = db.Exec(`DELETE FROM ledger_events`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	restarted := openSQLStore(t, dsn)
+	assert.Equal(t, []ledger.EventType{
+		ledger.EventLedgerOpened,
+		ledger.EventCredited,
+		ledger.EventDebited,
+		ledger.EventHoldPlaced,
+		ledger.EventHoldCaptured,
+		ledger.EventHoldPlaced,
+	}, eventTypes(t, restarted, wallet.ID))
+
+	backfilled, err := restarted.GetTransactionHistory(context.Background(), wallet.ID, ledger.StatementQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, statement, backfilled)
+	balance, err := restarted.GetLastBalance(context.Background(), wallet.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "90", balance.Balance.String())
+	assert.Equal(t, "85", balance.Available.String())
+	counter, err := restarted.GetLedger(context.Background(), "counter-eur")
+	assert.NoError(t, err)
+	assert.Equal(t, ledger.PolicyUnlimited, counter.Policy.Type)
+
+	_, err = restarted.ReleaseHold(context.Background(), active.ID)
+	assert.NoError(t, err)
+	require.NoError(t, restarted.Close())
+
+	// the events are only backfilled once
+	reopened := openSQLStore(t, dsn)
+	defer reopened.Close()
+	reloaded, err := reopened.GetLedger(context.Background(), wallet.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), reloaded.Version)
+}
+
+func TestSQLStoreChainsTransactionsOfEarlierDatabase(t *testing.T) {
+	dsn := sqliteDSN(t)
+	storeInstance := openSQLStore(t, dsn)
+	wallet, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "cash", Currency: "EUR"})
+	require.NoError(t, err)
+	for _, amount := range []string{"10", "20"} {
+		_, err := storeInstance.Credit(context.Background(), wallet.ID, ledger.TransactionRequestDTO{
+			Type: ledger.Credit, Description: "deposit", Currency: "EUR", Amount: ledger.MustParseMoney(amount),
+		})
+		require.NoError(t, err)
+	}
+	transactions := allTransactions(t, storeInstance, wallet.ID)
+	require.NoError(t, storeInstance.Close())
+
+	// a database created before transactions were chained holds no hashes, and migrating it marks
+	// the events of its transactions unhashed
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec(`UPDATE transactions SET hash = NULL`)
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE ledger_events SET payload = json_remove(payload, '$.transaction.hash'), unhashed = TRUE
+WHERE type IN ('credited', 'debited')`)
+	require.NoError(t, err)
+
+	restarted := openSQLStore(t, dsn)
+	defer restarted.Close()
+	assert.Equal(t, transactions, allTransactions(t, restarted, wallet.ID))
+	_, err = restarted.Credit(context.Background(), wallet.ID, ledger.TransactionRequestDTO{
+		Type: ledger.Credit, Description: "deposit", Currency: "EUR", Amount: ledger.MustParseMoney("30"),
+	})
+	require.NoError(t, err)
+	verification, err := restarted.VerifyLedger(context.Background(), wallet.ID)
+	assert.NoError(t, err)
+	assert.True(t, verification.Verified)
+	assert.Equal(t, 3, verification.Checked)
+	assert.Equal(t, 2, verification.Unhashed)
+
+	// the hashes chained on load are not written back
+	var unhashed int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM transactions WHERE hash IS NULL`).Scan(&unhashed))
+	assert.Equal(t, 4, unhashed)
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM ledger_events
+WHERE type IN ('credited', 'debited') AND json_extract(payload, '$.transaction.hash') IS NULL`).Scan(&unhashed))
+	assert.Equal(t, 4, unhashed)
+}
+
+func TestSQLStoreDetectsTransactionsStrippedOfHashes(t *testing.T) {
+	dsn := sqliteDSN(t)
+	storeInstance := openSQLStore(t, dsn)
+	wallet, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "cash", Currency: "EUR"})
+	require.NoError(t, err)
+	for _, amount := range []string{"10", "20"} {
+		_, err := storeInstance.Credit(context.Background(), wallet.ID, ledger.TransactionRequestDTO{
+			Type: ledger.Credit, Description: "deposit", Currency: "EUR", Amount: ledger.MustParseMoney(amount),
+		})
+		require.NoError(t, err)
+	}
+	transactions := allTransactions(t, storeInstance, wallet.ID)
+	require.NoError(t, storeInstance.Close())
+
+	// stripping every hash does not pass the altered transactions off as recorded before chaining
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE transactions SET hash = NULL, description = 'refund'`)
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE ledger_events SET payload = json_set(json_remove(payload, '$.transaction.hash'), '$.transaction.description', 'refund')
+WHERE type IN ('credited', 'debited')`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	restarted := openSQLStore(t, dsn)
+	defer restarted.Close()
+	verification, err := restarted.VerifyLedger(context.Background(), wallet.ID)
+	require.NoError(t, err)
+	assert.False(t, verification.Verified)
+	assert.Zero(t, verification.Checked)
+	assert.Zero(t, verification.Unhashed)
+	require.NotNil(t, verification.BrokenLink)
+	assert.Equal(t, 1, verification.BrokenLink.Sequence)
+	assert.Equal(t, transactions[0].ID, verification.BrokenLink.TransactionID)
+	assert.Empty(t, verification.BrokenLink.RecordedHash)
+}
+
+func TestSQLStoreDetectsUnhashedTransactionAfterHashedOne(t *testing.T) {
+	dsn := sqliteDSN(t)
+	storeInstance := openSQLStore(t, dsn)
+	wallet, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "cash", Currency: "EUR"})
+	require.NoError(t, err)
+	for _, amount := range []string{"10", "20", "30"} {
+		_, err := storeInstance.Credit(context.Background(), wallet.ID, ledger.TransactionRequestDTO{
+			Type: ledger.Credit, Description: "deposit", Currency: "EUR", Amount: ledger.MustParseMoney(amount),
+		})
+		require.NoError(t, err)
+	}
+	transactions := allTransactions(t, storeInstance, wallet.ID)
+	require.NoError(t, storeInstance.Close())
+
+	// dropping the hash of an altered transaction does not pass it off as recorded before chaining
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE transactions SET hash = NULL, description = 'refund' WHERE id = $1`, transactions[2].ID)
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE ledger_events SET payload = json_set(json_remove(payload, '$.transaction.hash'), '$.transaction.description', 'refund')
+WHERE ledger_id = $1 AND json_extract(payload, '$.transaction.id') = $2`, wallet.ID, transactions[2].ID)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	restarted := openSQLStore(t, dsn)
+	defer restarted.Close()
+	verification, err := restarted.VerifyLedger(context.Background(), wallet.ID)
+	require.NoError(t, err)
+	assert.False(t, verification.Verified)
+	assert.Equal(t, 2, verification.Checked)
+	assert.Zero(t, verification.Unhashed)
+	require.NotNil(t, verification.BrokenLink)
+	assert.Equal(t, 3, verification.BrokenLink.Sequence)
+	assert.Equal(t, transactions[2].ID, verification.BrokenLink.TransactionID)
+	assert.Empty(t, verification.BrokenLink.RecordedHash)
+}
+
+func TestSQLStoreDetectsAlteredTransactionRow(t *testing.T) {
+	dsn := sqliteDSN(t)
+	storeInstance := openSQLStore(t, dsn)
+	wallet, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "cash", Currency: "EUR"})
+	require.NoError(t, err)
+	for _, amount := range []string{"10", "20", "30"} {
+		_, err := storeInstance.Credit(context.Background(), wallet.ID, ledger.TransactionRequestDTO{
+			Type: ledger.Credit, Description: "deposit", Currency: "EUR", Amount: ledger.MustParseMoney(amount),
+		})
+		require.NoError(t, err)
+	}
+	transactions := allTransactions(t, storeInstance, wallet.ID)
+	require.NoError(t, storeInstance.Close())
+
+	// the events stay untouched, so the chain holds while the table queried for statements differs
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE transactions SET description = 'refund' WHERE id = $1`, transactions[1].ID)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	restarted := openSQLStore(t, dsn)
+	defer restarted.Close()
+	verification, err := restarted.VerifyLedger(context.Background(), wallet.ID)
+	require.NoError(t, err)
+	assert.False(t, verification.Verified)
+	assert.Equal(t, 1, verification.Checked)
+	require.NotNil(t, verification.BrokenLink)
+	assert.Equal(t, "transactions", verification.BrokenLink.Table)
+	assert.Equal(t, 2, verification.BrokenLink.Sequence)
+	assert.Equal(t, transactions[1].ID, verification.BrokenLink.TransactionID)
+	assert.Equal(t, transactions[1].Hash, verification.BrokenLink.ExpectedHash)
+	assert.NotEqual(t, transactions[1].Hash, verification.BrokenLink.RecordedHash)
+}
+
+func TestReadOnlySQLStoreRejectsChanges(t *testing.T) {
+	dsn := sqliteDSN(t)
+	storeInstance := openSQLStore(t, dsn)
+	wallet, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "cash", Currency: "EUR"})
+	require.NoError(t, err)
+	require.NoError(t, storeInstance.Close())
+
+	readOnly, err := ledger.NewReadOnlySQLStore(ledger.NewUUIDGenerator(), newLedgersWithCounterAccount(), counterAccountOptions, "sqlite", dsn)
+	require.NoError(t, err)
+	defer readOnly.Close()
+	verification, err := readOnly.VerifyLedger(context.Background(), wallet.ID)
+	require.NoError(t, err)
+	assert.True(t, verification.Verified)
+	_, err = readOnly.Credit(context.Background(), wallet.ID, ledger.TransactionRequestDTO{
+		Type: ledger.Credit, Description: "deposit", Currency: "EUR", Amount: ledger.MustParseMoney("5"),
+	})
+	assert.Error(t, err)
+
+	_, err = ledger.NewReadOnlySQLStore(ledger.NewUUIDGenerator(), newLedgersWithCounterAccount(), counterAccountOptions, "sqlite", sqliteDSN(t))
+	assert.Error(t, err)
+}
+
+func TestSQLStoreDetectsAlteredTransaction(t *testing.T) {
+	dsn := sqliteDSN(t)
+	storeInstance := openSQLStore(t, dsn)
+	wallet, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "cash", Currency: "EUR"})
+	require.NoError(t, err)
+	for _, amount := range []string{"10", "20", "30"} {
+		_, err := storeInstance.Credit(context.Background(), wallet.ID, ledger.TransactionRequestDTO{
+			Type: ledger.Credit, Description: "deposit", Currency: "EUR", Amount: ledger.MustParseMoney(amount),
+		})
+		require.NoError(t, err)
+	}
+	transactions := allTransactions(t, storeInstance, wallet.ID)
+	require.NoError(t, storeInstance.Close())
+
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	result, err := db.Exec(`UPDATE ledger_events SET payload = json_set(payload, '$.transaction.description', 'refund')
+WHERE ledger_id = $1 AND json_extract(payload, '$.transaction.id') = $2`, wallet.ID, transactions[2].ID)
+	require.NoError(t, err)
+	altered, err := result.RowsAffected()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), altered)
+	require.NoError(t, db.Close())
+
+	restarted := openSQLStore(t, dsn)
+	defer restarted.Close()
+	verification, err := restarted.VerifyLedger(context.Background(), wallet.ID)
+	require.NoError(t, err)
+	assert.False(t, verification.Verified)
+	assert.Equal(t, 2, verification.Checked)
+	require.NotNil(t, verification.BrokenLink)
+	assert.Equal(t, 3, verification.BrokenLink.Sequence)
+	assert.Equal(t, transactions[2].ID, verification.BrokenLink.TransactionID)
+	assert.Equal(t, transactions[1].Hash, verification.BrokenLink.PreviousHash)
+}
+
+func TestSQLStoreReloadsLedgerChangedByAnotherWriter(t *testing.T) {
+	dsn := sqliteDSN(t)
+	first := openSQLStore(t, dsn)
+	defer first.Close()
+	wallet, err := first.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "cash", Currency: "EUR"})
+	require.NoError(t, err)
+
+	second := openSQLStore(t, dsn)
+	defer second.Close()
+
+	deposit := ledger.TransactionRequestDTO{
+		Type: ledger.Credit, Description: "deposit", Currency: "EUR", Amount: ledger.MustParseMoney("10"), IdempotencyKey: "payment-1",
+	}
+	credit, err := first.Credit(context.Background(), wallet.ID, deposit)
+	require.NoError(t, err)
+
+	// the second writer reloads the wallet and replays the credit the first one recorded
+	replayed, err := second.Credit(context.Background(), wallet.ID, deposit)
+	assert.NoError(t, err)
+	assert.Equal(t, credit, replayed)
+
+	deposit.IdempotencyKey = "payment-2"
+	_, err = second.Credit(context.Background(), wallet.ID, deposit)
+	assert.NoError(t, err)
+	hold, err := second.PlaceHold(context.Background(), wallet.ID, holdFor("5"))
+	assert.NoError(t, err)
+
+	// the first writer reloads the wallet as it is changed by the second one
+	_, err = first.Debit(context.Background(), wallet.ID, ledger.TransactionRequestDTO{
+		Type: ledger.Debit, Description: "withdrawal", Currency: "EUR", Amount: ledger.MustParseMoney("20"),
+	})
+	assert.ErrorIs(t, err, ledger.ErrInsufficientFunds)
+	_, err = first.ReleaseHold(context.Background(), hold.ID)
+	assert.NoError(t, err)
+
+	for _, storeInstance := range []ledger.Store{first, second} {
+		_, err = storeInstance.Debit(context.Background(), wallet.ID, ledger.TransactionRequestDTO{
+			Type: ledger.Debit, Description: "withdrawal", Currency: "EUR", Amount: ledger.MustParseMoney("5"),
+		})
+		assert.NoError(t, err)
+	}
+
+	for _, storeInstance := range []ledger.Store{first, second} {
+		assert.Equal(t, ledger.MustParseMoney("10"), assertConsistentHistory(t, storeInstance, wallet.ID))
+		assert.Len(t, allTransactions(t, storeInstance, wallet.ID), 4)
+		verification, err := storeInstance.VerifyLedger(context.Background(), wallet.ID)
+		assert.NoError(t, err)
+		assert.True(t, verification.Verified)
+	}
+}
+
+func TestSQLStoreConcurrentCredits(t *testing.T) {
+	storeInstance := openSQLStore(t, sqliteDSN(t))
+	defer storeInstance.Close()
+	wallet, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "cash", Currency: "EUR"})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := storeInstance.Credit(context.Background(), wallet.ID, ledger.TransactionRequestDTO{
+				Type: ledger.Credit, Description: "deposit", Currency: "EUR", Amount: ledger.MustParseMoney("1"),
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, ledger.MustParseMoney("20"), assertConsistentHistory(t, storeInstance, wallet.ID))
+}
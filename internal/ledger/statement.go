@@ -0,0 +1,146 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultStatementPageSize is the number of transactions listed when no limit is requested
+	DefaultStatementPageSize = 100
+	// MaxStatementPageSize is the largest number of transactions listed in a single page
+	MaxStatementPageSize = 500
+)
+
+// ErrUnknownCursor is returned when a statement cursor is not a transaction of the ledger
+var ErrUnknownCursor = errors.New("failed get transaction of the ledger for cursor")
+
+// StatementQuery represents the filters and page of a statement. Zero values leave a filter unset.
+// From is inclusive and To exclusive, both in unix milliseconds like Transaction.Date, and the
+// amount range is inclusive. Cursor is the id of the last transaction of the previous page.
+type StatementQuery struct {
+	From        int64
+	To          int64
+	Type        TransactionType
+	MinAmount   Money
+	MaxAmount   Money
+	Description string
+	Cursor      string
+	Limit       int
+}
+
+// Validate checks the filters of the statement query are consistent
+func (q StatementQuery) Validate() error {
+	if q.From < 0 || q.To < 0 {
+		return errors.New("failed get from and to of at least 0")
+	}
+
+	if q.To != 0 && q.From >= q.To {
+		return errors.New("failed get from before to")
+	}
+
+	if q.Type != "" && q.Type != Credit && q.Type != Debit {
+		return errors.New("failed get transaction type either credit or debit")
+	}
+
+	if q.MinAmount.IsNegative() || q.MaxAmount.IsNegative() {
+		return errors.New("failed get minAmount and maxAmount of at least 0")
+	}
+
+	if !q.MaxAmount.IsZero() && q.MinAmount.Cmp(q.MaxAmount) > 0 {
+		return errors.New("failed get minAmount not greater than maxAmount")
+	}
+
+	return nil
+}
+
+// matches reports whether the transaction passes every filter of the query
+func (q StatementQuery) matches(tx Transaction) bool {
+	if q.From != 0 && tx.Date < q.From {
+		return false
+	}
+	if q.To != 0 && tx.Date >= q.To {
+		return false
+	}
+	if q.Type != "" && tx.Type != q.Type {
+		return false
+	}
+	if !q.MinAmount.IsZero() && tx.Amount.Cmp(q.MinAmount) < 0 {
+		return false
+	}
+	if !q.MaxAmount.IsZero() && tx.Amount.Cmp(q.MaxAmount) > 0 {
+		return false
+	}
+	if q.Description != "" && !strings.Contains(strings.ToLower(tx.Description), strings.ToLower(q.Description)) {
+		return false
+	}
+	return true
+}
+
+// GetTransactionHistory returns a page of the transaction history for ledger in posting order,
+// filtered by the query
+func (s *store) GetTransactionHistory(ctx context.Context, ledgerId string, query StatementQuery) (Statement, error) {
+	if err := query.Validate(); err != nil {
+		return Statement{}, fmt.Errorf("failed to get transaction history, got error : %w", withKind(ErrValidation, err))
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultStatementPageSize
+	}
+	if limit > MaxStatementPageSize {
+		limit = MaxStatementPageSize
+	}
+
+	unlock := s.lockLedgers(ledgerId)
+	defer unlock()
+
+	ledger, _, err := s.getLedgerWithBalance(ledgerId)
+	if err != nil {
+		return Statement{}, fmt.Errorf("failed to get transaction history, got error : %w", err)
+	}
+
+	start := 0
+	if query.Cursor != "" {
+		i := indexOfTransaction(ledger.Transactions, query.Cursor)
+		if i < 0 {
+			return Statement{}, fmt.Errorf("failed to get transaction history, got error : %w: %s", ErrUnknownCursor, query.Cursor)
+		}
+		start = i + 1
+	}
+
+	statement := Statement{
+		LedgerID:     ledger.ID,
+		Currency:     ledger.Currency,
+		Transactions: make([]Transaction, 0, min(limit, len(ledger.Transactions)-start)),
+	}
+	for _, tx := range ledger.Transactions[start:] {
+		if !query.matches(tx) {
+			continue
+		}
+
+		if len(statement.Transactions) == limit {
+			statement.NextCursor = statement.Transactions[limit-1].ID
+			break
+		}
+		tx.ReversedBy = ledger.reversedBy[tx.ID]
+		statement.Transactions = append(statement.Transactions, tx)
+	}
+
+	loggerFrom(ctx).Info("got transaction history for ledger", zap.String("ledgerId", ledgerId), zap.Int("transactions", len(statement.Transactions)))
+	return statement, nil
+}
+
+// indexOfTransaction returns the position of the transaction in the history, or -1 if it is not there
+func indexOfTransaction(transactions []Transaction, transactionId string) int {
+	for i := len(transactions) - 1; i >= 0; i-- {
+		if transactions[i].ID == transactionId {
+			return i
+		}
+	}
+	return -1
+}
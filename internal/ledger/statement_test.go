@@ -0,0 +1,124 @@
+package ledger_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreGetTransactionHistory(t *testing.T) {
+	transactions := []ledger.Transaction{
+		{ID: "tx-1", LedgerID: "wallet", Date: 1000, Type: ledger.Credit, Description: "Salary", Amount: ledger.MustParseMoney("1000")},
+		{ID: "tx-2", LedgerID: "wallet", Date: 2000, Type: ledger.Debit, Description: "Rent March", Amount: ledger.MustParseMoney("500")},
+		{ID: "tx-3", LedgerID: "wallet", Date: 2000, Type: ledger.Debit, Description: "groceries", Amount: ledger.MustParseMoney("45.5")},
+		{ID: "tx-4", LedgerID: "wallet", Date: 3000, Type: ledger.Credit, Description: "refund", Amount: ledger.MustParseMoney("5")},
+		{ID: "tx-5", LedgerID: "wallet", Date: 4000, Type: ledger.Debit, Description: "rent April", Amount: ledger.MustParseMoney("500")},
+	}
+	ledgers := map[string]*ledger.Ledger{
+		"wallet": {ID: "wallet", Type: "cash", Currency: "EUR", Transactions: transactions},
+	}
+	storeInstance := ledger.NewStore(ledger.NewUUIDGenerator(), ledgers, ledger.StoreOptions{})
+
+	tests := []struct {
+		name               string
+		query              ledger.StatementQuery
+		expectedIds        []string
+		expectedNextCursor string
+		expectError        bool
+	}{
+		{
+			name:        "Whole history in posting order",
+			query:       ledger.StatementQuery{},
+			expectedIds: []string{"tx-1", "tx-2", "tx-3", "tx-4", "tx-5"},
+		},
+		{
+			name:               "First page",
+			query:              ledger.StatementQuery{Limit: 2},
+			expectedIds:        []string{"tx-1", "tx-2"},
+			expectedNextCursor: "tx-2",
+		},
+		{
+			name:        "Last page",
+			query:       ledger.StatementQuery{Limit: 3, Cursor: "tx-2"},
+			expectedIds: []string{"tx-3", "tx-4", "tx-5"},
+		},
+		{
+			name:        "Date range includes from and excludes to",
+			query:       ledger.StatementQuery{From: 2000, To: 4000},
+			expectedIds: []string{"tx-2", "tx-3", "tx-4"},
+		},
+		{
+			name:               "Type filter with page",
+			query:              ledger.StatementQuery{Type: ledger.Debit, Limit: 1, Cursor: "tx-2"},
+			expectedIds:        []string{"tx-3"},
+			expectedNextCursor: "tx-3",
+		},
+		{
+			name:        "Amount range is inclusive",
+			query:       ledger.StatementQuery{MinAmount: ledger.MustParseMoney("5"), MaxAmount: ledger.MustParseMoney("500")},
+			expectedIds: []string{"tx-2", "tx-3", "tx-4", "tx-5"},
+		},
+		{
+			name:        "Description substring ignores case",
+			query:       ledger.StatementQuery{Description: "RENT"},
+			expectedIds: []string{"tx-2", "tx-5"},
+		},
+		{
+			name:        "No matching transactions",
+			query:       ledger.StatementQuery{Description: "holiday"},
+			expectedIds: []string{},
+		},
+		{
+			name:        "Unknown cursor returns error",
+			query:       ledger.StatementQuery{Cursor: "tx-unknown"},
+			expectError: true,
+		},
+		{
+			name:        "From after to returns error",
+			query:       ledger.StatementQuery{From: 3000, To: 2000},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			statement, err := storeInstance.GetTransactionHistory(context.Background(), "wallet", tc.query)
+
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			ids := make([]string, 0, len(statement.Transactions))
+			for _, tx := range statement.Transactions {
+				ids = append(ids, tx.ID)
+			}
+			assert.Equal(t, tc.expectedIds, ids)
+			assert.Equal(t, tc.expectedNextCursor, statement.NextCursor)
+		})
+	}
+}
+
+func TestStoreGetTransactionHistoryLimitsPageSize(t *testing.T) {
+	transactions := make([]ledger.Transaction, 0, ledger.MaxStatementPageSize+1)
+	for i := 0; i <= ledger.MaxStatementPageSize; i++ {
+		transactions = append(transactions, ledger.Transaction{ID: fmt.Sprintf("tx-%d", i), LedgerID: "wallet", Type: ledger.Credit})
+	}
+	ledgers := map[string]*ledger.Ledger{
+		"wallet": {ID: "wallet", Type: "cash", Currency: "EUR", Transactions: transactions},
+	}
+	storeInstance := ledger.NewStore(ledger.NewUUIDGenerator(), ledgers, ledger.StoreOptions{})
+
+	statement, err := storeInstance.GetTransactionHistory(context.Background(), "wallet", ledger.StatementQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, statement.Transactions, ledger.DefaultStatementPageSize)
+
+	statement, err = storeInstance.GetTransactionHistory(context.Background(), "wallet", ledger.StatementQuery{Limit: 10 * ledger.MaxStatementPageSize})
+	assert.NoError(t, err)
+	assert.Len(t, statement.Transactions, ledger.MaxStatementPageSize)
+	assert.Equal(t, fmt.Sprintf("tx-%d", ledger.MaxStatementPageSize-1), statement.NextCursor)
+}
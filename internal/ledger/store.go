@@ -2,9 +2,10 @@ package ledger
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"math"
+	"slices"
+	"sort"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -21,134 +22,438 @@ const (
 // Transaction represents a single ledger entry
 type Transaction struct {
 	ID             string          `json:"id"`
+	LedgerID       string          `json:"ledgerId"`
 	Date           int64           `json:"date"`
 	Type           TransactionType `json:"type"`
 	Description    string          `json:"description"`
-	Amount         float64         `json:"amount"`
-	RunningBalance float64         `json:"runningBalance"`
+	Amount         Money           `json:"amount"`
+	RunningBalance Money           `json:"runningBalance"`
+	JournalEntryID string          `json:"journalEntryId"`
+	TransferID     string          `json:"transferId,omitempty"`
+	IdempotencyKey string          `json:"idempotencyKey,omitempty"`
+	// ReversalOf is the id of the transaction this transaction reverses
+	ReversalOf string `json:"reversalOf,omitempty"`
+	// Hash chains the transaction to the previous transaction of its ledger, so that altering any
+	// recorded transaction breaks the chain from there on
+	Hash string `json:"hash,omitempty"`
+	// ReversedBy is the id of the transaction reversing this transaction; it is filled in from the
+	// reversal index of the ledger when read and never recorded with the transaction
+	ReversedBy string `json:"reversedBy,omitempty"`
 }
 
-// Ledger holds the ledger metadata and transaction history
+// LedgerStatus represents whether a ledger accepts new transactions
+type LedgerStatus string
+
+const (
+	LedgerOpen   LedgerStatus = "open"
+	LedgerClosed LedgerStatus = "closed"
+)
+
+// Ledger holds the ledger metadata and transaction history. Version is the number of events in the
+// history of the ledger, from which its state is derived.
 type Ledger struct {
 	ID           string        `json:"id"`
 	Type         string        `json:"type"`
-	Transactions []Transaction `json:"transactions"`
+	Currency     Currency      `json:"currency"`
+	Status       LedgerStatus  `json:"status"`
+	CreatedAt    int64         `json:"createdAt"`
+	ClosedAt     int64         `json:"closedAt,omitempty"`
+	Policy       BalancePolicy `json:"policy"`
+	Version      int64         `json:"version"`
+	Transactions []Transaction `json:"-"`
+	// balance is the posted balance of the ledger
+	balance Money
+	// held is the sum of the active holds on the ledger
+	held Money
+	// reversedBy maps the id of every reversed transaction of the ledger to its reversal
+	reversedBy map[string]string
+	// events is the history of the ledger, the event of version v at index v-1
+	events []Event
+	// snapshots holds the state of the ledger every snapshot interval, in version order
+	snapshots []LedgerState
+	// unhashed is the number of leading transactions recorded before transactions were chained,
+	// which are chained as they are loaded
+	unhashed int
 }
 
-// TransactionRequestDTO represents the request payload for deposit and withdraw operations
+// TransactionRequestDTO represents the request payload for deposit and withdraw operations.
+// IdempotencyKey is taken from the Idempotency-Key request header rather than the payload.
 type TransactionRequestDTO struct {
-	Type        TransactionType `json:"type"`
-	Description string          `json:"description"`
-	Amount      float64         `json:"amount"`
+	Type           TransactionType `json:"type"`
+	Description    string          `json:"description"`
+	Currency       Currency        `json:"currency"`
+	Amount         Money           `json:"amount"`
+	IdempotencyKey string          `json:"-"`
+}
+
+// Balance represents the current balance of a ledger
+type Balance struct {
+	LedgerID string   `json:"ledgerId"`
+	Currency Currency `json:"currency"`
+	Balance  Money    `json:"balance"`
+	// Available is the balance less the active holds; it is only reported for the current balance
+	Available *Money `json:"available,omitempty"`
+	AsOf      int64  `json:"asOf,omitempty"`
+}
+
+// Statement represents one page of the transaction history of a ledger
+type Statement struct {
+	LedgerID     string        `json:"ledgerId"`
+	Currency     Currency      `json:"currency"`
+	Transactions []Transaction `json:"transactions"`
+	NextCursor   string        `json:"nextCursor,omitempty"`
 }
 
 // Store represents the operations on the ledger
 type Store interface {
 	Credit(ctx context.Context, ledgerId string, trd TransactionRequestDTO) (Transaction, error)
 	Debit(ctx context.Context, ledgerId string, trd TransactionRequestDTO) (Transaction, error)
-	GetLastBalance(ctx context.Context, ledgerId string) (float64, error)
-	GetTransactionHistory(ctx context.Context, ledgerId string) ([]Transaction, error)
+	Transfer(ctx context.Context, trd TransferRequestDTO) (Transfer, error)
+	Post(ctx context.Context, jrd JournalEntryRequestDTO) (JournalEntry, error)
+	GetJournalEntry(ctx context.Context, ledgerId string, transactionId string) (JournalEntry, error)
+	CreateLedger(ctx context.Context, lrd LedgerRequestDTO) (Ledger, error)
+	GetLedger(ctx context.Context, ledgerId string) (Ledger, error)
+	ListLedgers(ctx context.Context, query LedgerQuery) (LedgerPage, error)
+	CloseLedger(ctx context.Context, ledgerId string) (Ledger, error)
+	SetBalancePolicy(ctx context.Context, ledgerId string, policy BalancePolicy) (Ledger, error)
+	GetLastBalance(ctx context.Context, ledgerId string) (Balance, error)
+	GetBalanceAsOf(ctx context.Context, ledgerId string, asOf int64) (Balance, error)
+	GetTransactionHistory(ctx context.Context, ledgerId string, query StatementQuery) (Statement, error)
+	PlaceHold(ctx context.Context, ledgerId string, hrd HoldRequestDTO) (Hold, error)
+	GetHold(ctx context.Context, holdId string) (Hold, error)
+	CaptureHold(ctx context.Context, holdId string, crd CaptureRequestDTO) (HoldCapture, error)
+	ReleaseHold(ctx context.Context, holdId string) (Hold, error)
+	ExpireHolds(ctx context.Context) (int, error)
+	ReverseTransaction(ctx context.Context, ledgerId string, transactionId string, rrd ReversalRequestDTO) (Reversal, error)
+	GetLedgerEvents(ctx context.Context, ledgerId string, query EventQuery) (EventPage, error)
+	GetLedgerAtVersion(ctx context.Context, ledgerId string, version int64) (LedgerState, error)
+	Project(ctx context.Context, projection Projection) error
+	VerifyLedger(ctx context.Context, ledgerId string) (ChainVerification, error)
+	Ping(ctx context.Context) error
+	Close() error
 }
 
-// store is our in-memory implementation of Store
+// StoreOptions holds the optional settings of a store
+type StoreOptions struct {
+	// CounterAccounts maps each currency to the ledger that offsets single-sided credits and debits
+	CounterAccounts map[Currency]string
+	// IdempotencyRetention is how long idempotency keys are remembered, DefaultIdempotencyRetention if zero
+	IdempotencyRetention time.Duration
+	// SnapshotInterval is the number of events between two snapshots of a ledger, DefaultSnapshotInterval if zero
+	SnapshotInterval int
+	// Metrics records the domain metrics of the store, none if nil
+	Metrics *Metrics
+}
+
+// store is our implementation of Store and is safe for concurrent use. Ledgers are held in memory
+// and every change is first recorded to changes, which keeps them durable for the file store. The
+// state of each ledger is derived from its events; its transactions are kept for statements.
+// mu guards the ledgers, locks, holds and entries maps only; each ledger's fields, transactions,
+// holds and reversals are guarded by its own lock in locks, so operations on unrelated ledgers do
+// not contend. mu is never held while waiting for a ledger lock.
 type store struct {
-	uuid    UUIDGenerator
+	uuid             UUIDGenerator
+	counterAccounts  map[Currency]string
+	idempotency      *idempotencyKeys
+	changes          changeLog
+	snapshotInterval int
+	metrics          *Metrics
+
+	mu      sync.RWMutex
 	ledgers map[string]*Ledger
+	locks   map[string]*sync.Mutex
+	holds   map[string]*Hold
+	// activeHolds maps the id of every active hold to its expiry, for the sweeper
+	activeHolds map[string]int64
+	// entries maps the id of every journal entry to the ledgers it touches
+	entries map[string][]string
+	// opening serialises loading the ledgers other writers created, so that each is added once
+	opening sync.Mutex
 }
 
-// NewStore creates a new in-memory store instance
-func NewStore(uuid UUIDGenerator, ledgers map[string]*Ledger) Store {
-	return &store{
-		uuid:    uuid,
-		ledgers: ledgers,
+// NewStore creates a new in-memory store instance; ledgers without a status are treated as open
+// and ledgers without a balance policy get the default one
+func NewStore(uuid UUIDGenerator, ledgers map[string]*Ledger, opts StoreOptions) Store {
+	return newStore(uuid, ledgers, opts, memoryChangeLog{})
+}
+
+// newStore creates a store over the given ledgers recording its changes to changes. Each ledger is
+// rebuilt in place from the events recreating its metadata and transactions.
+func newStore(uuid UUIDGenerator, ledgers map[string]*Ledger, opts StoreOptions, changes changeLog) *store {
+	snapshotInterval := opts.SnapshotInterval
+	if snapshotInterval <= 0 {
+		snapshotInterval = DefaultSnapshotInterval
+	}
+
+	s := &store{
+		uuid:             uuid,
+		counterAccounts:  opts.CounterAccounts,
+		idempotency:      newIdempotencyKeys(opts.IdempotencyRetention),
+		changes:          changes,
+		snapshotInterval: snapshotInterval,
+		metrics:          opts.Metrics,
+		ledgers:          ledgers,
+		locks:            make(map[string]*sync.Mutex, len(ledgers)),
+		holds:            make(map[string]*Hold),
+		activeHolds:      make(map[string]int64),
+		entries:          make(map[string][]string),
+	}
+
+	for id, ledger := range ledgers {
+		if ledger.Status == "" {
+			ledger.Status = LedgerOpen
+		}
+		if ledger.Policy.Type == "" {
+			ledger.Policy = s.defaultPolicy(id)
+		}
+
+		events := historyEvents(ledger.metadata(), ledger.Transactions, nil)
+		*ledger = Ledger{}
+		for _, e := range events {
+			if err := s.applyEvent(e); err != nil {
+				// the events are built from the ledger itself, so they always follow on each other
+				panic(fmt.Sprintf("failed to rebuild ledger %s, got error : %s", id, err))
+			}
+		}
 	}
+
+	return s
 }
 
-// Credit adds a credit transaction to the ledger
+// Credit adds a credit transaction to the ledger, balanced by a debit on the counter account
 func (s *store) Credit(ctx context.Context, ledgerId string, trd TransactionRequestDTO) (Transaction, error) {
-	ledger, lastBalance, err := s.getLedgerWithBalance(ledgerId)
+	tx, err := retryStale(func() (Transaction, error) {
+		return s.postAgainstCounterAccount(ctx, ledgerId, Credit, trd)
+	})
 	if err != nil {
 		return Transaction{}, fmt.Errorf("failed to perform credit transaction, got error : %w", err)
 	}
 
-	newBalance := lastBalance + trd.Amount
-	newTransaction := Transaction{
-		ID:             s.uuid.Generate(),
-		Date:           time.Now().UTC().UnixMilli(),
-		Type:           trd.Type,
-		Description:    trd.Description,
-		Amount:         trd.Amount,
-		RunningBalance: round(newBalance, 4),
-	}
-	ledger.Transactions = append(ledger.Transactions, newTransaction)
-	zap.L().Info("credited the ledger", zap.String("ledgerId", ledgerId), zap.Float64("newBalance", newBalance))
-	return newTransaction, nil
+	loggerFrom(ctx).Info("credited the ledger", zap.String("ledgerId", ledgerId), zap.Stringer("newBalance", tx.RunningBalance))
+	return tx, nil
 }
 
-// Debit subtracts an amount from the ledger
+// Debit subtracts an amount from the ledger, balanced by a credit on the counter account
 func (s *store) Debit(ctx context.Context, ledgerId string, trd TransactionRequestDTO) (Transaction, error) {
-	ledger, lastBalance, err := s.getLedgerWithBalance(ledgerId)
+	tx, err := retryStale(func() (Transaction, error) {
+		return s.postAgainstCounterAccount(ctx, ledgerId, Debit, trd)
+	})
 	if err != nil {
 		return Transaction{}, fmt.Errorf("failed to perform debit transaction, got error : %w", err)
 	}
 
-	newBalance := lastBalance - trd.Amount
-	if newBalance <= 0 {
-		return Transaction{}, errors.New("failed to get new balance greater than or equal to 0")
-	}
+	loggerFrom(ctx).Info("debited the ledger", zap.String("ledgerId", ledgerId), zap.Stringer("newBalance", tx.RunningBalance))
+	return tx, nil
+}
+
+// GetLastBalance returns the last balance for ledger
+func (s *store) GetLastBalance(ctx context.Context, ledgerId string) (Balance, error) {
+	unlock := s.lockLedgers(ledgerId)
+	defer unlock()
 
-	newTransaction := Transaction{
-		ID:             s.uuid.Generate(),
-		Date:           time.Now().UTC().UnixMilli(),
-		Type:           trd.Type,
-		Description:    trd.Description,
-		Amount:         trd.Amount,
-		RunningBalance: round(newBalance, 4),
+	ledger, lastBalance, err := s.getLedgerWithBalance(ledgerId)
+	if err != nil {
+		return Balance{}, fmt.Errorf("failed to get last balance, got error : %w", err)
 	}
-	ledger.Transactions = append(ledger.Transactions, newTransaction)
-	zap.L().Info("debited the ledger", zap.String("ledgerId", ledgerId), zap.Float64("newBalance", newBalance))
-	return newTransaction, nil
+
+	available := lastBalance.Sub(ledger.held)
+	loggerFrom(ctx).Info("got last ledger balance", zap.String("ledgerId", ledgerId), zap.Stringer("lastBalance", lastBalance), zap.Stringer("available", available))
+	return Balance{
+		LedgerID:  ledger.ID,
+		Currency:  ledger.Currency,
+		Balance:   lastBalance,
+		Available: &available,
+	}, nil
 }
 
-// GetLastBalance returns the last balance for ledger
-func (s *store) GetLastBalance(ctx context.Context, ledgerId string) (float64, error) {
-	_, lastBalance, err := s.getLedgerWithBalance(ledgerId)
+// GetBalanceAsOf returns the balance of the ledger after its last transaction dated at or before
+// asOf, in unix milliseconds. Transaction dates never decrease along a ledger's history, so the
+// transaction is found by binary search.
+func (s *store) GetBalanceAsOf(ctx context.Context, ledgerId string, asOf int64) (Balance, error) {
+	unlock := s.lockLedgers(ledgerId)
+	defer unlock()
+
+	ledger, err := s.getLedger(ledgerId)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get last balance, got error : %w", err)
+		return Balance{}, fmt.Errorf("failed to get balance as of %d, got error : %w", asOf, err)
 	}
 
-	zap.L().Info("got last ledger balance", zap.String("ledgerId", ledgerId), zap.Float64("lastBalance", lastBalance))
-	return lastBalance, nil
+	var balance Money
+	after := sort.Search(len(ledger.Transactions), func(i int) bool {
+		return ledger.Transactions[i].Date > asOf
+	})
+	if after > 0 {
+		balance = ledger.Transactions[after-1].RunningBalance
+	}
+
+	loggerFrom(ctx).Info("got ledger balance as of date", zap.String("ledgerId", ledgerId), zap.Int64("asOf", asOf), zap.Stringer("balance", balance))
+	return Balance{
+		LedgerID: ledger.ID,
+		Currency: ledger.Currency,
+		Balance:  balance,
+		AsOf:     asOf,
+	}, nil
 }
 
-// GetTransactionHistory returns the transaction history for ledger
-func (s *store) GetTransactionHistory(ctx context.Context, ledgerId string) ([]Transaction, error) {
-	ledger, _, err := s.getLedgerWithBalance(ledgerId)
+// postAgainstCounterAccount records a single-sided credit or debit as a two-leg journal entry
+// and returns the transaction recorded on the requested ledger. A request repeating an earlier
+// idempotency key on the ledger returns the earlier transaction instead of recording a new one.
+func (s *store) postAgainstCounterAccount(ctx context.Context, ledgerId string, txType TransactionType, trd TransactionRequestDTO) (Transaction, error) {
+	ledger, err := s.getLedger(ledgerId)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get transaction history, got error : %w", err)
+		return Transaction{}, err
 	}
 
-	zap.L().Info("got transaction history for ledger", zap.String("ledgerId", ledgerId))
-	return ledger.Transactions, nil
+	if err := validateAmount(ledger, trd.Currency, trd.Amount); err != nil {
+		return Transaction{}, err
+	}
+
+	counterAccountId, err := s.counterAccount(ledger.Currency)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	counterType := Debit
+	if txType == Debit {
+		counterType = Credit
+	}
+
+	unlock := s.lockLedgers(ledgerId, counterAccountId)
+	defer unlock()
+
+	fingerprint := trd.fingerprint(txType)
+	if trd.IdempotencyKey != "" {
+		if record, ok := s.idempotency.lookup(ledgerId, trd.IdempotencyKey, time.Now()); ok {
+			if record.fingerprint != fingerprint {
+				return Transaction{}, ErrIdempotencyConflict
+			}
+
+			loggerFrom(ctx).Info("replayed idempotent transaction", zap.String("ledgerId", ledgerId), zap.String("transactionId", record.transaction.ID))
+			return record.transaction, nil
+		}
+	}
+
+	entry, err := s.prepareEntry(JournalEntryRequestDTO{
+		Description: trd.Description,
+		Currency:    trd.Currency,
+		Legs: []LegDTO{
+			{LedgerID: ledgerId, Type: txType, Amount: trd.Amount},
+			{LedgerID: counterAccountId, Type: counterType, Amount: trd.Amount},
+		},
+	}, func(tx *Transaction) {
+		if tx.LedgerID == ledgerId {
+			tx.IdempotencyKey = trd.IdempotencyKey
+		}
+	})
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	c := change{Kind: changeJournalEntry, Entry: &entry}
+	if trd.IdempotencyKey != "" {
+		c.Idempotency = &idempotentRequest{LedgerID: ledgerId, Key: trd.IdempotencyKey, Fingerprint: fingerprint}
+	}
+	if err := s.commit(c); err != nil {
+		return Transaction{}, err
+	}
+
+	return entry.Transactions[0], nil
+}
+
+// counterAccount returns the id of the counter account configured for the currency
+func (s *store) counterAccount(currency Currency) (string, error) {
+	counterAccountId, ok := s.counterAccounts[currency]
+	if !ok {
+		return "", withKind(ErrValidation, fmt.Errorf("failed get counter account for currency %s", currency))
+	}
+	return counterAccountId, nil
+}
+
+// isCounterAccount reports whether the ledger is one of the configured counter accounts
+func (s *store) isCounterAccount(ledgerId string) bool {
+	for _, id := range s.counterAccounts {
+		if id == ledgerId {
+			return true
+		}
+	}
+	return false
 }
 
-// getLedgerWithBalance retrieves the ledger, last balance by ledgerId
-func (s *store) getLedgerWithBalance(id string) (*Ledger, float64, error) {
-	lastBalance := 0.0
+// lockLedgers acquires the locks of the given ledgers in id order, so that concurrent operations
+// over overlapping sets of ledgers cannot deadlock, and returns a function releasing them. Once
+// locked, the ledgers are brought up to date with the changes other writers to the store recorded.
+// Unknown ledger ids are skipped; looking them up afterwards reports the error.
+func (s *store) lockLedgers(ids ...string) func() {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	// a ledger another writer created has no lock yet, so it is loaded first
+	for _, id := range ids {
+		if _, err := s.getLedger(id); err != nil {
+			s.reloadLedger(id)
+		}
+	}
+
+	s.mu.RLock()
+	locked := make([]string, 0, len(ids))
+	locks := make([]*sync.Mutex, 0, len(ids))
+	for _, id := range ids {
+		if lock, ok := s.locks[id]; ok {
+			locked = append(locked, id)
+			locks = append(locks, lock)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, lock := range locks {
+		lock.Lock()
+	}
+	for _, id := range locked {
+		s.reloadLedger(id)
+	}
+
+	return func() {
+		for i := len(locks) - 1; i >= 0; i-- {
+			locks[i].Unlock()
+		}
+	}
+}
+
+// getLedger retrieves the ledger by ledgerId; only its immutable fields may be read without
+// holding its lock
+func (s *store) getLedger(id string) (*Ledger, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	ledger, exists := s.ledgers[id]
 	if !exists {
-		return nil, lastBalance, fmt.Errorf("failed get ledger: %s", id)
+		return nil, withKind(ErrNotFound, fmt.Errorf("failed get ledger: %s", id))
 	}
 
-	if len(ledger.Transactions) > 0 {
-		lastBalance = ledger.Transactions[len(ledger.Transactions)-1].RunningBalance
+	return ledger, nil
+}
+
+// getLedgerWithBalance retrieves the ledger, last balance by ledgerId; the caller must hold the
+// ledger lock
+func (s *store) getLedgerWithBalance(id string) (*Ledger, Money, error) {
+	ledger, err := s.getLedger(id)
+	if err != nil {
+		return nil, Money{}, err
 	}
 
-	return ledger, lastBalance, nil
+	return ledger, ledger.balance, nil
 }
 
-// round rounds float value to specific precision places
-func round(val float64, precision uint) float64 {
-	ratio := math.Pow(10, float64(precision))
-	return math.Round(val*ratio) / ratio
+// validateAmount checks the requested amount against the currency rules of the ledger
+func validateAmount(ledger *Ledger, currency Currency, amount Money) error {
+	if currency != ledger.Currency {
+		return withKind(ErrValidation, fmt.Errorf("failed to match ledger currency %s, got %s", ledger.Currency, currency))
+	}
+
+	if !ledger.Currency.Allows(amount) {
+		return withKind(ErrValidation, fmt.Errorf("failed get amount with at most %d decimal places for %s", ledger.Currency.MinorUnits(), ledger.Currency))
+	}
+
+	return nil
 }
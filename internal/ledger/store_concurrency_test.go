@@ -0,0 +1,210 @@
+package ledger_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newConcurrentStore returns a store with a EUR counter account and the given EUR wallets
+func newConcurrentStore(walletIds ...string) ledger.Store {
+	ledgers := newLedgersWithCounterAccount()
+	for _, id := range walletIds {
+		ledgers[id] = &ledger.Ledger{ID: id, Type: "cash", Currency: "EUR"}
+	}
+	return ledger.NewStore(ledger.NewUUIDGenerator(), ledgers, counterAccountOptions)
+}
+
+// allTransactions pages through the whole transaction history of the ledger
+func allTransactions(t *testing.T, storeInstance ledger.Store, ledgerId string) []ledger.Transaction {
+	var transactions []ledger.Transaction
+	query := ledger.StatementQuery{Limit: ledger.MaxStatementPageSize}
+	for {
+		statement, err := storeInstance.GetTransactionHistory(context.Background(), ledgerId, query)
+		require.NoError(t, err)
+		transactions = append(transactions, statement.Transactions...)
+		if statement.NextCursor == "" {
+			return transactions
+		}
+		query.Cursor = statement.NextCursor
+	}
+}
+
+// assertConsistentHistory checks dates never decrease and every running balance follows from the
+// previous one and the amount of the transaction, and returns the final balance
+func assertConsistentHistory(t *testing.T, storeInstance ledger.Store, ledgerId string) ledger.Money {
+	var balance ledger.Money
+	var date int64
+	for i, tx := range allTransactions(t, storeInstance, ledgerId) {
+		require.GreaterOrEqual(t, tx.Date, date, "transaction %d on %s", i, ledgerId)
+		date = tx.Date
+
+		if tx.Type == ledger.Credit {
+			balance = balance.Add(tx.Amount)
+		} else {
+			balance = balance.Sub(tx.Amount)
+		}
+		require.Equal(t, balance, tx.RunningBalance, "transaction %d on %s", i, ledgerId)
+	}
+
+	last, err := storeInstance.GetLastBalance(context.Background(), ledgerId)
+	require.NoError(t, err)
+	assert.Equal(t, balance, last.Balance)
+	return balance
+}
+
+func TestStoreConcurrentCreditDebit(t *testing.T) {
+	storeInstance := newConcurrentStore("wallet")
+	one := ledger.MustParseMoney("1")
+	_, err := storeInstance.Credit(context.Background(), "wallet", ledger.TransactionRequestDTO{
+		Type: ledger.Credit, Description: "opening", Currency: "EUR", Amount: ledger.MustParseMoney("100"),
+	})
+	require.NoError(t, err)
+
+	const workers = 20
+	const perWorker = 50
+	var successfulDebits, successfulCredits atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				if (w+i)%2 == 0 {
+					_, err := storeInstance.Debit(context.Background(), "wallet", ledger.TransactionRequestDTO{
+						Type: ledger.Debit, Description: "debit", Currency: "EUR", Amount: one,
+					})
+					if err == nil {
+						successfulDebits.Add(1)
+					}
+				} else {
+					_, err := storeInstance.Credit(context.Background(), "wallet", ledger.TransactionRequestDTO{
+						Type: ledger.Credit, Description: "credit", Currency: "EUR", Amount: one,
+					})
+					if assert.NoError(t, err) {
+						successfulCredits.Add(1)
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	expected := ledger.MustParseMoney("100")
+	for i := int64(0); i < successfulCredits.Load(); i++ {
+		expected = expected.Add(one)
+	}
+	for i := int64(0); i < successfulDebits.Load(); i++ {
+		expected = expected.Sub(one)
+	}
+
+	assert.Equal(t, expected, assertConsistentHistory(t, storeInstance, "wallet"))
+	assert.Equal(t, expected.Neg(), assertConsistentHistory(t, storeInstance, "counter-eur"))
+}
+
+func TestStoreConcurrentDebitsCannotOverdraw(t *testing.T) {
+	storeInstance := newConcurrentStore("wallet")
+	_, err := storeInstance.Credit(context.Background(), "wallet", ledger.TransactionRequestDTO{
+		Type: ledger.Credit, Description: "opening", Currency: "EUR", Amount: ledger.MustParseMoney("100"),
+	})
+	require.NoError(t, err)
+
+	var successfulDebits atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := storeInstance.Debit(context.Background(), "wallet", ledger.TransactionRequestDTO{
+				Type: ledger.Debit, Description: "debit", Currency: "EUR", Amount: ledger.MustParseMoney("1"),
+			})
+			if err == nil {
+				successfulDebits.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(100), successfulDebits.Load())
+	assert.Equal(t, ledger.MustParseMoney("0"), assertConsistentHistory(t, storeInstance, "wallet"))
+}
+
+func TestStoreConcurrentTransfersInBothDirections(t *testing.T) {
+	storeInstance := newConcurrentStore("alice", "bob")
+	for _, id := range []string{"alice", "bob"} {
+		_, err := storeInstance.Credit(context.Background(), id, ledger.TransactionRequestDTO{
+			Type: ledger.Credit, Description: "opening", Currency: "EUR", Amount: ledger.MustParseMoney("1000"),
+		})
+		require.NoError(t, err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			source, destination := "alice", "bob"
+			if i%2 == 1 {
+				source, destination = destination, source
+			}
+			_, err := storeInstance.Transfer(context.Background(), ledger.TransferRequestDTO{
+				SourceLedgerID:      source,
+				DestinationLedgerID: destination,
+				Description:         "transfer",
+				Currency:            "EUR",
+				Amount:              ledger.MustParseMoney("3.5"),
+			})
+			assert.NoError(t, err)
+		}(i)
+	}
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "cash", Currency: "EUR"})
+			assert.NoError(t, err)
+			_, err = storeInstance.ListLedgers(context.Background(), ledger.LedgerQuery{})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	alice := assertConsistentHistory(t, storeInstance, "alice")
+	bob := assertConsistentHistory(t, storeInstance, "bob")
+	assert.Equal(t, ledger.MustParseMoney("2000"), alice.Add(bob))
+	assert.Equal(t, ledger.MustParseMoney("1000"), alice)
+}
+
+func TestStoreConcurrentLedgerCreationAndCredits(t *testing.T) {
+	storeInstance := newConcurrentStore("wallet")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			created, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "cash", Currency: "EUR"})
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), created.Version)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := storeInstance.Credit(context.Background(), "wallet", ledger.TransactionRequestDTO{
+				Type: ledger.Credit, Description: "credit", Currency: "EUR", Amount: ledger.MustParseMoney("1"),
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	page, err := storeInstance.ListLedgers(context.Background(), ledger.LedgerQuery{Type: "cash", Limit: ledger.MaxLedgerPageSize})
+	require.NoError(t, err)
+	assert.Len(t, page.Ledgers, 51)
+	assert.Equal(t, ledger.MustParseMoney("50"), assertConsistentHistory(t, storeInstance, "wallet"))
+}
@@ -3,61 +3,133 @@ package ledger_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/dineshd30/ledger-service/internal/ledger"
 	internalMock "github.com/dineshd30/ledger-service/internal/mock"
 	"github.com/stretchr/testify/assert"
 )
 
+// counterAccountOptions configures "counter-eur" as the counter account for EUR ledgers
+var counterAccountOptions = ledger.StoreOptions{
+	CounterAccounts: map[ledger.Currency]string{"EUR": "counter-eur"},
+}
+
+// newLedgersWithCounterAccount returns a ledger map holding only the EUR counter account
+func newLedgersWithCounterAccount() map[string]*ledger.Ledger {
+	return map[string]*ledger.Ledger{
+		"counter-eur": {
+			ID:       "counter-eur",
+			Type:     "counter-account",
+			Currency: "EUR",
+		},
+	}
+}
+
 func TestStoreCredit(t *testing.T) {
 	tests := []struct {
 		name            string
 		ledgerId        string
 		initialLedger   *ledger.Ledger
 		creditRequest   ledger.TransactionRequestDTO
-		expectedBalance float64
+		expectedBalance ledger.Money
 		expectError     bool
 	}{
 		{
 			name:     "Credit transaction on new ledger",
 			ledgerId: "ledger1",
 			initialLedger: &ledger.Ledger{
-				ID:   "ledger1",
-				Type: "cash",
+				ID:       "ledger1",
+				Type:     "cash",
+				Currency: "EUR",
 			},
 			creditRequest: ledger.TransactionRequestDTO{
 				Type:        ledger.Credit,
 				Description: "initial deposit",
-				Amount:      100,
+				Currency:    "EUR",
+				Amount:      ledger.MustParseMoney("100"),
 			},
-			expectedBalance: 100,
+			expectedBalance: ledger.MustParseMoney("100"),
 			expectError:     false,
 		},
 		{
 			name:     "Credit transaction on existing ledger",
 			ledgerId: "ledger2",
 			initialLedger: &ledger.Ledger{
-				ID:   "ledger2",
-				Type: "cash",
+				ID:       "ledger2",
+				Type:     "cash",
+				Currency: "EUR",
 				Transactions: []ledger.Transaction{
 					{
 						ID:             "tx-old",
 						Date:           1234567890,
 						Type:           ledger.Credit,
 						Description:    "previous deposit",
-						Amount:         50,
-						RunningBalance: 50,
+						Amount:         ledger.MustParseMoney("50"),
+						RunningBalance: ledger.MustParseMoney("50"),
 					},
 				},
 			},
 			creditRequest: ledger.TransactionRequestDTO{
 				Type:        ledger.Credit,
 				Description: "additional deposit",
-				Amount:      75,
+				Currency:    "EUR",
+				Amount:      ledger.MustParseMoney("75"),
 			},
-			expectedBalance: 125,
+			expectedBalance: ledger.MustParseMoney("125"),
 			expectError:     false,
 		},
+		{
+			name:     "Credit transaction in different currency returns error",
+			ledgerId: "ledger6",
+			initialLedger: &ledger.Ledger{
+				ID:       "ledger6",
+				Type:     "cash",
+				Currency: "GBP",
+			},
+			creditRequest: ledger.TransactionRequestDTO{
+				Type:        ledger.Credit,
+				Description: "deposit in euros",
+				Currency:    "EUR",
+				Amount:      ledger.MustParseMoney("100"),
+			},
+			expectedBalance: ledger.Money{},
+			expectError:     true,
+		},
+		{
+			name:     "Credit transaction on ledger without counter account returns error",
+			ledgerId: "ledger8",
+			initialLedger: &ledger.Ledger{
+				ID:       "ledger8",
+				Type:     "cash",
+				Currency: "USD",
+			},
+			creditRequest: ledger.TransactionRequestDTO{
+				Type:        ledger.Credit,
+				Description: "deposit in dollars",
+				Currency:    "USD",
+				Amount:      ledger.MustParseMoney("100"),
+			},
+			expectedBalance: ledger.Money{},
+			expectError:     true,
+		},
+		{
+			name:     "Credit transaction with minor units on JPY ledger returns error",
+			ledgerId: "ledger7",
+			initialLedger: &ledger.Ledger{
+				ID:       "ledger7",
+				Type:     "cash",
+				Currency: "JPY",
+			},
+			creditRequest: ledger.TransactionRequestDTO{
+				Type:        ledger.Credit,
+				Description: "deposit with sen",
+				Currency:    "JPY",
+				Amount:      ledger.MustParseMoney("100.5"),
+			},
+			expectedBalance: ledger.Money{},
+			expectError:     true,
+		},
 		{
 			name:          "Credit transaction with empty ledgerId returns error",
 			ledgerId:      "",
@@ -65,22 +137,23 @@ func TestStoreCredit(t *testing.T) {
 			creditRequest: ledger.TransactionRequestDTO{
 				Type:        ledger.Credit,
 				Description: "deposit with empty ledgerId",
-				Amount:      100,
+				Currency:    "EUR",
+				Amount:      ledger.MustParseMoney("100"),
 			},
-			expectedBalance: 0,
+			expectedBalance: ledger.Money{},
 			expectError:     true,
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			ledgers := make(map[string]*ledger.Ledger)
+			ledgers := newLedgersWithCounterAccount()
 			if tc.initialLedger != nil {
 				ledgers[tc.ledgerId] = tc.initialLedger
 			}
 			uuid := internalMock.UUIDGenerator{}
 			uuid.On("Generate").Return("123")
-			storeInstance := ledger.NewStore(&uuid, ledgers)
+			storeInstance := ledger.NewStore(&uuid, ledgers, counterAccountOptions)
 
 			tx, err := storeInstance.Credit(context.Background(), tc.ledgerId, tc.creditRequest)
 
@@ -106,32 +179,34 @@ func TestStoreDebit(t *testing.T) {
 		ledgerId        string
 		initialLedger   *ledger.Ledger
 		debitRequest    ledger.TransactionRequestDTO
-		expectedBalance float64
+		expectedBalance ledger.Money
 		expectError     bool
 	}{
 		{
 			name:     "Debit transaction on existing ledger",
 			ledgerId: "ledger3",
 			initialLedger: &ledger.Ledger{
-				ID:   "ledger3",
-				Type: "cash",
+				ID:       "ledger3",
+				Type:     "cash",
+				Currency: "EUR",
 				Transactions: []ledger.Transaction{
 					{
 						ID:             "tx-old",
 						Date:           1234567890,
 						Type:           ledger.Credit,
 						Description:    "previous deposit",
-						Amount:         200,
-						RunningBalance: 200,
+						Amount:         ledger.MustParseMoney("200"),
+						RunningBalance: ledger.MustParseMoney("200"),
 					},
 				},
 			},
 			debitRequest: ledger.TransactionRequestDTO{
 				Type:        ledger.Debit,
 				Description: "withdrawal",
-				Amount:      75,
+				Currency:    "EUR",
+				Amount:      ledger.MustParseMoney("75"),
 			},
-			expectedBalance: 125,
+			expectedBalance: ledger.MustParseMoney("125"),
 			expectError:     false,
 		},
 		{
@@ -141,47 +216,50 @@ func TestStoreDebit(t *testing.T) {
 			debitRequest: ledger.TransactionRequestDTO{
 				Type:        ledger.Debit,
 				Description: "withdrawal",
-				Amount:      50,
+				Currency:    "EUR",
+				Amount:      ledger.MustParseMoney("50"),
 			},
-			expectedBalance: 0,
+			expectedBalance: ledger.Money{},
 			expectError:     true,
 		},
 		{
 			name:     "Debit transaction with insufficient funds",
 			ledgerId: "ledger5",
 			initialLedger: &ledger.Ledger{
-				ID:   "ledger5",
-				Type: "cash",
+				ID:       "ledger5",
+				Type:     "cash",
+				Currency: "EUR",
 				Transactions: []ledger.Transaction{
 					{
 						ID:             "tx-old",
 						Date:           1234567890,
 						Type:           ledger.Credit,
 						Description:    "previous deposit",
-						Amount:         50,
-						RunningBalance: 50,
+						Amount:         ledger.MustParseMoney("50"),
+						RunningBalance: ledger.MustParseMoney("50"),
 					},
 				},
 			},
 			debitRequest: ledger.TransactionRequestDTO{
 				Type:        ledger.Debit,
 				Description: "withdrawal exceeding funds",
-				Amount:      75,
+				Currency:    "EUR",
+				Amount:      ledger.MustParseMoney("75"),
 			},
-			expectedBalance: 0,
+			expectedBalance: ledger.Money{},
 			expectError:     true,
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			ledgers := make(map[string]*ledger.Ledger)
+			ledgers := newLedgersWithCounterAccount()
 			if tc.initialLedger != nil {
 				ledgers[tc.ledgerId] = tc.initialLedger
 			}
 			uuid := internalMock.UUIDGenerator{}
 			uuid.On("Generate").Return("123")
-			storeInstance := ledger.NewStore(&uuid, ledgers)
+			storeInstance := ledger.NewStore(&uuid, ledgers, counterAccountOptions)
 
 			tx, err := storeInstance.Debit(context.Background(), tc.ledgerId, tc.debitRequest)
 
@@ -199,3 +277,73 @@ func TestStoreDebit(t *testing.T) {
 		})
 	}
 }
+
+func TestStoreGetBalanceAsOf(t *testing.T) {
+	ledgers := map[string]*ledger.Ledger{
+		"wallet": {
+			ID:       "wallet",
+			Type:     "cash",
+			Currency: "EUR",
+			Transactions: []ledger.Transaction{
+				{ID: "tx-1", Date: 1000, Type: ledger.Credit, Amount: ledger.MustParseMoney("100"), RunningBalance: ledger.MustParseMoney("100")},
+				{ID: "tx-2", Date: 2000, Type: ledger.Debit, Amount: ledger.MustParseMoney("30"), RunningBalance: ledger.MustParseMoney("70")},
+				{ID: "tx-3", Date: 2000, Type: ledger.Credit, Amount: ledger.MustParseMoney("5"), RunningBalance: ledger.MustParseMoney("75")},
+				{ID: "tx-4", Date: 3000, Type: ledger.Debit, Amount: ledger.MustParseMoney("75"), RunningBalance: ledger.MustParseMoney("0")},
+			},
+		},
+		"empty": {ID: "empty", Type: "cash", Currency: "EUR"},
+	}
+	storeInstance := ledger.NewStore(ledger.NewUUIDGenerator(), ledgers, ledger.StoreOptions{})
+
+	tests := []struct {
+		name            string
+		ledgerId        string
+		asOf            int64
+		expectedBalance string
+		expectError     bool
+	}{
+		{name: "Before first transaction", ledgerId: "wallet", asOf: 999, expectedBalance: "0"},
+		{name: "At first transaction", ledgerId: "wallet", asOf: 1000, expectedBalance: "100"},
+		{name: "Between transactions", ledgerId: "wallet", asOf: 1999, expectedBalance: "100"},
+		{name: "At several transactions with the same date", ledgerId: "wallet", asOf: 2000, expectedBalance: "75"},
+		{name: "After last transaction", ledgerId: "wallet", asOf: 5000, expectedBalance: "0"},
+		{name: "Ledger without transactions", ledgerId: "empty", asOf: 5000, expectedBalance: "0"},
+		{name: "Unknown ledger returns error", ledgerId: "unknown", asOf: 5000, expectError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			balance, err := storeInstance.GetBalanceAsOf(context.Background(), tc.ledgerId, tc.asOf)
+
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedBalance, balance.Balance.String())
+			assert.Equal(t, tc.asOf, balance.AsOf)
+			assert.Equal(t, ledger.Currency("EUR"), balance.Currency)
+		})
+	}
+}
+
+func TestStoreDatesNeverDecrease(t *testing.T) {
+	future := time.Now().Add(time.Hour).UTC().UnixMilli()
+	ledgers := newLedgersWithCounterAccount()
+	ledgers["wallet"] = &ledger.Ledger{
+		ID:       "wallet",
+		Type:     "cash",
+		Currency: "EUR",
+		Transactions: []ledger.Transaction{
+			{ID: "tx-future", Date: future, Type: ledger.Credit, Amount: ledger.MustParseMoney("10"), RunningBalance: ledger.MustParseMoney("10")},
+		},
+	}
+	storeInstance := ledger.NewStore(ledger.NewUUIDGenerator(), ledgers, counterAccountOptions)
+
+	tx, err := storeInstance.Credit(context.Background(), "wallet", ledger.TransactionRequestDTO{
+		Type: ledger.Credit, Description: "deposit", Currency: "EUR", Amount: ledger.MustParseMoney("5"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, future, tx.Date)
+}
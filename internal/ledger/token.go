@@ -0,0 +1,156 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultJWKSRefreshInterval is how often the keys verifying tokens are reloaded unless configured
+const DefaultJWKSRefreshInterval = time.Hour
+
+// DefaultJWKSMinRefreshInterval is how long after loading the keys a token signed with an unknown
+// key reloads them unless configured, so that forged key ids cannot make every request reload them
+const DefaultJWKSMinRefreshInterval = time.Minute
+
+// DefaultRolesClaim is the claim holding the roles of a token unless configured
+const DefaultRolesClaim = "roles"
+
+// the roles a token may carry
+const (
+	RoleLedgerRead  = "ledger:read"
+	RoleLedgerWrite = "ledger:write"
+	RoleLedgerAdmin = "ledger:admin"
+)
+
+// rolePermissions maps each role a token may carry to the permissions it grants
+var rolePermissions = map[string][]Permission{
+	RoleLedgerRead:  {PermissionReadBalance, PermissionReadStatement},
+	RoleLedgerWrite: {PermissionCredit, PermissionDebit},
+	RoleLedgerAdmin: {PermissionAdmin},
+}
+
+// signingMethods lists the asymmetric algorithms accepted for tokens, so that a token cannot be
+// signed with the public key as an HMAC secret
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// TokenOptions configure the validation of bearer tokens. JWKS is the file path or http(s) URL of
+// the keys of the identity provider. Issuer and Audience, when set, must match the iss and aud
+// claims. RolesClaim is the claim, possibly a dotted path such as "realm_access.roles", holding
+// the roles as a list or a space separated string. LedgersClaim, when set, is the claim holding
+// the ids of the ledgers the token is granted its roles on, every ledger otherwise.
+type TokenOptions struct {
+	JWKS               string
+	Issuer             string
+	Audience           string
+	RolesClaim         string
+	LedgersClaim       string
+	RefreshInterval    time.Duration
+	MinRefreshInterval time.Duration
+}
+
+// TokenVerifier validates the JWT bearer tokens issued by the identity provider
+type TokenVerifier struct {
+	keys   *jwks
+	parser *jwt.Parser
+	opts   TokenOptions
+}
+
+// NewTokenVerifier loads the keys of the identity provider and creates a verifier of its tokens
+func NewTokenVerifier(opts TokenOptions) (*TokenVerifier, error) {
+	if opts.JWKS == "" {
+		return nil, errors.New("failed get jwks file or url")
+	}
+	if opts.RolesClaim == "" {
+		opts.RolesClaim = DefaultRolesClaim
+	}
+	if opts.RefreshInterval <= 0 {
+		opts.RefreshInterval = DefaultJWKSRefreshInterval
+	}
+	if opts.MinRefreshInterval <= 0 {
+		opts.MinRefreshInterval = DefaultJWKSMinRefreshInterval
+	}
+
+	keys, err := newJWKS(opts.JWKS, opts.RefreshInterval, opts.MinRefreshInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30 * time.Second),
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+
+	return &TokenVerifier{keys: keys, parser: jwt.NewParser(parserOpts...), opts: opts}, nil
+}
+
+// verify validates the token and returns the client it identifies, as a key named after its sub
+// claim with the permissions of its roles on its ledgers
+func (v *TokenVerifier) verify(ctx context.Context, raw string) (APIKey, error) {
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.key(ctx, kid)
+	})
+	if err != nil {
+		return APIKey{}, err
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return APIKey{}, errors.New("failed get sub claim of token")
+	}
+
+	client := APIKey{ID: subject, Ledgers: []string{AllLedgers}}
+	for _, role := range claimStrings(claims, v.opts.RolesClaim) {
+		for _, permission := range rolePermissions[role] {
+			if !slices.Contains(client.Permissions, permission) {
+				client.Permissions = append(client.Permissions, permission)
+			}
+		}
+	}
+	if v.opts.LedgersClaim != "" {
+		client.Ledgers = claimStrings(claims, v.opts.LedgersClaim)
+	}
+
+	return client, nil
+}
+
+// claimStrings returns the strings of the claim at the dotted path, a list of strings or a space
+// separated string such as the scope claim, none when the claim is missing or of another type
+func claimStrings(claims jwt.MapClaims, path string) []string {
+	var value interface{} = map[string]interface{}(claims)
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,380 @@
+package ledger_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signingKey is a key of the identity provider signing tokens
+type signingKey struct {
+	kid string
+	key *ecdsa.PrivateKey
+}
+
+func newSigningKey(t *testing.T, kid string) signingKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return signingKey{kid: kid, key: key}
+}
+
+// jwks returns the JWKS publishing the public keys
+func jwks(keys ...signingKey) []byte {
+	set := struct {
+		Keys []map[string]string `json:"keys"`
+	}{}
+	for _, k := range keys {
+		set.Keys = append(set.Keys, map[string]string{
+			"kty": "EC",
+			"kid": k.kid,
+			"use": "sig",
+			"crv": "P-256",
+			"x":   base64.RawURLEncoding.EncodeToString(k.key.X.FillBytes(make([]byte, 32))),
+			"y":   base64.RawURLEncoding.EncodeToString(k.key.Y.FillBytes(make([]byte, 32))),
+		})
+	}
+	body, _ := json.Marshal(set)
+	return body
+}
+
+// sign returns the token of the claims signed with the key
+func (k signingKey) sign(t *testing.T, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = k.kid
+	signed, err := token.SignedString(k.key)
+	require.NoError(t, err)
+	return signed
+}
+
+// claimsOf returns valid claims of the subject for the ledger service, with the extra claims
+func claimsOf(subject string, extra jwt.MapClaims) jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"iss": "https://idp.example.com/",
+		"aud": "ledger-service",
+		"sub": subject,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for name, value := range extra {
+		claims[name] = value
+	}
+	return claims
+}
+
+// jwksServer serves the JWKS of the keys, which rotate replaces, counting the fetches. While status
+// is set it fails with it, and while held it answers once released.
+type jwksServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	body    []byte
+	status  int
+	held    chan struct{}
+	fetches int
+}
+
+func newJWKSServer(t *testing.T, keys ...signingKey) *jwksServer {
+	s := &jwksServer{body: jwks(keys...)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.fetches++
+		body, status, held := s.body, s.status, s.held
+		s.mu.Unlock()
+
+		if held != nil {
+			<-held
+		}
+		if status != 0 {
+			w.WriteHeader(status)
+			return
+		}
+		w.Write(body)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) rotate(keys ...signingKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.body = jwks(keys...)
+}
+
+func (s *jwksServer) fail(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+// hold makes the fetches wait until the returned function releases them
+func (s *jwksServer) hold() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	held := make(chan struct{})
+	s.held = held
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.held = nil
+		close(held)
+	}
+}
+
+func (s *jwksServer) fetched() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetches
+}
+
+// newTokenRouter routes balance reads and transactions of ledgers through auth
+func newTokenRouter(auth *ledger.Auth) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api := router.Group("", auth.Authenticate())
+	api.GET("/ledger/:ledgerId/balance", auth.Authorize(ledger.PermissionReadBalance), echoBody)
+	api.POST("/ledger/:ledgerId/transaction", auth.AuthorizeTransaction(), echoBody)
+	return router
+}
+
+// serveWithToken serves the request with the bearer token and returns its status
+func serveWithToken(router *gin.Engine, method string, path string, body string, token string) int {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestBearerTokens(t *testing.T) {
+	current := newSigningKey(t, "current")
+	unknown := newSigningKey(t, "unknown")
+	server := newJWKSServer(t, current)
+
+	tokens, err := ledger.NewTokenVerifier(ledger.TokenOptions{
+		JWKS:     server.URL,
+		Issuer:   "https://idp.example.com/",
+		Audience: "ledger-service",
+	})
+	require.NoError(t, err)
+	auth, err := ledger.NewAuth(apiKeys, tokens, newConcurrentStore())
+	require.NoError(t, err)
+	router := newTokenRouter(auth)
+
+	reader := jwt.MapClaims{"roles": []string{ledger.RoleLedgerRead}}
+	hs256, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claimsOf("payments", reader)).SignedString(jwks(current))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		token        string
+		expectedCode int
+	}{
+		{
+			name:         "should authorise read with read role",
+			method:       http.MethodGet,
+			path:         "/ledger/wallet/balance",
+			token:        current.sign(t, claimsOf("payments", reader)),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "should reject credit with read role",
+			method:       http.MethodPost,
+			path:         "/ledger/wallet/transaction",
+			body:         `{"type": "credit"}`,
+			token:        current.sign(t, claimsOf("payments", reader)),
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "should authorise credit with write role in space separated claim",
+			method:       http.MethodPost,
+			path:         "/ledger/wallet/transaction",
+			body:         `{"type": "credit"}`,
+			token:        current.sign(t, claimsOf("payments", jwt.MapClaims{"roles": "ledger:read ledger:write"})),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "should reject expired token",
+			method:       http.MethodGet,
+			path:         "/ledger/wallet/balance",
+			token:        current.sign(t, claimsOf("payments", jwt.MapClaims{"roles": []string{ledger.RoleLedgerRead}, "exp": time.Now().Add(-time.Hour).Unix()})),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "should reject token for other audience",
+			method:       http.MethodGet,
+			path:         "/ledger/wallet/balance",
+			token:        current.sign(t, claimsOf("payments", jwt.MapClaims{"roles": []string{ledger.RoleLedgerRead}, "aud": "other-service"})),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "should reject token of other issuer",
+			method:       http.MethodGet,
+			path:         "/ledger/wallet/balance",
+			token:        current.sign(t, claimsOf("payments", jwt.MapClaims{"roles": []string{ledger.RoleLedgerRead}, "iss": "https://other.example.com/"})),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "should reject token signed with unknown key",
+			method:       http.MethodGet,
+			path:         "/ledger/wallet/balance",
+			token:        unknown.sign(t, claimsOf("payments", reader)),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "should reject token signed with hmac",
+			method:       http.MethodGet,
+			path:         "/ledger/wallet/balance",
+			token:        hs256,
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "should reject token without subject",
+			method:       http.MethodGet,
+			path:         "/ledger/wallet/balance",
+			token:        current.sign(t, claimsOf("", reader)),
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedCode, serveWithToken(router, tt.method, tt.path, tt.body, tt.token))
+		})
+	}
+
+	t.Run("should still accept api keys", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/ledger/wallet/balance", nil)
+		req.Header.Set(ledger.APIKeyHeader, "wallet-key")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+func TestBearerTokensOfRotatedKey(t *testing.T) {
+	previous := newSigningKey(t, "previous")
+	next := newSigningKey(t, "next")
+	server := newJWKSServer(t, previous)
+
+	tokens, err := ledger.NewTokenVerifier(ledger.TokenOptions{JWKS: server.URL, MinRefreshInterval: time.Millisecond})
+	require.NoError(t, err)
+	auth, err := ledger.NewAuth(nil, tokens, newConcurrentStore())
+	require.NoError(t, err)
+	router := newTokenRouter(auth)
+
+	reader := jwt.MapClaims{"roles": []string{ledger.RoleLedgerRead}}
+	assert.Equal(t, http.StatusUnauthorized, serveWithToken(router, http.MethodGet, "/ledger/wallet/balance", "", next.sign(t, claimsOf("payments", reader))))
+
+	server.rotate(previous, next)
+	time.Sleep(2 * time.Millisecond)
+	assert.Equal(t, http.StatusOK, serveWithToken(router, http.MethodGet, "/ledger/wallet/balance", "", next.sign(t, claimsOf("payments", reader))))
+	assert.Equal(t, http.StatusOK, serveWithToken(router, http.MethodGet, "/ledger/wallet/balance", "", previous.sign(t, claimsOf("payments", reader))))
+}
+
+func TestBearerTokensBackOffReloadsAfterFailures(t *testing.T) {
+	current := newSigningKey(t, "current")
+	unknown := newSigningKey(t, "unknown")
+	server := newJWKSServer(t, current)
+
+	tokens, err := ledger.NewTokenVerifier(ledger.TokenOptions{JWKS: server.URL, MinRefreshInterval: 100 * time.Millisecond})
+	require.NoError(t, err)
+	auth, err := ledger.NewAuth(nil, tokens, newConcurrentStore())
+	require.NoError(t, err)
+	router := newTokenRouter(auth)
+	reader := jwt.MapClaims{"roles": []string{ledger.RoleLedgerRead}}
+
+	server.fail(http.StatusServiceUnavailable)
+	time.Sleep(110 * time.Millisecond)
+	for range 3 {
+		assert.Equal(t, http.StatusUnauthorized, serveWithToken(router, http.MethodGet, "/ledger/wallet/balance", "", unknown.sign(t, claimsOf("payments", reader))))
+	}
+	assert.Equal(t, 2, server.fetched())
+
+	// a failed reload doubles the time until the next one
+	time.Sleep(110 * time.Millisecond)
+	assert.Equal(t, http.StatusUnauthorized, serveWithToken(router, http.MethodGet, "/ledger/wallet/balance", "", unknown.sign(t, claimsOf("payments", reader))))
+	assert.Equal(t, 2, server.fetched())
+	assert.Equal(t, http.StatusOK, serveWithToken(router, http.MethodGet, "/ledger/wallet/balance", "", current.sign(t, claimsOf("payments", reader))))
+
+	time.Sleep(110 * time.Millisecond)
+	assert.Equal(t, http.StatusUnauthorized, serveWithToken(router, http.MethodGet, "/ledger/wallet/balance", "", unknown.sign(t, claimsOf("payments", reader))))
+	assert.Equal(t, 3, server.fetched())
+}
+
+func TestBearerTokensVerifiedWhileJWKSReloads(t *testing.T) {
+	current := newSigningKey(t, "current")
+	next := newSigningKey(t, "next")
+	server := newJWKSServer(t, current)
+
+	tokens, err := ledger.NewTokenVerifier(ledger.TokenOptions{JWKS: server.URL, RefreshInterval: time.Millisecond, MinRefreshInterval: time.Millisecond})
+	require.NoError(t, err)
+	auth, err := ledger.NewAuth(nil, tokens, newConcurrentStore())
+	require.NoError(t, err)
+	router := newTokenRouter(auth)
+	reader := jwt.MapClaims{"roles": []string{ledger.RoleLedgerRead}}
+
+	release := server.hold()
+	server.rotate(current, next)
+	time.Sleep(2 * time.Millisecond)
+	// the stale keys keep verifying tokens while the reload waits on the JWKS
+	for range 3 {
+		assert.Equal(t, http.StatusOK, serveWithToken(router, http.MethodGet, "/ledger/wallet/balance", "", current.sign(t, claimsOf("payments", reader))))
+	}
+
+	// a request giving up on the reload of a kid the keys do not hold does not cancel the reload
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/ledger/wallet/balance", nil)
+	req.Header.Set("Authorization", "Bearer "+next.sign(t, claimsOf("payments", reader)))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	// the keys are reloaded one load at a time
+	assert.Equal(t, 2, server.fetched())
+
+	release()
+	assert.Eventually(t, func() bool {
+		return serveWithToken(router, http.MethodGet, "/ledger/wallet/balance", "", next.sign(t, claimsOf("payments", reader))) == http.StatusOK
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestBearerTokensOfJWKSFileWithClaimPaths(t *testing.T) {
+	key := newSigningKey(t, "file")
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwks(key), 0o600))
+
+	tokens, err := ledger.NewTokenVerifier(ledger.TokenOptions{
+		JWKS:         path,
+		RolesClaim:   "realm_access.roles",
+		LedgersClaim: "ledgers",
+	})
+	require.NoError(t, err)
+	auth, err := ledger.NewAuth(nil, tokens, newConcurrentStore())
+	require.NoError(t, err)
+	router := newTokenRouter(auth)
+
+	token := key.sign(t, claimsOf("payments", jwt.MapClaims{
+		"realm_access": map[string]interface{}{"roles": []string{ledger.RoleLedgerRead}},
+		"ledgers":      []string{"wallet"},
+	}))
+	assert.Equal(t, http.StatusOK, serveWithToken(router, http.MethodGet, "/ledger/wallet/balance", "", token))
+	assert.Equal(t, http.StatusForbidden, serveWithToken(router, http.MethodGet, "/ledger/savings/balance", "", token))
+}
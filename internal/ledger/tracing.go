@@ -0,0 +1,230 @@
+package ledger
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the name of the tracer of the spans started around Store calls
+const TracerName = "github.com/dineshd30/ledger-service/internal/ledger"
+
+// attribute keys of the spans started around Store calls
+const (
+	attrLedgerID            = attribute.Key("ledger.id")
+	attrSourceLedgerID      = attribute.Key("ledger.source_id")
+	attrDestinationLedgerID = attribute.Key("ledger.destination_id")
+	attrTransactionType     = attribute.Key("ledger.transaction.type")
+	attrTransactionID       = attribute.Key("ledger.transaction.id")
+	attrHoldID              = attribute.Key("ledger.hold.id")
+	attrLegs                = attribute.Key("ledger.journal_entry.legs")
+)
+
+// tracedStore is a Store running every call to the wrapped store in a span of its own
+type tracedStore struct {
+	store  Store
+	tracer trace.Tracer
+}
+
+// NewTracedStore wraps the store so that every call runs in a span named after the Store method,
+// child of the span of the request context, with the ledger id, hold id and transaction type of
+// the call as attributes. A failed call records its error on the span.
+func NewTracedStore(store Store, provider trace.TracerProvider) Store {
+	return &tracedStore{store: store, tracer: provider.Tracer(TracerName)}
+}
+
+// start starts the span of the Store method
+func (t *tracedStore) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, "Store."+method, trace.WithAttributes(attrs...))
+}
+
+// end records the error of the call, if any, and ends its span
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (t *tracedStore) Credit(ctx context.Context, ledgerId string, trd TransactionRequestDTO) (tx Transaction, err error) {
+	ctx, span := t.start(ctx, "Credit", attrLedgerID.String(ledgerId), attrTransactionType.String(string(Credit)))
+	defer func() { end(span, err) }()
+	return t.store.Credit(ctx, ledgerId, trd)
+}
+
+func (t *tracedStore) Debit(ctx context.Context, ledgerId string, trd TransactionRequestDTO) (tx Transaction, err error) {
+	ctx, span := t.start(ctx, "Debit", attrLedgerID.String(ledgerId), attrTransactionType.String(string(Debit)))
+	defer func() { end(span, err) }()
+	return t.store.Debit(ctx, ledgerId, trd)
+}
+
+func (t *tracedStore) Transfer(ctx context.Context, trd TransferRequestDTO) (transfer Transfer, err error) {
+	ctx, span := t.start(ctx, "Transfer",
+		attrSourceLedgerID.String(trd.SourceLedgerID), attrDestinationLedgerID.String(trd.DestinationLedgerID))
+	defer func() { end(span, err) }()
+	return t.store.Transfer(ctx, trd)
+}
+
+func (t *tracedStore) Post(ctx context.Context, jrd JournalEntryRequestDTO) (entry JournalEntry, err error) {
+	ctx, span := t.start(ctx, "Post", attrLegs.Int(len(jrd.Legs)))
+	defer func() { end(span, err) }()
+	return t.store.Post(ctx, jrd)
+}
+
+func (t *tracedStore) GetJournalEntry(ctx context.Context, ledgerId string, transactionId string) (entry JournalEntry, err error) {
+	ctx, span := t.start(ctx, "GetJournalEntry", attrLedgerID.String(ledgerId), attrTransactionID.String(transactionId))
+	defer func() { end(span, err) }()
+	return t.store.GetJournalEntry(ctx, ledgerId, transactionId)
+}
+
+func (t *tracedStore) CreateLedger(ctx context.Context, lrd LedgerRequestDTO) (ledger Ledger, err error) {
+	ctx, span := t.start(ctx, "CreateLedger")
+	defer func() {
+		if err == nil {
+			span.SetAttributes(attrLedgerID.String(ledger.ID))
+		}
+		end(span, err)
+	}()
+	return t.store.CreateLedger(ctx, lrd)
+}
+
+func (t *tracedStore) GetLedger(ctx context.Context, ledgerId string) (ledger Ledger, err error) {
+	ctx, span := t.start(ctx, "GetLedger", attrLedgerID.String(ledgerId))
+	defer func() { end(span, err) }()
+	return t.store.GetLedger(ctx, ledgerId)
+}
+
+func (t *tracedStore) ListLedgers(ctx context.Context, query LedgerQuery) (page LedgerPage, err error) {
+	ctx, span := t.start(ctx, "ListLedgers")
+	defer func() { end(span, err) }()
+	return t.store.ListLedgers(ctx, query)
+}
+
+func (t *tracedStore) CloseLedger(ctx context.Context, ledgerId string) (ledger Ledger, err error) {
+	ctx, span := t.start(ctx, "CloseLedger", attrLedgerID.String(ledgerId))
+	defer func() { end(span, err) }()
+	return t.store.CloseLedger(ctx, ledgerId)
+}
+
+func (t *tracedStore) SetBalancePolicy(ctx context.Context, ledgerId string, policy BalancePolicy) (ledger Ledger, err error) {
+	ctx, span := t.start(ctx, "SetBalancePolicy", attrLedgerID.String(ledgerId))
+	defer func() { end(span, err) }()
+	return t.store.SetBalancePolicy(ctx, ledgerId, policy)
+}
+
+func (t *tracedStore) GetLastBalance(ctx context.Context, ledgerId string) (balance Balance, err error) {
+	ctx, span := t.start(ctx, "GetLastBalance", attrLedgerID.String(ledgerId))
+	defer func() { end(span, err) }()
+	return t.store.GetLastBalance(ctx, ledgerId)
+}
+
+func (t *tracedStore) GetBalanceAsOf(ctx context.Context, ledgerId string, asOf int64) (balance Balance, err error) {
+	ctx, span := t.start(ctx, "GetBalanceAsOf", attrLedgerID.String(ledgerId))
+	defer func() { end(span, err) }()
+	return t.store.GetBalanceAsOf(ctx, ledgerId, asOf)
+}
+
+func (t *tracedStore) GetTransactionHistory(ctx context.Context, ledgerId string, query StatementQuery) (statement Statement, err error) {
+	attrs := []attribute.KeyValue{attrLedgerID.String(ledgerId)}
+	if query.Type != "" {
+		attrs = append(attrs, attrTransactionType.String(string(query.Type)))
+	}
+	ctx, span := t.start(ctx, "GetTransactionHistory", attrs...)
+	defer func() { end(span, err) }()
+	return t.store.GetTransactionHistory(ctx, ledgerId, query)
+}
+
+func (t *tracedStore) PlaceHold(ctx context.Context, ledgerId string, hrd HoldRequestDTO) (hold Hold, err error) {
+	ctx, span := t.start(ctx, "PlaceHold", attrLedgerID.String(ledgerId))
+	defer func() {
+		if err == nil {
+			span.SetAttributes(attrHoldID.String(hold.ID))
+		}
+		end(span, err)
+	}()
+	return t.store.PlaceHold(ctx, ledgerId, hrd)
+}
+
+func (t *tracedStore) GetHold(ctx context.Context, holdId string) (hold Hold, err error) {
+	ctx, span := t.start(ctx, "GetHold", attrHoldID.String(holdId))
+	defer func() { end(span, err) }()
+	return t.store.GetHold(ctx, holdId)
+}
+
+func (t *tracedStore) CaptureHold(ctx context.Context, holdId string, crd CaptureRequestDTO) (capture HoldCapture, err error) {
+	ctx, span := t.start(ctx, "CaptureHold", attrHoldID.String(holdId), attrTransactionType.String(string(Debit)))
+	defer func() {
+		if err == nil {
+			span.SetAttributes(attrLedgerID.String(capture.Hold.LedgerID))
+		}
+		end(span, err)
+	}()
+	return t.store.CaptureHold(ctx, holdId, crd)
+}
+
+func (t *tracedStore) ReleaseHold(ctx context.Context, holdId string) (hold Hold, err error) {
+	ctx, span := t.start(ctx, "ReleaseHold", attrHoldID.String(holdId))
+	defer func() {
+		if err == nil {
+			span.SetAttributes(attrLedgerID.String(hold.LedgerID))
+		}
+		end(span, err)
+	}()
+	return t.store.ReleaseHold(ctx, holdId)
+}
+
+func (t *tracedStore) ExpireHolds(ctx context.Context) (expired int, err error) {
+	ctx, span := t.start(ctx, "ExpireHolds")
+	defer func() {
+		span.SetAttributes(attribute.Int("ledger.holds.expired", expired))
+		end(span, err)
+	}()
+	return t.store.ExpireHolds(ctx)
+}
+
+func (t *tracedStore) ReverseTransaction(ctx context.Context, ledgerId string, transactionId string, rrd ReversalRequestDTO) (reversal Reversal, err error) {
+	ctx, span := t.start(ctx, "ReverseTransaction", attrLedgerID.String(ledgerId), attrTransactionID.String(transactionId))
+	defer func() {
+		if err == nil {
+			span.SetAttributes(attrTransactionType.String(string(reversal.Transaction.Type)))
+		}
+		end(span, err)
+	}()
+	return t.store.ReverseTransaction(ctx, ledgerId, transactionId, rrd)
+}
+
+func (t *tracedStore) GetLedgerEvents(ctx context.Context, ledgerId string, query EventQuery) (page EventPage, err error) {
+	ctx, span := t.start(ctx, "GetLedgerEvents", attrLedgerID.String(ledgerId))
+	defer func() { end(span, err) }()
+	return t.store.GetLedgerEvents(ctx, ledgerId, query)
+}
+
+func (t *tracedStore) GetLedgerAtVersion(ctx context.Context, ledgerId string, version int64) (state LedgerState, err error) {
+	ctx, span := t.start(ctx, "GetLedgerAtVersion", attrLedgerID.String(ledgerId))
+	defer func() { end(span, err) }()
+	return t.store.GetLedgerAtVersion(ctx, ledgerId, version)
+}
+
+func (t *tracedStore) Project(ctx context.Context, projection Projection) (err error) {
+	ctx, span := t.start(ctx, "Project")
+	defer func() { end(span, err) }()
+	return t.store.Project(ctx, projection)
+}
+
+func (t *tracedStore) VerifyLedger(ctx context.Context, ledgerId string) (verification ChainVerification, err error) {
+	ctx, span := t.start(ctx, "VerifyLedger", attrLedgerID.String(ledgerId))
+	defer func() { end(span, err) }()
+	return t.store.VerifyLedger(ctx, ledgerId)
+}
+
+// Ping is not traced, as the readiness probe calls it every few seconds
+func (t *tracedStore) Ping(ctx context.Context) error {
+	return t.store.Ping(ctx)
+}
+
+func (t *tracedStore) Close() error {
+	return t.store.Close()
+}
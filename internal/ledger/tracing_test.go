@@ -0,0 +1,89 @@
+package ledger_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// spanAttributes returns the attributes of the span by key
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestTracedStoreStartsSpanPerCall(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	storeInstance := ledger.NewTracedStore(newFundedStore(t), provider)
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	_, err := storeInstance.Debit(ctx, "wallet", ledger.TransactionRequestDTO{
+		Type: ledger.Debit, Description: "payment", Currency: "EUR", Amount: ledger.MustParseMoney("10"),
+	})
+	require.NoError(t, err)
+	_, err = storeInstance.Debit(ctx, "wallet", ledger.TransactionRequestDTO{
+		Type: ledger.Debit, Description: "payment", Currency: "EUR", Amount: ledger.MustParseMoney("1000"),
+	})
+	require.ErrorIs(t, err, ledger.ErrInsufficientFunds)
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	for _, span := range spans[:2] {
+		assert.Equal(t, "Store.Debit", span.Name())
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+		attrs := spanAttributes(span)
+		assert.Equal(t, "wallet", attrs["ledger.id"].AsString())
+		assert.Equal(t, "debit", attrs["ledger.transaction.type"].AsString())
+	}
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Len(t, spans[1].Events(), 1, "recorded error")
+}
+
+func TestTracingHonoursIncomingTraceparent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	storeInstance := ledger.NewTracedStore(newFundedStore(t), provider)
+
+	router := gin.New()
+	router.Use(otelgin.Middleware("ledger-service",
+		otelgin.WithTracerProvider(provider), otelgin.WithPropagators(propagation.TraceContext{})))
+	router.POST("/ledger/:ledgerId/transaction", ledger.DoTransaction(storeInstance))
+
+	req := httptest.NewRequest(http.MethodPost, "/ledger/wallet/transaction",
+		strings.NewReader(`{"type": "credit", "description": "deposit", "currency": "EUR", "amount": 5}`))
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	storeSpan, requestSpan := spans[0], spans[1]
+	assert.Equal(t, "Store.Credit", storeSpan.Name())
+	assert.Equal(t, "/ledger/:ledgerId/transaction", requestSpan.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", requestSpan.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", requestSpan.Parent().SpanID().String())
+	assert.Equal(t, requestSpan.SpanContext().TraceID(), storeSpan.SpanContext().TraceID())
+	assert.Equal(t, requestSpan.SpanContext().SpanID(), storeSpan.Parent().SpanID())
+	assert.Equal(t, "wallet", spanAttributes(storeSpan)["ledger.id"].AsString())
+}
@@ -0,0 +1,59 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// TransferRequestDTO represents the request payload for moving money between two ledgers
+type TransferRequestDTO struct {
+	SourceLedgerID      string   `json:"sourceLedgerId"`
+	DestinationLedgerID string   `json:"destinationLedgerId"`
+	Description         string   `json:"description"`
+	Currency            Currency `json:"currency"`
+	Amount              Money    `json:"amount"`
+}
+
+// Transfer represents the linked debit and credit entries of a ledger-to-ledger transfer
+type Transfer struct {
+	ID     string      `json:"id"`
+	Debit  Transaction `json:"debit"`
+	Credit Transaction `json:"credit"`
+}
+
+// Transfer debits the source ledger and credits the destination ledger as a single journal entry.
+// Both ledgers are validated before either is changed, so a rejected debit leaves both untouched.
+func (s *store) Transfer(ctx context.Context, trd TransferRequestDTO) (Transfer, error) {
+	if trd.SourceLedgerID == trd.DestinationLedgerID {
+		return Transfer{}, withKind(ErrValidation, errors.New("failed to perform transfer, got same source and destination ledger"))
+	}
+
+	entry, err := s.post(JournalEntryRequestDTO{
+		Description: trd.Description,
+		Currency:    trd.Currency,
+		Legs: []LegDTO{
+			{LedgerID: trd.SourceLedgerID, Type: Debit, Amount: trd.Amount},
+			{LedgerID: trd.DestinationLedgerID, Type: Credit, Amount: trd.Amount},
+		},
+	}, func(tx *Transaction) {
+		tx.TransferID = tx.JournalEntryID
+	})
+	if err != nil {
+		return Transfer{}, fmt.Errorf("failed to perform transfer, got error : %w", err)
+	}
+
+	transfer := Transfer{
+		ID:     entry.ID,
+		Debit:  entry.Transactions[0],
+		Credit: entry.Transactions[1],
+	}
+	loggerFrom(ctx).Info("transferred between ledgers",
+		zap.String("transferId", transfer.ID),
+		zap.String("sourceLedgerId", trd.SourceLedgerID),
+		zap.String("destinationLedgerId", trd.DestinationLedgerID),
+		zap.Stringer("amount", trd.Amount))
+	return transfer, nil
+}
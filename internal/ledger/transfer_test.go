@@ -0,0 +1,148 @@
+package ledger_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+	internalMock "github.com/dineshd30/ledger-service/internal/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreTransfer(t *testing.T) {
+	newLedgers := func() map[string]*ledger.Ledger {
+		return map[string]*ledger.Ledger{
+			"source": {
+				ID:       "source",
+				Type:     "cash",
+				Currency: "EUR",
+				Transactions: []ledger.Transaction{
+					{
+						ID:             "tx-old",
+						Date:           1234567890,
+						Type:           ledger.Credit,
+						Description:    "previous deposit",
+						Amount:         ledger.MustParseMoney("100"),
+						RunningBalance: ledger.MustParseMoney("100"),
+					},
+				},
+			},
+			"destination": {
+				ID:       "destination",
+				Type:     "cash",
+				Currency: "EUR",
+			},
+			"sterling": {
+				ID:       "sterling",
+				Type:     "cash",
+				Currency: "GBP",
+			},
+		}
+	}
+
+	tests := []struct {
+		name                       string
+		request                    ledger.TransferRequestDTO
+		expectedSourceBalance      ledger.Money
+		expectedDestinationBalance ledger.Money
+		expectError                bool
+	}{
+		{
+			name: "Transfer between ledgers",
+			request: ledger.TransferRequestDTO{
+				SourceLedgerID:      "source",
+				DestinationLedgerID: "destination",
+				Description:         "rent",
+				Currency:            "EUR",
+				Amount:              ledger.MustParseMoney("40.25"),
+			},
+			expectedSourceBalance:      ledger.MustParseMoney("59.75"),
+			expectedDestinationBalance: ledger.MustParseMoney("40.25"),
+		},
+		{
+			name: "Transfer exceeding source balance is rolled back",
+			request: ledger.TransferRequestDTO{
+				SourceLedgerID:      "source",
+				DestinationLedgerID: "destination",
+				Description:         "too much",
+				Currency:            "EUR",
+				Amount:              ledger.MustParseMoney("150"),
+			},
+			expectedSourceBalance:      ledger.MustParseMoney("100"),
+			expectedDestinationBalance: ledger.Money{},
+			expectError:                true,
+		},
+		{
+			name: "Transfer to ledger in different currency is rolled back",
+			request: ledger.TransferRequestDTO{
+				SourceLedgerID:      "source",
+				DestinationLedgerID: "sterling",
+				Description:         "cross currency",
+				Currency:            "EUR",
+				Amount:              ledger.MustParseMoney("10"),
+			},
+			expectedSourceBalance:      ledger.MustParseMoney("100"),
+			expectedDestinationBalance: ledger.Money{},
+			expectError:                true,
+		},
+		{
+			name: "Transfer to unknown ledger is rolled back",
+			request: ledger.TransferRequestDTO{
+				SourceLedgerID:      "source",
+				DestinationLedgerID: "unknown",
+				Description:         "nowhere",
+				Currency:            "EUR",
+				Amount:              ledger.MustParseMoney("10"),
+			},
+			expectedSourceBalance: ledger.MustParseMoney("100"),
+			expectError:           true,
+		},
+		{
+			name: "Transfer to same ledger returns error",
+			request: ledger.TransferRequestDTO{
+				SourceLedgerID:      "source",
+				DestinationLedgerID: "source",
+				Description:         "loop",
+				Currency:            "EUR",
+				Amount:              ledger.MustParseMoney("10"),
+			},
+			expectedSourceBalance: ledger.MustParseMoney("100"),
+			expectError:           true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ledgers := newLedgers()
+			uuid := internalMock.UUIDGenerator{}
+			uuid.On("Generate").Return("123")
+			storeInstance := ledger.NewStore(&uuid, ledgers, ledger.StoreOptions{})
+
+			transfer, err := storeInstance.Transfer(context.Background(), tc.request)
+
+			source := ledgers[tc.request.SourceLedgerID]
+			sourceBalance := source.Transactions[len(source.Transactions)-1].RunningBalance
+			assert.Equal(t, tc.expectedSourceBalance, sourceBalance)
+
+			if tc.expectError {
+				assert.Error(t, err)
+				assert.Len(t, source.Transactions, 1)
+				if destination, ok := ledgers[tc.request.DestinationLedgerID]; ok && destination != source {
+					assert.Empty(t, destination.Transactions)
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, ledger.Debit, transfer.Debit.Type)
+				assert.Equal(t, ledger.Credit, transfer.Credit.Type)
+				assert.Equal(t, transfer.ID, transfer.Debit.TransferID)
+				assert.Equal(t, transfer.ID, transfer.Credit.TransferID)
+				assert.Equal(t, tc.expectedSourceBalance, transfer.Debit.RunningBalance)
+				assert.Equal(t, tc.expectedDestinationBalance, transfer.Credit.RunningBalance)
+
+				destination := ledgers[tc.request.DestinationLedgerID]
+				assert.Equal(t, []ledger.Transaction{transfer.Credit}, destination.Transactions)
+				assert.Equal(t, transfer.Debit, source.Transactions[len(source.Transactions)-1])
+			}
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package ledger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// writeAheadLog is a changeLog appending one JSON encoded record per line to a file on local
+// disk: each change with the events it added to the history of each ledger, and the snapshots of
+// the ledgers. Every record is synced to disk before append returns, so a change the store has
+// applied survives a crash or restart.
+type writeAheadLog struct {
+	mu   sync.Mutex
+	file *os.File
+	// size is the length of the log up to the last complete record
+	size int64
+	// readOnly is set for a log opened to be read, which records nothing
+	readOnly bool
+}
+
+// logRecord is one line of the write-ahead log: a change with its events, or a snapshot of a
+// ledger. A line recorded before events were logged holds the change alone, with its kind at the
+// top level, and the events of the change are derived again as it is replayed.
+type logRecord struct {
+	Change   *change      `json:"change,omitempty"`
+	Events   []Event      `json:"events,omitempty"`
+	Snapshot *LedgerState `json:"snapshot,omitempty"`
+	Kind     changeKind   `json:"kind,omitempty"`
+}
+
+// NewFileStore creates a store that appends every change to the write-ahead log at path before
+// applying it, and rebuilds the ledgers from the events and snapshots recorded in the log on
+// startup. The ledgers passed in are the configured ones, such as counter accounts, which are
+// recorded in the log with the event opening them unless it already holds them.
+func NewFileStore(uuid UUIDGenerator, ledgers map[string]*Ledger, opts StoreOptions, path string) (Store, error) {
+	return newFileStore(uuid, ledgers, opts, path, false)
+}
+
+// NewReadOnlyFileStore creates a store over the ledgers recorded in the write-ahead log at path
+// which leaves the log as it is: a torn record at its end is skipped rather than truncated, the
+// configured ledgers it does not hold are not recorded and every change is rejected
+func NewReadOnlyFileStore(uuid UUIDGenerator, ledgers map[string]*Ledger, opts StoreOptions, path string) (Store, error) {
+	return newFileStore(uuid, ledgers, opts, path, true)
+}
+
+// newFileStore creates a store over the write-ahead log at path, read-only if readOnly is set
+func newFileStore(uuid UUIDGenerator, ledgers map[string]*Ledger, opts StoreOptions, path string, readOnly bool) (Store, error) {
+	wal, records, err := openWriteAheadLog(path, readOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open write-ahead log, got error : %w", err)
+	}
+
+	s := newStore(uuid, make(map[string]*Ledger), opts, wal)
+	if err := wal.load(s, records, ledgers); err != nil {
+		wal.close()
+		return nil, err
+	}
+
+	zap.L().Info("replayed write-ahead log", zap.String("path", path), zap.Int("records", len(records)))
+	return s, nil
+}
+
+// load adds the ledgers recorded in the log to the store, along with the idempotency keys of their
+// journal entries, after recording the configured ledgers the log does not hold yet
+func (w *writeAheadLog) load(s *store, records []logRecord, configured map[string]*Ledger) error {
+	streams := make(map[string][]Event)
+	snapshots := make(map[string][]LedgerState)
+	changes := make([]change, 0, len(records))
+	version := func(ledgerId string) (int64, error) {
+		if events := streams[ledgerId]; len(events) > 0 {
+			return events[len(events)-1].Version, nil
+		}
+		if _, ok := configured[ledgerId]; ok {
+			// the configured ledger was opened from the configuration before it was logged
+			return 1, nil
+		}
+		return 0, withKind(ErrNotFound, fmt.Errorf("failed get ledger: %s", ledgerId))
+	}
+
+	for i, r := range records {
+		if r.Snapshot != nil {
+			snapshots[r.Snapshot.Ledger.ID] = append(snapshots[r.Snapshot.Ledger.ID], *r.Snapshot)
+			continue
+		}
+
+		events := r.Events
+		if events == nil {
+			var err error
+			if events, err = changeEvents(*r.Change, version); err != nil {
+				return fmt.Errorf("failed to replay change %d of write-ahead log, got error : %w", i+1, err)
+			}
+			// a change logged without its events was logged before transactions were chained
+			for j := range events {
+				events[j].unhashed = true
+			}
+		}
+		for _, e := range events {
+			streams[e.LedgerID] = append(streams[e.LedgerID], e)
+		}
+		changes = append(changes, *r.Change)
+	}
+
+	ids := make([]string, 0, len(configured))
+	for id := range configured {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		if events := streams[id]; len(events) > 0 && events[0].Version == 1 {
+			continue
+		}
+
+		ledger := configured[id].metadata()
+		if ledger.Status == "" {
+			ledger.Status = LedgerOpen
+		}
+		if ledger.Policy.Type == "" {
+			ledger.Policy = s.defaultPolicy(id)
+		}
+		opened := historyEvents(ledger, nil, nil)[0]
+		if !w.readOnly {
+			if err := w.append(change{Kind: changeLedgerCreated, Ledger: &ledger}, []Event{opened}); err != nil {
+				return fmt.Errorf("failed to record configured ledger %s, got error : %w", id, err)
+			}
+		}
+		streams[id] = append([]Event{opened}, streams[id]...)
+	}
+
+	if err := s.loadLedgers(streams, snapshots); err != nil {
+		return fmt.Errorf("failed to replay write-ahead log, got error : %w", err)
+	}
+	for _, c := range changes {
+		s.rememberIdempotency(c)
+	}
+
+	return nil
+}
+
+// openWriteAheadLog opens or creates the log at path and returns the records in it. A torn record
+// at the end of the log, left by a crash in the middle of an append that was never acknowledged,
+// is truncated. A log opened read-only must exist and is only read.
+func openWriteAheadLog(path string, readOnly bool) (*writeAheadLog, []logRecord, error) {
+	var file *os.File
+	var err error
+	if readOnly {
+		file, err = os.Open(path)
+	} else {
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			return nil, nil, err
+		}
+		file, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	records, size, err := readRecords(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	if info.Size() > size && readOnly {
+		zap.L().Warn("skipping torn record at end of write-ahead log", zap.String("path", path), zap.Int64("offset", size))
+	} else if info.Size() > size {
+		zap.L().Warn("truncating torn record at end of write-ahead log", zap.String("path", path), zap.Int64("offset", size))
+		if err := file.Truncate(size); err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+	}
+
+	return &writeAheadLog{file: file, size: size, readOnly: readOnly}, records, nil
+}
+
+// readRecords decodes the complete records of the log and returns them with the length they span
+func readRecords(r io.Reader) ([]logRecord, int64, error) {
+	var records []logRecord
+	var size int64
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return records, size, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		record, err := decodeRecord(bytes.TrimSpace(line))
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to decode record at offset %d, got error : %w", size, err)
+		}
+		records = append(records, record)
+		size += int64(len(line))
+	}
+}
+
+// decodeRecord decodes one line of the log, either a record or a change logged without its events
+func decodeRecord(line []byte) (logRecord, error) {
+	var record logRecord
+	if err := json.Unmarshal(line, &record); err != nil {
+		return logRecord{}, err
+	}
+
+	if record.Kind != "" {
+		var c change
+		if err := json.Unmarshal(line, &c); err != nil {
+			return logRecord{}, err
+		}
+		return logRecord{Change: &c}, nil
+	}
+	if record.Change == nil && record.Snapshot == nil {
+		return logRecord{}, errors.New("failed get change or snapshot")
+	}
+	if record.Change != nil && len(record.Events) == 0 {
+		return logRecord{}, fmt.Errorf("failed get events of %s", record.Change.Kind)
+	}
+
+	return record, nil
+}
+
+// append writes the change and its events to the end of the log and syncs them to disk
+func (w *writeAheadLog) append(c change, events []Event) error {
+	return w.write(logRecord{Change: &c, Events: events})
+}
+
+// recordSnapshot writes the snapshot to the end of the log and syncs it to disk
+func (w *writeAheadLog) recordSnapshot(snapshot LedgerState) error {
+	return w.write(logRecord{Snapshot: &snapshot})
+}
+
+// write appends the record to the log and syncs it to disk. A failed write is truncated so that
+// the log keeps ending with a complete record.
+func (w *writeAheadLog) write(record logRecord) error {
+	if w.readOnly {
+		return errReadOnly
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, err := w.file.Write(line); err != nil {
+		return errors.Join(err, w.file.Truncate(w.size))
+	}
+	if err := w.file.Sync(); err != nil {
+		return errors.Join(err, w.file.Truncate(w.size))
+	}
+	w.size += int64(len(line))
+
+	return nil
+}
+
+// reload applies nothing, as the log has no other writers
+func (w *writeAheadLog) reload(context.Context, *store, string) error {
+	return nil
+}
+
+// holdLedger finds no hold, as the log has no other writers
+func (w *writeAheadLog) holdLedger(context.Context, string) (string, error) {
+	return "", nil
+}
+
+// transactions returns none, as the log keeps the transactions in the events only
+func (w *writeAheadLog) transactions(context.Context, string, int) ([]Transaction, bool, error) {
+	return nil, false, nil
+}
+
+// ping checks that the log file is still open and syncs to disk, and that it ends with its last
+// complete record, which it does not when a failed write could not be truncated. A log opened
+// read-only is only checked to be open.
+func (w *writeAheadLog) ping(context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	info, err := w.file.Stat()
+	if err != nil || w.readOnly {
+		return err
+	}
+	if info.Size() != w.size {
+		return fmt.Errorf("failed get complete write-ahead log, got %d bytes instead of %d", info.Size(), w.size)
+	}
+
+	return w.file.Sync()
+}
+
+// close closes the log file
+func (w *writeAheadLog) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.file.Close()
+}
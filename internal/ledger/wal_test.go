@@ -0,0 +1,427 @@
+package ledger_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/dineshd30/ledger-service/internal/ledger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openFileStore opens the file store at path with a EUR counter account
+func openFileStore(t *testing.T, path string) ledger.Store {
+	storeInstance, err := ledger.NewFileStore(ledger.NewUUIDGenerator(), newLedgersWithCounterAccount(), counterAccountOptions, path)
+	require.NoError(t, err)
+	return storeInstance
+}
+
+func TestFileStoreReplaysChangesAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.wal")
+	storeInstance := openFileStore(t, path)
+
+	wallet, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "cash", Currency: "EUR"})
+	require.NoError(t, err)
+	savings, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "savings", Currency: "EUR"})
+	require.NoError(t, err)
+
+	deposit := ledger.TransactionRequestDTO{
+		Type:           ledger.Credit,
+		Description:    "deposit",
+		Currency:       "EUR",
+		Amount:         ledger.MustParseMoney("100"),
+		IdempotencyKey: "payment-1",
+	}
+	credit, err := storeInstance.Credit(context.Background(), wallet.ID, deposit)
+	require.NoError(t, err)
+	_, err = storeInstance.Transfer(context.Background(), ledger.TransferRequestDTO{
+		SourceLedgerID:      wallet.ID,
+		DestinationLedgerID: savings.ID,
+		Description:         "save",
+		Currency:            "EUR",
+		Amount:              ledger.MustParseMoney("40.5"),
+	})
+	require.NoError(t, err)
+	closed, err := storeInstance.CloseLedger(context.Background(), savings.ID)
+	require.NoError(t, err)
+	overdrawn, err := storeInstance.SetBalancePolicy(context.Background(), wallet.ID, ledger.BalancePolicy{
+		Type: ledger.PolicyOverdraft, OverdraftLimit: ledger.MustParseMoney("25"),
+	})
+	require.NoError(t, err)
+	captured, err := storeInstance.PlaceHold(context.Background(), wallet.ID, holdFor("10"))
+	require.NoError(t, err)
+	capture, err := storeInstance.CaptureHold(context.Background(), captured.ID, ledger.CaptureRequestDTO{})
+	require.NoError(t, err)
+	_, err = storeInstance.ReverseTransaction(context.Background(), wallet.ID, capture.Transaction.ID, ledger.ReversalRequestDTO{
+		Amount: ledger.MustParseMoney("2"),
+	})
+	require.NoError(t, err)
+	active, err := storeInstance.PlaceHold(context.Background(), wallet.ID, holdFor("9.5"))
+	require.NoError(t, err)
+
+	statements := make(map[string]ledger.Statement)
+	events := make(map[string]ledger.EventPage)
+	for _, id := range []string{wallet.ID, savings.ID, "counter-eur"} {
+		statements[id], err = storeInstance.GetTransactionHistory(context.Background(), id, ledger.StatementQuery{})
+		require.NoError(t, err)
+		events[id], err = storeInstance.GetLedgerEvents(context.Background(), id, ledger.EventQuery{})
+		require.NoError(t, err)
+	}
+	walletBeforeRestart, err := storeInstance.GetLedger(context.Background(), wallet.ID)
+	require.NoError(t, err)
+	require.NoError(t, storeInstance.Close())
+
+	restarted := openFileStore(t, path)
+	defer restarted.Close()
+
+	for id, expected := range statements {
+		statement, err := restarted.GetTransactionHistory(context.Background(), id, ledger.StatementQuery{})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, statement, id)
+
+		page, err := restarted.GetLedgerEvents(context.Background(), id, ledger.EventQuery{})
+		assert.NoError(t, err)
+		assert.Equal(t, events[id], page, id)
+	}
+
+	reopened, err := restarted.GetLedger(context.Background(), savings.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, closed, reopened)
+	reloaded, err := restarted.GetLedger(context.Background(), wallet.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, walletBeforeRestart, reloaded)
+	assert.Equal(t, overdrawn.Policy, reloaded.Policy)
+	counter, err := restarted.GetLedger(context.Background(), "counter-eur")
+	assert.NoError(t, err)
+	assert.Equal(t, ledger.PolicyUnlimited, counter.Policy.Type)
+
+	replayed, err := restarted.Credit(context.Background(), wallet.ID, deposit)
+	assert.NoError(t, err)
+	assert.Equal(t, credit, replayed)
+
+	balance, err := restarted.GetLastBalance(context.Background(), wallet.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "51.5", balance.Balance.String())
+	assert.Equal(t, "42", balance.Available.String())
+
+	_, err = restarted.ReverseTransaction(context.Background(), wallet.ID, capture.Transaction.ID, ledger.ReversalRequestDTO{})
+	assert.Error(t, err)
+
+	hold, err := restarted.GetHold(context.Background(), captured.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, ledger.HoldCaptured, hold.Status)
+	_, err = restarted.ReleaseHold(context.Background(), active.ID)
+	assert.NoError(t, err)
+}
+
+func TestFileStoreRejectedChangesAreNotRecorded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.wal")
+	storeInstance := openFileStore(t, path)
+
+	wallet, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "cash", Currency: "EUR"})
+	require.NoError(t, err)
+	_, err = storeInstance.Debit(context.Background(), wallet.ID, ledger.TransactionRequestDTO{
+		Type: ledger.Debit, Description: "overdraw", Currency: "EUR", Amount: ledger.MustParseMoney("10"),
+	})
+	require.Error(t, err)
+	require.NoError(t, storeInstance.Close())
+
+	restarted := openFileStore(t, path)
+	defer restarted.Close()
+
+	statement, err := restarted.GetTransactionHistory(context.Background(), wallet.ID, ledger.StatementQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, statement.Transactions)
+}
+
+func TestFileStoreTruncatesTornChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.wal")
+	storeInstance := openFileStore(t, path)
+	wallet, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "cash", Currency: "EUR"})
+	require.NoError(t, err)
+	require.NoError(t, storeInstance.Close())
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"kind":"journal-entry","entry":{"id":`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	restarted := openFileStore(t, path)
+	_, err = restarted.Credit(context.Background(), wallet.ID, ledger.TransactionRequestDTO{
+		Type: ledger.Credit, Description: "deposit", Currency: "EUR", Amount: ledger.MustParseMoney("5"),
+	})
+	require.NoError(t, err)
+	require.NoError(t, restarted.Close())
+
+	again := openFileStore(t, path)
+	defer again.Close()
+	balance, err := again.GetLastBalance(context.Background(), wallet.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "5", balance.Balance.String())
+}
+
+func TestReadOnlyFileStoreLeavesLogUntouched(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.wal")
+	storeInstance := openFileStore(t, path)
+	wallet, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "cash", Currency: "EUR"})
+	require.NoError(t, err)
+	require.NoError(t, storeInstance.Close())
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"kind":"journal-entry","entry":{"id":`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	readOnly, err := ledger.NewReadOnlyFileStore(ledger.NewUUIDGenerator(), newLedgersWithCounterAccount(), counterAccountOptions, path)
+	require.NoError(t, err)
+	verification, err := readOnly.VerifyLedger(context.Background(), wallet.ID)
+	assert.NoError(t, err)
+	assert.True(t, verification.Verified)
+	_, err = readOnly.Credit(context.Background(), wallet.ID, ledger.TransactionRequestDTO{
+		Type: ledger.Credit, Description: "deposit", Currency: "EUR", Amount: ledger.MustParseMoney("5"),
+	})
+	assert.Error(t, err)
+	require.NoError(t, readOnly.Close())
+
+	untouched, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, content, untouched)
+}
+
+func TestFileStoreRejectsCorruptLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.wal")
+	require.NoError(t, os.WriteFile(path, []byte("not a change\n"), 0o600))
+
+	_, err := ledger.NewFileStore(ledger.NewUUIDGenerator(), newLedgersWithCounterAccount(), counterAccountOptions, path)
+	assert.Error(t, err)
+}
+
+func TestFileStoreDetectsAlteredTransaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.wal")
+	storeInstance := openFileStore(t, path)
+	wallet, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "cash", Currency: "EUR"})
+	require.NoError(t, err)
+	for _, description := range []string{"first deposit", "second deposit", "third deposit"} {
+		_, err := storeInstance.Credit(context.Background(), wallet.ID, ledger.TransactionRequestDTO{
+			Type: ledger.Credit, Description: description, Currency: "EUR", Amount: ledger.MustParseMoney("5"),
+		})
+		require.NoError(t, err)
+	}
+	transactions := allTransactions(t, storeInstance, wallet.ID)
+	require.NoError(t, storeInstance.Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	altered := strings.ReplaceAll(string(content), `"second deposit"`, `"second refund"`)
+	require.NotEqual(t, string(content), altered)
+	require.NoError(t, os.WriteFile(path, []byte(altered), 0o600))
+
+	restarted := openFileStore(t, path)
+	defer restarted.Close()
+	verification, err := restarted.VerifyLedger(context.Background(), wallet.ID)
+	require.NoError(t, err)
+	assert.False(t, verification.Verified)
+	assert.Equal(t, 1, verification.Checked)
+	assert.Equal(t, transactions[0].Hash, verification.Head)
+	require.NotNil(t, verification.BrokenLink)
+	assert.Equal(t, 2, verification.BrokenLink.Sequence)
+	assert.Equal(t, transactions[1].ID, verification.BrokenLink.TransactionID)
+	assert.Equal(t, transactions[1].Hash, verification.BrokenLink.RecordedHash)
+	assert.NotEqual(t, transactions[1].Hash, verification.BrokenLink.ExpectedHash)
+}
+
+func TestFileStoreDetectsTransactionsStrippedOfHashes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.wal")
+	storeInstance := openFileStore(t, path)
+	wallet, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "cash", Currency: "EUR"})
+	require.NoError(t, err)
+	for _, description := range []string{"first deposit", "second deposit"} {
+		_, err := storeInstance.Credit(context.Background(), wallet.ID, ledger.TransactionRequestDTO{
+			Type: ledger.Credit, Description: description, Currency: "EUR", Amount: ledger.MustParseMoney("5"),
+		})
+		require.NoError(t, err)
+	}
+	transactions := allTransactions(t, storeInstance, wallet.ID)
+	require.NoError(t, storeInstance.Close())
+
+	// stripping every hash does not pass the altered transactions off as logged before chaining
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	stripped := regexp.MustCompile(`,"hash":"[0-9a-f]{64}"`).ReplaceAllString(string(content), "")
+	altered := strings.ReplaceAll(stripped, `"second deposit"`, `"second refund"`)
+	require.NotEqual(t, stripped, altered)
+	require.NoError(t, os.WriteFile(path, []byte(altered), 0o600))
+
+	restarted := openFileStore(t, path)
+	defer restarted.Close()
+	verification, err := restarted.VerifyLedger(context.Background(), wallet.ID)
+	require.NoError(t, err)
+	assert.False(t, verification.Verified)
+	assert.Zero(t, verification.Checked)
+	assert.Zero(t, verification.Unhashed)
+	require.NotNil(t, verification.BrokenLink)
+	assert.Equal(t, 1, verification.BrokenLink.Sequence)
+	assert.Equal(t, transactions[0].ID, verification.BrokenLink.TransactionID)
+	assert.Empty(t, verification.BrokenLink.RecordedHash)
+}
+
+func TestFileStoreRestoresLedgersFromRecordedSnapshots(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.wal")
+	opts := counterAccountOptions
+	opts.SnapshotInterval = 2
+	open := func(counterCreatedAt int64) ledger.Store {
+		ledgers := newLedgersWithCounterAccount()
+		ledgers["counter-eur"].CreatedAt = counterCreatedAt
+		storeInstance, err := ledger.NewFileStore(ledger.NewUUIDGenerator(), ledgers, opts, path)
+		require.NoError(t, err)
+		return storeInstance
+	}
+
+	storeInstance := open(1000)
+	wallet, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "cash", Currency: "EUR"})
+	require.NoError(t, err)
+	for range 3 {
+		_, err := storeInstance.Credit(context.Background(), wallet.ID, ledger.TransactionRequestDTO{
+			Type: ledger.Credit, Description: "deposit", Currency: "EUR", Amount: ledger.MustParseMoney("5"),
+		})
+		require.NoError(t, err)
+	}
+	_, err = storeInstance.PlaceHold(context.Background(), wallet.ID, holdFor("2"))
+	require.NoError(t, err)
+
+	states := make(map[string][]any)
+	events := make(map[string]ledger.EventPage)
+	for _, id := range []string{wallet.ID, "counter-eur"} {
+		states[id] = ledgerStates(t, storeInstance, id)
+		events[id], err = storeInstance.GetLedgerEvents(context.Background(), id, ledger.EventQuery{})
+		require.NoError(t, err)
+	}
+	require.NoError(t, storeInstance.Close())
+
+	// the wallet and the counter account are each snapshotted at versions 2 and 4
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 4, strings.Count(string(content), `"snapshot":`))
+
+	// the counter account keeps the creation time it was first recorded with
+	restarted := open(2000)
+	defer restarted.Close()
+	for id, expected := range states {
+		assert.Equal(t, expected, ledgerStates(t, restarted, id), id)
+		page, err := restarted.GetLedgerEvents(context.Background(), id, ledger.EventQuery{})
+		assert.NoError(t, err)
+		assert.Equal(t, events[id], page, id)
+	}
+	counter, err := restarted.GetLedger(context.Background(), "counter-eur")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), counter.CreatedAt)
+
+	_, err = restarted.Debit(context.Background(), wallet.ID, ledger.TransactionRequestDTO{
+		Type: ledger.Debit, Description: "withdrawal", Currency: "EUR", Amount: ledger.MustParseMoney("13"),
+	})
+	assert.NoError(t, err)
+	verification, err := restarted.VerifyLedger(context.Background(), wallet.ID)
+	assert.NoError(t, err)
+	assert.True(t, verification.Verified)
+}
+
+func TestFileStoreIgnoresSnapshotsNotMatchingEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		snapshot string
+	}{
+		{name: "altered balance", snapshot: `"balance":1000000,"held":0}}`},
+		{name: "altered held amount", snapshot: `"balance":10,"held":5}}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ledger.wal")
+			opts := counterAccountOptions
+			opts.SnapshotInterval = 2
+			storeInstance, err := ledger.NewFileStore(ledger.NewUUIDGenerator(), newLedgersWithCounterAccount(), opts, path)
+			require.NoError(t, err)
+			wallet, err := storeInstance.CreateLedger(context.Background(), ledger.LedgerRequestDTO{Type: "cash", Currency: "EUR"})
+			require.NoError(t, err)
+			_, err = storeInstance.Credit(context.Background(), wallet.ID, ledger.TransactionRequestDTO{
+				Type: ledger.Credit, Description: "deposit", Currency: "EUR", Amount: ledger.MustParseMoney("10"),
+			})
+			require.NoError(t, err)
+			require.NoError(t, storeInstance.Close())
+
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+			altered := strings.Replace(string(content), `"balance":10,"held":0}}`, tc.snapshot, 1)
+			require.NotEqual(t, string(content), altered)
+			require.NoError(t, os.WriteFile(path, []byte(altered), 0o600))
+
+			restarted, err := ledger.NewFileStore(ledger.NewUUIDGenerator(), newLedgersWithCounterAccount(), opts, path)
+			require.NoError(t, err)
+			defer restarted.Close()
+			balance, err := restarted.GetLastBalance(context.Background(), wallet.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "10", balance.Balance.String())
+			require.NotNil(t, balance.Available)
+			assert.Equal(t, "10", balance.Available.String())
+			state, err := restarted.GetLedgerAtVersion(context.Background(), wallet.ID, 2)
+			require.NoError(t, err)
+			assert.Equal(t, "10", state.Balance.String())
+			assert.True(t, state.Held.IsZero())
+			verification, err := restarted.VerifyLedger(context.Background(), wallet.ID)
+			require.NoError(t, err)
+			assert.True(t, verification.Verified)
+			assert.Nil(t, verification.BalanceMismatch)
+		})
+	}
+}
+
+func TestFileStoreReplaysChangesLoggedWithoutEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.wal")
+	legacy := `{"kind":"ledger-created","ledger":{"id":"wallet","type":"cash","currency":"EUR","status":"open","createdAt":1000,"policy":{"type":"non-negative"},"version":0}}
+{"kind":"ledger-policy-set","ledger":{"id":"wallet","type":"cash","currency":"EUR","status":"open","createdAt":1000,"policy":{"type":"unlimited"},"version":1},"date":2000}
+`
+	require.NoError(t, os.WriteFile(path, []byte(legacy), 0o600))
+
+	storeInstance := openFileStore(t, path)
+	assert.Equal(t, []ledger.EventType{ledger.EventLedgerOpened, ledger.EventPolicySet}, eventTypes(t, storeInstance, "wallet"))
+	assert.Equal(t, []ledger.EventType{ledger.EventLedgerOpened}, eventTypes(t, storeInstance, "counter-eur"))
+	_, err := storeInstance.Debit(context.Background(), "wallet", ledger.TransactionRequestDTO{
+		Type: ledger.Debit, Description: "withdrawal", Currency: "EUR", Amount: ledger.MustParseMoney("5"),
+	})
+	require.NoError(t, err)
+	require.NoError(t, storeInstance.Close())
+
+	restarted := openFileStore(t, path)
+	defer restarted.Close()
+	assert.Equal(t, []ledger.EventType{ledger.EventLedgerOpened, ledger.EventPolicySet, ledger.EventDebited}, eventTypes(t, restarted, "wallet"))
+	assert.Equal(t, []ledger.EventType{ledger.EventLedgerOpened, ledger.EventCredited}, eventTypes(t, restarted, "counter-eur"))
+}
+
+func TestFileStoreChainsTransactionsLoggedWithoutEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.wal")
+	legacy := `{"kind":"ledger-created","ledger":{"id":"wallet","type":"cash","currency":"EUR","status":"open","createdAt":1000,"policy":{"type":"non-negative"},"version":0}}
+{"kind":"journal-entry","entry":{"id":"entry1","date":2000,"description":"deposit","currency":"EUR","transactions":[{"id":"tx1","ledgerId":"wallet","date":2000,"type":"credit","description":"deposit","amount":10,"runningBalance":10,"journalEntryId":"entry1"},{"id":"tx2","ledgerId":"counter-eur","date":2000,"type":"debit","description":"deposit","amount":10,"runningBalance":-10,"journalEntryId":"entry1"}]}}
+`
+	require.NoError(t, os.WriteFile(path, []byte(legacy), 0o600))
+
+	storeInstance := openFileStore(t, path)
+	defer storeInstance.Close()
+	_, err := storeInstance.Credit(context.Background(), "wallet", ledger.TransactionRequestDTO{
+		Type: ledger.Credit, Description: "deposit", Currency: "EUR", Amount: ledger.MustParseMoney("5"),
+	})
+	require.NoError(t, err)
+
+	verification, err := storeInstance.VerifyLedger(context.Background(), "wallet")
+	require.NoError(t, err)
+	assert.True(t, verification.Verified)
+	assert.Equal(t, 2, verification.Checked)
+	assert.Equal(t, 1, verification.Unhashed)
+}
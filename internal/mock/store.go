@@ -24,14 +24,140 @@ func (s *Store) Debit(ctx context.Context, ledgerId string, trd ledger.Transacti
 	return args.Get(0).(ledger.Transaction), args.Error(1)
 }
 
-func (s *Store) GetLastBalance(ctx context.Context, ledgerId string) (float64, error) {
+func (s *Store) Transfer(ctx context.Context, trd ledger.TransferRequestDTO) (ledger.Transfer, error) {
+	fmt.Println("Called mocked Transfer function")
+	args := s.Called(ctx, trd)
+	return args.Get(0).(ledger.Transfer), args.Error(1)
+}
+
+func (s *Store) Post(ctx context.Context, jrd ledger.JournalEntryRequestDTO) (ledger.JournalEntry, error) {
+	fmt.Println("Called mocked Post function")
+	args := s.Called(ctx, jrd)
+	return args.Get(0).(ledger.JournalEntry), args.Error(1)
+}
+
+func (s *Store) GetJournalEntry(ctx context.Context, ledgerId string, transactionId string) (ledger.JournalEntry, error) {
+	fmt.Println("Called mocked GetJournalEntry function")
+	args := s.Called(ctx, ledgerId, transactionId)
+	return args.Get(0).(ledger.JournalEntry), args.Error(1)
+}
+
+func (s *Store) CreateLedger(ctx context.Context, lrd ledger.LedgerRequestDTO) (ledger.Ledger, error) {
+	fmt.Println("Called mocked CreateLedger function")
+	args := s.Called(ctx, lrd)
+	return args.Get(0).(ledger.Ledger), args.Error(1)
+}
+
+func (s *Store) GetLedger(ctx context.Context, ledgerId string) (ledger.Ledger, error) {
+	fmt.Println("Called mocked GetLedger function")
+	args := s.Called(ctx, ledgerId)
+	return args.Get(0).(ledger.Ledger), args.Error(1)
+}
+
+func (s *Store) ListLedgers(ctx context.Context, query ledger.LedgerQuery) (ledger.LedgerPage, error) {
+	fmt.Println("Called mocked ListLedgers function")
+	args := s.Called(ctx, query)
+	return args.Get(0).(ledger.LedgerPage), args.Error(1)
+}
+
+func (s *Store) CloseLedger(ctx context.Context, ledgerId string) (ledger.Ledger, error) {
+	fmt.Println("Called mocked CloseLedger function")
+	args := s.Called(ctx, ledgerId)
+	return args.Get(0).(ledger.Ledger), args.Error(1)
+}
+
+func (s *Store) SetBalancePolicy(ctx context.Context, ledgerId string, policy ledger.BalancePolicy) (ledger.Ledger, error) {
+	fmt.Println("Called mocked SetBalancePolicy function")
+	args := s.Called(ctx, ledgerId, policy)
+	return args.Get(0).(ledger.Ledger), args.Error(1)
+}
+
+func (s *Store) GetLastBalance(ctx context.Context, ledgerId string) (ledger.Balance, error) {
 	fmt.Println("Called mocked GetLastBalance function")
 	args := s.Called(ctx, ledgerId)
-	return args.Get(0).(float64), args.Error(1)
+	return args.Get(0).(ledger.Balance), args.Error(1)
+}
+
+func (s *Store) GetBalanceAsOf(ctx context.Context, ledgerId string, asOf int64) (ledger.Balance, error) {
+	fmt.Println("Called mocked GetBalanceAsOf function")
+	args := s.Called(ctx, ledgerId, asOf)
+	return args.Get(0).(ledger.Balance), args.Error(1)
 }
 
-func (s *Store) GetTransactionHistory(ctx context.Context, ledgerId string) ([]ledger.Transaction, error) {
+func (s *Store) GetTransactionHistory(ctx context.Context, ledgerId string, query ledger.StatementQuery) (ledger.Statement, error) {
 	fmt.Println("Called mocked GetTransactionHistory function")
+	args := s.Called(ctx, ledgerId, query)
+	return args.Get(0).(ledger.Statement), args.Error(1)
+}
+
+func (s *Store) Ping(ctx context.Context) error {
+	fmt.Println("Called mocked Ping function")
+	args := s.Called(ctx)
+	return args.Error(0)
+}
+
+func (s *Store) Close() error {
+	fmt.Println("Called mocked Close function")
+	args := s.Called()
+	return args.Error(0)
+}
+
+func (s *Store) PlaceHold(ctx context.Context, ledgerId string, hrd ledger.HoldRequestDTO) (ledger.Hold, error) {
+	fmt.Println("Called mocked PlaceHold function")
+	args := s.Called(ctx, ledgerId, hrd)
+	return args.Get(0).(ledger.Hold), args.Error(1)
+}
+
+func (s *Store) GetHold(ctx context.Context, holdId string) (ledger.Hold, error) {
+	fmt.Println("Called mocked GetHold function")
+	args := s.Called(ctx, holdId)
+	return args.Get(0).(ledger.Hold), args.Error(1)
+}
+
+func (s *Store) CaptureHold(ctx context.Context, holdId string, crd ledger.CaptureRequestDTO) (ledger.HoldCapture, error) {
+	fmt.Println("Called mocked CaptureHold function")
+	args := s.Called(ctx, holdId, crd)
+	return args.Get(0).(ledger.HoldCapture), args.Error(1)
+}
+
+func (s *Store) ReleaseHold(ctx context.Context, holdId string) (ledger.Hold, error) {
+	fmt.Println("Called mocked ReleaseHold function")
+	args := s.Called(ctx, holdId)
+	return args.Get(0).(ledger.Hold), args.Error(1)
+}
+
+func (s *Store) ExpireHolds(ctx context.Context) (int, error) {
+	fmt.Println("Called mocked ExpireHolds function")
+	args := s.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (s *Store) ReverseTransaction(ctx context.Context, ledgerId string, transactionId string, rrd ledger.ReversalRequestDTO) (ledger.Reversal, error) {
+	fmt.Println("Called mocked ReverseTransaction function")
+	args := s.Called(ctx, ledgerId, transactionId, rrd)
+	return args.Get(0).(ledger.Reversal), args.Error(1)
+}
+
+func (s *Store) GetLedgerEvents(ctx context.Context, ledgerId string, query ledger.EventQuery) (ledger.EventPage, error) {
+	fmt.Println("Called mocked GetLedgerEvents function")
+	args := s.Called(ctx, ledgerId, query)
+	return args.Get(0).(ledger.EventPage), args.Error(1)
+}
+
+func (s *Store) GetLedgerAtVersion(ctx context.Context, ledgerId string, version int64) (ledger.LedgerState, error) {
+	fmt.Println("Called mocked GetLedgerAtVersion function")
+	args := s.Called(ctx, ledgerId, version)
+	return args.Get(0).(ledger.LedgerState), args.Error(1)
+}
+
+func (s *Store) Project(ctx context.Context, projection ledger.Projection) error {
+	fmt.Println("Called mocked Project function")
+	args := s.Called(ctx, projection)
+	return args.Error(0)
+}
+
+func (s *Store) VerifyLedger(ctx context.Context, ledgerId string) (ledger.ChainVerification, error) {
+	fmt.Println("Called mocked VerifyLedger function")
 	args := s.Called(ctx, ledgerId)
-	return args.Get(0).([]ledger.Transaction), args.Error(1)
+	return args.Get(0).(ledger.ChainVerification), args.Error(1)
 }